@@ -1,22 +1,36 @@
 package main
 
 import (
+	"context"
 	"log"
 	"log/slog"
 	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/moriverse/45-server/internal/app/apikey"
 	"github.com/moriverse/45-server/internal/app/auth"
+	"github.com/moriverse/45-server/internal/app/avatar"
+	"github.com/moriverse/45-server/internal/app/loginhistory"
+	"github.com/moriverse/45-server/internal/app/oauth"
+	"github.com/moriverse/45-server/internal/app/rbac"
+	"github.com/moriverse/45-server/internal/app/session"
 	"github.com/moriverse/45-server/internal/app/user"
+	"github.com/moriverse/45-server/internal/infrastructure/blobstore"
 	"github.com/moriverse/45-server/internal/infrastructure/cache"
 	"github.com/moriverse/45-server/internal/infrastructure/config"
+	"github.com/moriverse/45-server/internal/infrastructure/email"
+	"github.com/moriverse/45-server/internal/infrastructure/geoip"
 	"github.com/moriverse/45-server/internal/infrastructure/logger"
+	"github.com/moriverse/45-server/internal/infrastructure/loginalert"
+	"github.com/moriverse/45-server/internal/infrastructure/oidc"
 	"github.com/moriverse/45-server/internal/infrastructure/persistence"
 	"github.com/moriverse/45-server/internal/infrastructure/persistence/repository"
-	"github.com/moriverse/45-server/internal/infrastructure/wechat"
+	"github.com/moriverse/45-server/internal/infrastructure/sms"
 	"github.com/moriverse/45-server/internal/infrastructure/web"
 	"github.com/moriverse/45-server/internal/infrastructure/web/handler"
 	"github.com/moriverse/45-server/internal/infrastructure/web/middleware"
+	"github.com/moriverse/45-server/internal/infrastructure/wechat"
+	"github.com/moriverse/45-server/internal/utils"
 )
 
 func main() {
@@ -51,21 +65,149 @@ func InitializeApp(cfg config.Config, appLogger *slog.Logger) (*gin.Engine, erro
 		return nil, err
 	}
 
+	keys, err := utils.LoadKeySet(cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHasher, err := utils.NewPasswordHasher(cfg.Password.Argon2)
+	if err != nil {
+		return nil, err
+	}
+
 	redisClient := cache.NewRedisClient(cfg.Redis)
-	wechatClient := wechat.NewClient()
+	wechatClient, err := wechat.NewClient(cfg.Wechat)
+	if err != nil {
+		return nil, err
+	}
+	smsSender, err := sms.NewSender(cfg.SMS, redisClient, appLogger)
+	if err != nil {
+		return nil, err
+	}
+	emailSender, err := email.NewSender(cfg.Email, appLogger)
+	if err != nil {
+		return nil, err
+	}
+	geoDatabase, err := geoip.NewDatabase(cfg.GeoIP)
+	if err != nil {
+		return nil, err
+	}
+	loginAlerter, err := loginalert.NewAlerter(cfg.LoginAlert, appLogger)
+	if err != nil {
+		return nil, err
+	}
+	blobStore, err := blobstore.NewBlobStore(cfg.BlobStore)
+	if err != nil {
+		return nil, err
+	}
+	// Only the local blob store serves its own files.
+	blobServer, _ := blobStore.(blobstore.Server)
 
 	userRepo := repository.NewUserRepository(db)
 	authRepo := repository.NewAuthRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	verificationRepo := repository.NewVerificationTokenRepository(db)
+	mergeRepo := repository.NewAccountMergeRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	rbacRepo := repository.NewRBACRepository(db)
+	impersonationRepo := repository.NewImpersonationRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	oauthRepo := repository.NewOAuthRepository(db)
+	loginEventRepo := repository.NewLoginEventRepository(db)
 
-	uow := persistence.NewUnitOfWork(db, userRepo, authRepo)
+	uow := persistence.NewUnitOfWork(
+		db,
+		userRepo,
+		authRepo,
+		refreshTokenRepo,
+		sessionRepo,
+		verificationRepo,
+		mergeRepo,
+		mfaRepo,
+		impersonationRepo,
+		rbacRepo,
+		loginEventRepo,
+		oauthRepo,
+		apiKeyRepo,
+	)
 
 	// Initialize services
-	authService := auth.NewService(uow, cfg.JWT, wechatClient)
-	userService := user.NewService(userRepo, redisClient, appLogger)
+	sessionService := session.NewService(uow, cfg.JWT, redisClient)
+	loginHistoryService := loginhistory.NewService(
+		loginEventRepo,
+		geoDatabase,
+		loginAlerter,
+		appLogger,
+	)
+	avatarService := avatar.NewService(blobStore, userRepo, cfg.Avatar, appLogger)
+	authService := auth.NewService(
+		uow,
+		cfg.JWT,
+		keys,
+		sessionService,
+		loginHistoryService,
+		avatarService,
+		wechatClient,
+		oidc.NewVerifier(cfg.Google),
+		oidc.NewVerifier(cfg.Apple),
+		cfg.Password,
+		passwordHasher,
+		cfg.MFA,
+		cfg.LoginThrottle,
+		cfg.Guest,
+		smsSender,
+		emailSender,
+		redisClient,
+		appLogger,
+	)
+	userService := user.NewService(userRepo, avatarService, redisClient, appLogger)
+	rbacService := rbac.NewService(rbacRepo, userRepo, redisClient, appLogger)
+	apiKeyService := apikey.NewService(apiKeyRepo, rbacService, redisClient, appLogger)
+	oauthService := oauth.NewService(
+		oauthRepo,
+		userRepo,
+		sessionService,
+		keys,
+		cfg.OAuth,
+		redisClient,
+		appLogger,
+	)
+
+	// Start background jobs
+	go authService.RunGuestPurge(context.Background())
 
 	// Initialize handlers and middleware
 	authHandler := handler.NewAuthHandler(authService)
-	mw := middleware.NewMiddleware(userService, cfg.JWT, appLogger)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+	jwksHandler := handler.NewJWKSHandler(keys)
+	roleHandler := handler.NewRoleHandler(rbacService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	oauthHandler := handler.NewOAuthHandler(oauthService)
+	loginHistoryHandler := handler.NewLoginHistoryHandler(loginHistoryService)
+	userHandler := handler.NewUserHandler(userService)
+	avatarHandler := handler.NewAvatarHandler(avatarService)
+	mw := middleware.NewMiddleware(
+		userService,
+		sessionService,
+		rbacService,
+		apiKeyService,
+		keys,
+		appLogger,
+	)
 
-	return web.NewRouter(authHandler, mw, cfg), nil
+	return web.NewRouter(
+		authHandler,
+		sessionHandler,
+		jwksHandler,
+		roleHandler,
+		apiKeyHandler,
+		oauthHandler,
+		loginHistoryHandler,
+		userHandler,
+		avatarHandler,
+		blobServer,
+		mw,
+		cfg,
+	)
 }
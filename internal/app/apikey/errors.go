@@ -0,0 +1,13 @@
+package apikey
+
+import "errors"
+
+var (
+	ErrInvalidAPIKey   = errors.New("invalid, expired or revoked api key")
+	ErrAPIKeyNotFound  = errors.New("api key does not exist")
+	ErrNameRequired    = errors.New("api key name is required")
+	ErrInvalidScope    = errors.New("unknown api key scope")
+	ErrScopesRequired  = errors.New("api key needs at least one scope")
+	ErrExpiryInThePast = errors.New("api key expiry is in the past")
+	ErrScopeNotGranted = errors.New("api key scope is not granted to its creator")
+)
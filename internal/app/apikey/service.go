@@ -0,0 +1,203 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	appRBAC "github.com/moriverse/45-server/internal/app/rbac"
+	"github.com/moriverse/45-server/internal/domain/apikey"
+	"github.com/moriverse/45-server/internal/domain/rbac"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+const (
+	// keyTag starts every key, so that leaked keys are easy to recognize and scan for.
+	keyTag         = "45k"
+	keyPrefixBytes = 4
+
+	lastUsedCacheKeyPrefix = "api-key-last-used"
+	lastUsedCacheTTL       = 5 * time.Minute
+)
+
+// Service is the application service for API keys.
+type Service struct {
+	apiKeyRepo  apikey.Repository
+	rbacService *appRBAC.Service
+	redisClient *redis.Client
+	logger      *slog.Logger
+}
+
+// NewService creates a new instance of the API key service.
+func NewService(
+	apiKeyRepo apikey.Repository,
+	rbacService *appRBAC.Service,
+	redisClient *redis.Client,
+	logger *slog.Logger,
+) *Service {
+	return &Service{
+		apiKeyRepo:  apiKeyRepo,
+		rbacService: rbacService,
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+// CreateParams contains the parameters for issuing an API key.
+type CreateParams struct {
+	Name      string
+	Scopes    []rbac.Permission
+	ExpiresAt *time.Time // Nil for a key that never expires
+	CreatedBy user.UserID
+}
+
+// CreateResult contains a newly issued API key.
+type CreateResult struct {
+	APIKey *apikey.APIKey
+	Key    string // The full key, which cannot be shown again
+}
+
+// Create issues an API key. The key has the form 45k_<prefix>_<secret>, and only its hash is
+// stored. Its scopes must be permissions that its creator has.
+func (s *Service) Create(ctx context.Context, params CreateParams) (*CreateResult, error) {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+	if len(params.Scopes) == 0 {
+		return nil, ErrScopesRequired
+	}
+	for _, scope := range params.Scopes {
+		if !scope.IsValid() {
+			return nil, ErrInvalidScope
+		}
+		// Otherwise anyone allowed to create keys could give a key any permission.
+		granted, err := s.rbacService.HasPermission(ctx, params.CreatedBy, scope)
+		if err != nil {
+			return nil, err
+		}
+		if !granted {
+			return nil, ErrScopeNotGranted
+		}
+	}
+	now := time.Now()
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(now) {
+		return nil, ErrExpiryInThePast
+	}
+
+	prefix, secret, err := generateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := fmt.Sprintf("%s_%s_%s", keyTag, prefix, secret)
+
+	created := &apikey.APIKey{
+		ID:        apikey.APIKeyID(uuid.New().String()),
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashToken(key),
+		Scopes:    params.Scopes,
+		CreatedBy: params.CreatedBy,
+		ExpiresAt: params.ExpiresAt,
+		CreatedAt: now,
+	}
+	if err := s.apiKeyRepo.Create(ctx, created); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info(
+		"API key created",
+		"apiKeyID", created.ID,
+		"name", created.Name,
+		"scopes", created.Scopes,
+		"createdBy", params.CreatedBy,
+	)
+	return &CreateResult{APIKey: created, Key: key}, nil
+}
+
+func generateKey() (prefix, secret string, err error) {
+	b := make([]byte, keyPrefixBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret, err = utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(b), secret, nil
+}
+
+// List lists every API key, newest first.
+func (s *Service) List(ctx context.Context) ([]*apikey.APIKey, error) {
+	return s.apiKeyRepo.List(ctx)
+}
+
+// Revoke stops an API key from working. Revoked keys are kept for the record.
+func (s *Service) Revoke(ctx context.Context, id apikey.APIKeyID, revokedBy user.UserID) error {
+	revoked, err := s.apiKeyRepo.Revoke(ctx, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+
+	s.logger.Info("API key revoked", "apiKeyID", id, "revokedBy", revokedBy)
+	return nil
+}
+
+// Authenticate finds the active API key matching the given key.
+func (s *Service) Authenticate(ctx context.Context, key string) (*apikey.APIKey, error) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != keyTag {
+		return nil, ErrInvalidAPIKey
+	}
+
+	found, err := s.apiKeyRepo.FindByPrefix(ctx, parts[1])
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	keyHash := utils.HashToken(key)
+	if subtle.ConstantTimeCompare([]byte(found.KeyHash), []byte(keyHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	if !found.IsActive(time.Now()) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	s.touchLastUsed(ctx, found.ID)
+	return found, nil
+}
+
+// touchLastUsed records that a key was used, at most once per cache TTL.
+func (s *Service) touchLastUsed(ctx context.Context, id apikey.APIKeyID) {
+	key := fmt.Sprintf("%s:%s", lastUsedCacheKeyPrefix, id)
+	wasSet, err := s.redisClient.SetNX(ctx, key, "used", lastUsedCacheTTL).Result()
+	if err != nil {
+		s.logger.Error("Failed to set api key last used cache key", "apiKeyID", id, "error", err)
+		return
+	}
+	if !wasSet {
+		return
+	}
+
+	go func() {
+		// The request context may be cancelled before the update finishes.
+		err := s.apiKeyRepo.UpdateLastUsedAt(context.Background(), id, time.Now())
+		if err != nil {
+			s.logger.Error("Failed to update api key last used time", "apiKeyID", id, "error", err)
+		}
+	}()
+}
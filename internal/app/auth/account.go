@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+// DeleteAccount deletes the user at their own request and signs them out everywhere. Their
+// login methods and phone number are released, so that they can sign up again as a new user.
+func (s *Service) DeleteAccount(ctx context.Context, userID user.UserID) error {
+	var deleted *user.User
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		u, err := work.Users().FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if u == nil || u.DeletedAt != nil {
+			return ErrUserNotFound
+		}
+		deleted = u
+		return deleteUser(ctx, work, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.avatarService.DeleteUploaded(deleted)
+	s.logger.Info("Account deleted", "userID", userID)
+	return s.sessionService.RevokeAll(ctx, userID)
+}
+
+// deleteUser deletes a user together with their identities and two-factor authentication, in
+// the caller's transaction. The caller signs the user out and deletes their uploaded avatar.
+func deleteUser(ctx context.Context, work unitofwork.UserAuthWork, userID user.UserID) error {
+	auths, err := work.Auths().ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, a := range auths {
+		if err := work.Auths().Delete(ctx, a.ID); err != nil {
+			return err
+		}
+	}
+	if err := work.MFA().DeleteTOTP(ctx, userID); err != nil {
+		return err
+	}
+	if err := work.MFA().ReplaceRecoveryCodes(ctx, userID, nil); err != nil {
+		return err
+	}
+	return work.Users().Delete(ctx, userID)
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/oidc"
+)
+
+// appleNonceKeyPrefix marks the nonces of Apple identity tokens that have been used.
+const appleNonceKeyPrefix = "apple-nonce"
+
+// LoginOrRegisterWithAppleParams contains the parameters for signing in a user via Apple.
+type LoginOrRegisterWithAppleParams struct {
+	IDToken string // The identity token from Sign in with Apple
+	// Nonce is the raw nonce the client passed to Apple. Apple puts its SHA-256 hash in the
+	// identity token.
+	Nonce string
+	// Apple sends the user's name to the client on the first sign-in only, never in the token,
+	// so the client forwards it to us.
+	GivenName  string
+	FamilyName string
+	Source     user.Source
+	Client     ClientInfo
+}
+
+// LoginOrRegisterWithApple verifies an Apple identity token, then finds the user who owns the
+// Apple ID or creates a new one if they don't exist.
+func (s *Service) LoginOrRegisterWithApple(
+	ctx context.Context,
+	params LoginOrRegisterWithAppleParams,
+) (*RegisterResult, error) {
+	attempt := loginAttempt{Method: string(auth.Apple), Client: params.Client}
+	attempt.Source = params.Source
+	return s.throttleLogin(ctx, attempt, func() (*RegisterResult, error) {
+		return s.loginOrRegisterWithApple(ctx, params)
+	})
+}
+
+// loginOrRegisterWithApple signs the user in without counting failed attempts.
+func (s *Service) loginOrRegisterWithApple(
+	ctx context.Context,
+	params LoginOrRegisterWithAppleParams,
+) (*RegisterResult, error) {
+	// 1. Verify the identity token
+	identity, err := s.verifyAppleIDToken(ctx, params.IDToken, params.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Find or create the user who owns the Apple ID
+	nickname := strings.TrimSpace(params.GivenName + " " + params.FamilyName)
+	u, created, err := s.findOrCreateUserByIdentity(
+		ctx,
+		identity,
+		params.Source,
+		func(u *user.User) {
+			u.Nickname = nickname
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Apple only sends the name once, so keep it if an earlier attempt failed to save it
+	if !created && u.Nickname == "" && nickname != "" {
+		u.Nickname = nickname
+		u.UpdatedAt = time.Now()
+		if err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+			return work.Users().Update(ctx, u)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// 4. Generate tokens for the found or created user
+	return s.completeLogin(ctx, u, auth.Apple, params.Client)
+}
+
+// verifyAppleIDToken verifies an Apple identity token and its nonce, and returns the Apple ID
+// it identifies. Each nonce is accepted once, so that a token cannot be replayed.
+func (s *Service) verifyAppleIDToken(
+	ctx context.Context,
+	idToken string,
+	nonce string,
+) (providerIdentity, error) {
+	claims, err := s.appleVerifier.Verify(ctx, idToken)
+	if errors.Is(err, oidc.ErrInvalidToken) {
+		return providerIdentity{}, ErrInvalidIDToken
+	}
+	if errors.Is(err, oidc.ErrKeysUnavailable) {
+		s.logger.Error("Failed to fetch Apple signing keys", "error", err)
+		return providerIdentity{}, ErrIdentityProviderUnavailable
+	}
+	if err != nil {
+		return providerIdentity{}, err
+	}
+	if nonce == "" || !appleNonceMatches(nonce, claims.Nonce) {
+		return providerIdentity{}, ErrInvalidIDToken
+	}
+	// Remember the nonce until the token expires, after which the token is rejected anyway.
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return providerIdentity{}, ErrInvalidIDToken
+	}
+	fresh, err := s.redisClient.SetNX(ctx, appleNonceKey(claims.Nonce), 1, ttl).Result()
+	if err != nil {
+		return providerIdentity{}, err
+	}
+	if !fresh {
+		return providerIdentity{}, ErrInvalidIDToken
+	}
+
+	return providerIdentity{
+		Provider:       auth.Apple,
+		ProviderID:     claims.Subject,
+		Email:          claims.Email,
+		EmailIsPrivate: bool(claims.IsPrivateEmail),
+		EmailVerified:  bool(claims.EmailVerified),
+	}, nil
+}
+
+func appleNonceKey(hashedNonce string) string {
+	return fmt.Sprintf("%s:%s", appleNonceKeyPrefix, hashedNonce)
+}
+
+// appleNonceMatches reports whether the nonce claim of an Apple identity token is the SHA-256
+// hash of the raw nonce.
+func appleNonceMatches(rawNonce, claim string) bool {
+	sum := sha256.Sum256([]byte(rawNonce))
+	expected := hex.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(claim)) == 1
+}
+
+// HandleAppleNotification processes a Sign in with Apple server-to-server notification. When
+// the user revokes consent, they are signed out everywhere but keep their Apple identity, so
+// that they can sign in with Apple again. When they delete their Apple account, the Apple
+// identity is removed too, and if it was their only way to sign in the user is deleted as
+// DeleteAccount would: their phone number is released and their uploaded avatar removed.
+func (s *Service) HandleAppleNotification(ctx context.Context, payload string) error {
+	event, err := s.appleVerifier.VerifyAppleNotification(ctx, payload)
+	if errors.Is(err, oidc.ErrInvalidToken) {
+		return ErrInvalidIDToken
+	}
+	if errors.Is(err, oidc.ErrKeysUnavailable) {
+		s.logger.Error("Failed to fetch Apple signing keys", "error", err)
+		return ErrIdentityProviderUnavailable
+	}
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case oidc.AppleConsentRevoked, oidc.AppleAccountDelete:
+	case oidc.AppleEmailEnabled, oidc.AppleEmailDisabled:
+		// We never send mail to relay addresses, so there is nothing to update.
+		s.logger.Info("Apple relay email forwarding changed", "type", event.Type)
+		return nil
+	default:
+		s.logger.Warn("Ignoring unknown Apple notification", "type", event.Type)
+		return nil
+	}
+
+	var userID user.UserID
+	var deleted *user.User
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		appleAuth, err := work.Auths().FindByProvider(ctx, auth.Apple, event.Subject)
+		if err != nil {
+			return err
+		}
+		if appleAuth == nil {
+			// Already handled, or the user never finished signing up.
+			return nil
+		}
+		userID = appleAuth.UserID
+		if event.Type != oidc.AppleAccountDelete {
+			return nil
+		}
+
+		if err := work.Auths().Delete(ctx, appleAuth.ID); err != nil {
+			return err
+		}
+
+		remaining, err := work.Auths().ListByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if len(remaining) > 0 {
+			return nil
+		}
+		u, err := work.Users().FindByID(ctx, userID)
+		if err != nil || u == nil || u.DeletedAt != nil {
+			return err
+		}
+		deleted = u
+		return deleteUser(ctx, work, userID)
+	})
+	if err != nil || userID == "" {
+		return err
+	}
+
+	if deleted != nil {
+		s.avatarService.DeleteUploaded(deleted)
+	}
+
+	s.logger.Info("Apple ID disconnected", "type", event.Type, "userID", userID)
+	return s.sessionService.RevokeAll(ctx, userID)
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/notification"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/domain/verificationtoken"
+)
+
+// SendEmailVerificationResult contains the result of sending an email verification link.
+type SendEmailVerificationResult struct {
+	Email     string
+	ExpiresIn time.Duration
+}
+
+// SendEmailVerification emails a verification link to the user's unverified email address.
+// Private relay addresses are never verified, as they are not the user's real address.
+func (s *Service) SendEmailVerification(
+	ctx context.Context,
+	userID user.UserID,
+) (*SendEmailVerificationResult, error) {
+	var auths []*auth.Auth
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		var err error
+		auths, err = work.Auths().ListByUserID(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var target *auth.Auth
+	hasEmail := false
+	for _, a := range auths {
+		if a.Email == "" || a.EmailIsPrivate {
+			continue
+		}
+		hasEmail = true
+		if a.EmailVerifiedAt == nil {
+			target = a
+			break
+		}
+	}
+	if target == nil {
+		if hasEmail {
+			return nil, ErrEmailAlreadyVerified
+		}
+		return nil, ErrNoEmailToVerify
+	}
+
+	allowed, err := s.allowVerificationEmail(ctx, target.ID, verificationtoken.EmailVerification)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrEmailThrottled
+	}
+
+	if err := s.sendEmailVerification(ctx, target); err != nil {
+		return nil, err
+	}
+	return &SendEmailVerificationResult{
+		Email:     target.Email,
+		ExpiresIn: emailVerificationTokenTTL,
+	}, nil
+}
+
+func (s *Service) sendEmailVerification(ctx context.Context, a *auth.Auth) error {
+	return s.sendVerificationToken(
+		ctx,
+		a,
+		verificationtoken.EmailVerification,
+		notification.EmailEmailVerification,
+		emailVerificationTokenTTL,
+	)
+}
+
+// VerifyEmail marks the email address a verification token was sent to as verified.
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	return s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		redeemed, a, err := redeemVerificationToken(
+			ctx,
+			work,
+			token,
+			verificationtoken.EmailVerification,
+		)
+		if err != nil {
+			return err
+		}
+		if redeemed == nil {
+			return ErrInvalidVerificationToken
+		}
+		if a.EmailVerifiedAt != nil {
+			return nil
+		}
+		return work.Auths().MarkEmailVerified(ctx, a.ID, time.Now())
+	})
+}
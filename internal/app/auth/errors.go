@@ -3,6 +3,48 @@ package auth
 import "errors"
 
 var (
-	ErrUserAlreadyExists  = errors.New("user with this email already exists")
-	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUserAlreadyExists           = errors.New("username is already taken")
+	ErrInvalidCredentials          = errors.New("invalid credentials")
+	ErrInvalidPhoneNumber          = errors.New("invalid phone number")
+	ErrInvalidVerificationCode     = errors.New("invalid or expired verification code")
+	ErrTooManyVerificationAttempts = errors.New("too many verification attempts")
+	ErrSMSThrottled                = errors.New("too many sms messages sent to this number")
+	ErrInvalidRefreshToken         = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused          = errors.New("refresh token has already been used")
+	ErrInvalidWechatCode           = errors.New("invalid or expired wechat code")
+	ErrWechatUnavailable           = errors.New("wechat is temporarily unavailable")
+	ErrWechatSessionExpired        = errors.New("wechat session key is missing or outdated")
+	ErrPhoneNumberTaken            = errors.New("phone number belongs to another user")
+	ErrPhoneNumberAlreadyBound     = errors.New("user already has a phone number")
+	ErrInvalidIDToken              = errors.New("invalid id token")
+	ErrIdentityProviderUnavailable = errors.New("identity provider is temporarily unavailable")
+	ErrInvalidIdentifier           = errors.New("invalid username or email address")
+	ErrWeakPassword                = errors.New("password does not meet the strength rules")
+	ErrInvalidResetToken           = errors.New("invalid or expired password reset token")
+	ErrInvalidVerificationToken    = errors.New("invalid or expired email verification token")
+	ErrEmailAlreadyVerified        = errors.New("email address is already verified")
+	ErrNoEmailToVerify             = errors.New("user has no email address to verify")
+	ErrEmailThrottled              = errors.New("too many emails sent to this address")
+	ErrIdentityLinkedToAnotherUser = errors.New("identity belongs to another user")
+	ErrProviderAlreadyLinked       = errors.New("user already has an identity with this provider")
+	ErrIdentityNotFound            = errors.New("user has no identity with this provider")
+	ErrLastIdentity                = errors.New("cannot remove the last login method")
+	ErrInvalidMergeToken           = errors.New("invalid or expired merge token")
+	ErrCannotMergeSameUser         = errors.New("cannot merge a user into themselves")
+	ErrMergeUserNotFound           = errors.New("user to merge does not exist")
+	ErrInvalidMFAToken             = errors.New("invalid or expired mfa token")
+	ErrInvalidMFACode              = errors.New("invalid mfa code")
+	ErrMFAAlreadyEnabled           = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled               = errors.New("two-factor authentication is not enabled")
+	ErrMFAEnrollmentNotFound       = errors.New("no two-factor enrollment to confirm")
+	ErrTooManyAttempts             = errors.New("too many failed login attempts")
+	ErrDeviceIDRequired            = errors.New("device id is required")
+	ErrCannotImpersonateSelf       = errors.New("cannot impersonate yourself")
+	ErrImpersonationReasonRequired = errors.New("a reason is required to impersonate a user")
+	ErrImpersonationTargetNotFound = errors.New("user to impersonate does not exist")
+	ErrMFACodeRequired             = errors.New("a two-factor authentication code is required")
+	ErrReauthProviderUnsupported   = errors.New("login method cannot be used to re-authenticate")
+	ErrSessionNotActive            = errors.New("session has been signed out or has expired")
+	ErrUserNotFound                = errors.New("user does not exist")
+	ErrTooManyGuests               = errors.New("too many guests created from this client")
 )
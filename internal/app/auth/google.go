@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/oidc"
+)
+
+// LoginOrRegisterWithGoogleParams contains the parameters for signing in a user via Google.
+type LoginOrRegisterWithGoogleParams struct {
+	IDToken string // The ID token from Google Sign-In
+	Source  user.Source
+	Client  ClientInfo
+}
+
+// LoginOrRegisterWithGoogle verifies a Google ID token, then finds the user who owns the Google
+// account or creates a new one if they don't exist.
+func (s *Service) LoginOrRegisterWithGoogle(
+	ctx context.Context,
+	params LoginOrRegisterWithGoogleParams,
+) (*RegisterResult, error) {
+	attempt := loginAttempt{Method: string(auth.Google), Client: params.Client}
+	attempt.Source = params.Source
+	return s.throttleLogin(ctx, attempt, func() (*RegisterResult, error) {
+		return s.loginOrRegisterWithGoogle(ctx, params)
+	})
+}
+
+// loginOrRegisterWithGoogle signs the user in without counting failed attempts.
+func (s *Service) loginOrRegisterWithGoogle(
+	ctx context.Context,
+	params LoginOrRegisterWithGoogleParams,
+) (*RegisterResult, error) {
+	// 1. Verify the ID token
+	identity, claims, err := s.verifyGoogleIDToken(ctx, params.IDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Find or create the user who owns the Google account
+	u, _, err := s.findOrCreateUserByIdentity(
+		ctx,
+		identity,
+		params.Source,
+		func(u *user.User) {
+			u.AvatarURL = claims.Picture
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Generate tokens for the found or created user
+	return s.completeLogin(ctx, u, auth.Google, params.Client)
+}
+
+// verifyGoogleIDToken verifies a Google ID token and returns the Google account it identifies.
+// Only verified email addresses are kept.
+func (s *Service) verifyGoogleIDToken(
+	ctx context.Context,
+	idToken string,
+) (providerIdentity, *oidc.Claims, error) {
+	claims, err := s.googleVerifier.Verify(ctx, idToken)
+	if errors.Is(err, oidc.ErrInvalidToken) {
+		return providerIdentity{}, nil, ErrInvalidIDToken
+	}
+	if errors.Is(err, oidc.ErrKeysUnavailable) {
+		s.logger.Error("Failed to fetch Google signing keys", "error", err)
+		return providerIdentity{}, nil, ErrIdentityProviderUnavailable
+	}
+	if err != nil {
+		return providerIdentity{}, nil, err
+	}
+
+	identity := providerIdentity{Provider: auth.Google, ProviderID: claims.Subject}
+	if claims.EmailVerified {
+		identity.Email = claims.Email
+		identity.EmailVerified = true
+	}
+	return identity, claims, nil
+}
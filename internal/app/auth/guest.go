@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+const (
+	guestPurgeLockKey       = "guest-purge-lock"
+	guestPurgeBatch         = 500
+	guestCreationsKeyPrefix = "guest-creations"
+)
+
+// LoginAsGuestParams contains the parameters for trying the app without signing in.
+type LoginAsGuestParams struct {
+	// GuestToken is the secret issued on the device's first visit, or empty to create a guest.
+	GuestToken string
+	Source     user.Source
+	Client     ClientInfo // Must identify the device, which the guest is bound to
+}
+
+// LoginAsGuest signs in the guest user who was issued the guest token, or creates a new guest
+// and issues them a token, which the result carries. A guest can only sign in from the device
+// they were created on, and each device and IP address can only create a few guests. The guest
+// can later link a real login method to keep everything they did.
+func (s *Service) LoginAsGuest(
+	ctx context.Context,
+	params LoginAsGuestParams,
+) (*RegisterResult, error) {
+	if params.Client.DeviceID == "" {
+		return nil, ErrDeviceIDRequired
+	}
+	attempt := loginAttempt{Method: string(auth.Guest), Client: params.Client}
+	attempt.Source = params.Source
+	return s.throttleLogin(ctx, attempt, func() (*RegisterResult, error) {
+		if params.GuestToken == "" {
+			return s.createGuest(ctx, params)
+		}
+		return s.loginAsGuest(ctx, params)
+	})
+}
+
+// createGuest creates a guest user, whose identity is the hash of a new guest token.
+func (s *Service) createGuest(
+	ctx context.Context,
+	params LoginAsGuestParams,
+) (*RegisterResult, error) {
+	allowed, err := s.allowGuestCreation(ctx, params.Client)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrTooManyGuests
+	}
+
+	guestToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate guest token: %w", err)
+	}
+
+	identity := providerIdentity{
+		Provider:   auth.Guest,
+		ProviderID: utils.HashToken(guestToken),
+		DeviceID:   params.Client.DeviceID,
+	}
+	u, _, err := s.findOrCreateUserByIdentity(
+		ctx,
+		identity,
+		params.Source,
+		func(u *user.User) { u.IsGuest = true },
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Guest user created", "userID", u.ID, "source", params.Source)
+
+	result, err := s.startSession(ctx, u, []string{string(auth.Guest)}, params.Client)
+	if err != nil {
+		return nil, err
+	}
+	result.GuestToken = guestToken
+	return result, nil
+}
+
+// allowGuestCreation counts a guest creation against the client's IP address and device, and
+// reports whether both are still within their limits.
+func (s *Service) allowGuestCreation(ctx context.Context, client ClientInfo) (bool, error) {
+	window := time.Duration(s.guestConfig.CreationWindowMinutes) * time.Minute
+	limits := []struct {
+		kind  string
+		value string
+		max   int
+	}{
+		{"ip", client.IPAddress, s.guestConfig.MaxCreationsPerIP},
+		{"device", client.DeviceID, s.guestConfig.MaxCreationsPerDevice},
+	}
+
+	allowed := true
+	for _, limit := range limits {
+		if limit.value == "" || limit.max <= 0 || window <= 0 {
+			continue
+		}
+		key := fmt.Sprintf("%s:%s:%s", guestCreationsKeyPrefix, limit.kind, limit.value)
+		created, err := s.redisClient.Incr(ctx, key).Result()
+		if err != nil {
+			return false, fmt.Errorf("failed to count guest creation: %w", err)
+		}
+		if created == 1 {
+			s.redisClient.Expire(ctx, key, window)
+		}
+		if created > int64(limit.max) {
+			allowed = false
+		}
+	}
+	if !allowed {
+		s.logger.Warn("Guest creation throttled", "ip", client.IPAddress,
+			"deviceID", client.DeviceID)
+	}
+	return allowed, nil
+}
+
+// loginAsGuest signs in the guest who owns the guest token, from the device they are bound to.
+func (s *Service) loginAsGuest(
+	ctx context.Context,
+	params LoginAsGuestParams,
+) (*RegisterResult, error) {
+	var u *user.User
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		guestAuth, err := work.Auths().FindByProvider(
+			ctx,
+			auth.Guest,
+			utils.HashToken(params.GuestToken),
+		)
+		if err != nil || guestAuth == nil {
+			return err
+		}
+		if guestAuth.DeviceID != "" && guestAuth.DeviceID != params.Client.DeviceID {
+			return nil
+		}
+		u, err = work.Users().FindByID(ctx, guestAuth.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.DeletedAt != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return s.startSession(ctx, u, []string{string(auth.Guest)}, params.Client)
+}
+
+// upgradeGuest turns a guest into a full user once they have a real login method. The guest
+// identity is removed, since it would otherwise let anyone with the guest token sign in to the
+// account.
+func (s *Service) upgradeGuest(
+	ctx context.Context,
+	work unitofwork.UserAuthWork,
+	userID user.UserID,
+) error {
+	u, err := work.Users().FindByID(ctx, userID)
+	if err != nil || u == nil {
+		return err
+	}
+	if u.IsGuest {
+		u.IsGuest = false
+		u.UpdatedAt = time.Now()
+		if err := work.Users().Update(ctx, u); err != nil {
+			return err
+		}
+		s.logger.Info("Guest user upgraded", "userID", userID)
+	}
+	return s.removeGuestIdentities(ctx, work, userID)
+}
+
+func (s *Service) removeGuestIdentities(
+	ctx context.Context,
+	work unitofwork.UserAuthWork,
+	userID user.UserID,
+) error {
+	auths, err := work.Auths().ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, a := range auths {
+		if a.Provider != auth.Guest {
+			continue
+		}
+		if err := work.Auths().Delete(ctx, a.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeStaleGuests permanently deletes guests who have not used the app for the configured
+// number of days, along with their uploaded avatars, and returns how many were deleted.
+func (s *Service) PurgeStaleGuests(ctx context.Context) (int64, error) {
+	inactiveSince := time.Now().AddDate(0, 0, -s.guestConfig.MaxInactiveDays)
+
+	var total int64
+	for {
+		var deleted []*user.User
+		err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+			var err error
+			deleted, err = work.Users().DeleteStaleGuests(ctx, inactiveSince, guestPurgeBatch)
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+		for _, u := range deleted {
+			s.avatarService.DeleteUploaded(u)
+		}
+		total += int64(len(deleted))
+		if len(deleted) < guestPurgeBatch {
+			return total, nil
+		}
+	}
+}
+
+// RunGuestPurge purges stale guests at the configured interval until ctx is done. When several
+// servers run it, a lock in Redis makes sure only one of them purges at a time.
+func (s *Service) RunGuestPurge(ctx context.Context) {
+	interval := time.Duration(s.guestConfig.PurgeIntervalMinutes) * time.Minute
+	if interval <= 0 || s.guestConfig.MaxInactiveDays <= 0 {
+		s.logger.Info("Guest purge disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		acquired, err := s.redisClient.SetNX(ctx, guestPurgeLockKey, 1, interval/2).Result()
+		if err != nil {
+			s.logger.Error("Failed to acquire guest purge lock", "error", err)
+			continue
+		}
+		if !acquired {
+			continue
+		}
+
+		deleted, err := s.PurgeStaleGuests(ctx)
+		if err != nil {
+			s.logger.Error("Failed to purge stale guests", "deleted", deleted, "error", err)
+			continue
+		}
+		s.logger.Info("Stale guests purged", "deleted", deleted)
+	}
+}
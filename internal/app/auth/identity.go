@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+// providerIdentity is an account at an external identity provider, as asserted by the provider.
+type providerIdentity struct {
+	Provider       auth.Provider
+	ProviderID     string
+	UnionID        string
+	Email          string
+	EmailIsPrivate bool
+	EmailVerified  bool   // Whether the provider vouches that the user controls Email
+	DeviceID       string // The device a guest identity is bound to
+}
+
+// findOrCreateUserByIdentity finds the user who owns a provider identity, or creates a new user
+// with that identity. initUser, if not nil, fills in the profile of a newly created user.
+func (s *Service) findOrCreateUserByIdentity(
+	ctx context.Context,
+	identity providerIdentity,
+	source user.Source,
+	initUser func(u *user.User),
+) (u *user.User, created bool, err error) {
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		existingAuth, err := work.Auths().FindByProvider(
+			ctx,
+			identity.Provider,
+			identity.ProviderID,
+		)
+		if err != nil {
+			return err
+		}
+
+		if existingAuth != nil {
+			foundUser, err := work.Users().FindByID(ctx, existingAuth.UserID)
+			if err != nil {
+				return err
+			}
+			if foundUser == nil {
+				// This indicates data inconsistency and should not happen.
+				return errors.New("auth record found but user is missing")
+			}
+			u = foundUser
+			return nil
+		}
+
+		now := time.Now()
+		newUser := &user.User{
+			ID:        user.UserID(uuid.New().String()),
+			Source:    source,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if initUser != nil {
+			initUser(newUser)
+		}
+		if err := work.Users().Create(ctx, newUser); err != nil {
+			return err
+		}
+
+		newAuth := &auth.Auth{
+			ID:             auth.AuthID(uuid.New().String()),
+			UserID:         newUser.ID,
+			Provider:       identity.Provider,
+			ProviderID:     identity.ProviderID,
+			UnionID:        identity.UnionID,
+			Email:          identity.Email,
+			EmailIsPrivate: identity.EmailIsPrivate,
+			DeviceID:       identity.DeviceID,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if identity.EmailVerified && identity.Email != "" {
+			newAuth.EmailVerifiedAt = &now
+		}
+		if err := work.Auths().Create(ctx, newAuth); err != nil {
+			return err
+		}
+
+		u = newUser
+		created = true
+		return nil
+	})
+	return u, created, err
+}
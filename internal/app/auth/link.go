@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+// ListIdentities lists the login methods of a user, oldest first.
+func (s *Service) ListIdentities(ctx context.Context, userID user.UserID) ([]*auth.Auth, error) {
+	var auths []*auth.Auth
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		var err error
+		auths, err = work.Auths().ListByUserID(ctx, userID)
+		return err
+	})
+	return auths, err
+}
+
+// linkIdentity adds a provider identity to a user as another way to sign in. The caller must
+// have verified that the user owns the identity. A user can have one identity per provider,
+// except for the WeChat identities of one person in each of our WeChat apps.
+// beforeCreate, if not nil, runs in the same transaction before the identity is saved. If the
+// identity belongs to another user, a MergeRequiredError is returned, except for password
+// identities, whose owner the caller has not proved to be. Linking an identity to a
+// guest upgrades them to a full user, keeping their user ID and everything they own.
+func (s *Service) linkIdentity(
+	ctx context.Context,
+	userID user.UserID,
+	identity providerIdentity,
+	passwordHash string,
+	beforeCreate func(work unitofwork.UserAuthWork) error,
+) (*auth.Auth, error) {
+	var newAuth *auth.Auth
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		existingAuth, err := work.Auths().FindByProvider(
+			ctx,
+			identity.Provider,
+			identity.ProviderID,
+		)
+		if err != nil {
+			return err
+		}
+		if existingAuth != nil {
+			if existingAuth.UserID == userID {
+				return ErrProviderAlreadyLinked
+			}
+			// Anyone can type a username or email address, so it proves nothing about who owns
+			// the other account and must never lead to a merge.
+			if identity.Provider == auth.Password {
+				return ErrIdentityLinkedToAnotherUser
+			}
+			return &identityConflictError{ownerID: existingAuth.UserID}
+		}
+
+		auths, err := work.Auths().ListByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, a := range auths {
+			if a.Provider != identity.Provider {
+				continue
+			}
+			// A person has a different openid in each of our WeChat apps, all with the same
+			// unionid.
+			if a.Provider == auth.Wechat && a.UnionID != "" && a.UnionID == identity.UnionID {
+				continue
+			}
+			return ErrProviderAlreadyLinked
+		}
+
+		if beforeCreate != nil {
+			if err := beforeCreate(work); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		newAuth = &auth.Auth{
+			ID:             auth.AuthID(uuid.New().String()),
+			UserID:         userID,
+			Provider:       identity.Provider,
+			ProviderID:     identity.ProviderID,
+			UnionID:        identity.UnionID,
+			Email:          identity.Email,
+			EmailIsPrivate: identity.EmailIsPrivate,
+			PasswordHash:   passwordHash,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if identity.EmailVerified && identity.Email != "" {
+			newAuth.EmailVerifiedAt = &now
+		}
+		if err := work.Auths().Create(ctx, newAuth); err != nil {
+			return err
+		}
+		return s.upgradeGuest(ctx, work, userID)
+	})
+	var conflict *identityConflictError
+	if errors.As(err, &conflict) {
+		return nil, s.requireMerge(ctx, userID, conflict.ownerID)
+	}
+	if errors.Is(err, auth.ErrIdentityTaken) {
+		return nil, ErrIdentityLinkedToAnotherUser
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newAuth, nil
+}
+
+// LinkPhoneParams contains the parameters for adding a phone number as a login method.
+type LinkPhoneParams struct {
+	UserID      user.UserID
+	PhoneNumber string
+	Code        string // The verification code sent by SMS
+}
+
+// LinkPhone verifies the code sent to a phone number, then adds the number to the user as a
+// login method. The number also becomes the user's phone number, so a user who already has a
+// different number cannot link another one.
+func (s *Service) LinkPhone(ctx context.Context, params LinkPhoneParams) (*auth.Auth, error) {
+	phoneNumber, err := normalizePhoneNumber(params.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySMSCode(ctx, phoneNumber, params.Code); err != nil {
+		return nil, err
+	}
+
+	identity := providerIdentity{Provider: auth.Phone, ProviderID: phoneNumber}
+	bindPhoneNumber := func(work unitofwork.UserAuthWork) error {
+		u, err := work.Users().FindByID(ctx, params.UserID)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return errors.New("user not found")
+		}
+		if u.PhoneNumber == phoneNumber {
+			return nil
+		}
+		if u.PhoneNumber != "" {
+			return ErrPhoneNumberAlreadyBound
+		}
+		owner, err := work.Users().FindByPhoneNumber(ctx, phoneNumber)
+		if err != nil {
+			return err
+		}
+		if owner != nil {
+			return &identityConflictError{ownerID: owner.ID}
+		}
+
+		u.PhoneNumber = phoneNumber
+		u.UpdatedAt = time.Now()
+		return work.Users().Update(ctx, u)
+	}
+	a, err := s.linkIdentity(ctx, params.UserID, identity, "", bindPhoneNumber)
+	if errors.Is(err, user.ErrPhoneNumberTaken) {
+		return nil, ErrIdentityLinkedToAnotherUser
+	}
+	return a, err
+}
+
+// LinkWechatParams contains the parameters for adding a WeChat account as a login method.
+type LinkWechatParams struct {
+	UserID user.UserID
+	Code   string      // The code from Wechat OAuth
+	Source user.Source // Selects the WeChat app the code was issued by
+}
+
+// LinkWechat exchanges a WeChat code, then adds the WeChat account to the user as a login
+// method. An account whose unionid already belongs to another user cannot be linked.
+func (s *Service) LinkWechat(ctx context.Context, params LinkWechatParams) (*auth.Auth, error) {
+	wechatIdentity, err := s.exchangeWechatCode(ctx, params.Source, params.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := providerIdentity{
+		Provider:   auth.Wechat,
+		ProviderID: wechatIdentity.OpenID,
+		UnionID:    wechatIdentity.UnionID,
+	}
+	checkUnionID := func(work unitofwork.UserAuthWork) error {
+		if wechatIdentity.UnionID == "" {
+			return nil
+		}
+		linkedAuth, err := work.Auths().FindByUnionID(ctx, auth.Wechat, wechatIdentity.UnionID)
+		if err != nil {
+			return err
+		}
+		if linkedAuth != nil && linkedAuth.UserID != params.UserID {
+			return &identityConflictError{ownerID: linkedAuth.UserID}
+		}
+		return nil
+	}
+	a, err := s.linkIdentity(ctx, params.UserID, identity, "", checkUnionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if wechatIdentity.SessionKey != "" {
+		err := s.storeWechatSessionKey(ctx, params.UserID, wechatIdentity.SessionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store wechat session key: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// LinkGoogle verifies a Google ID token, then adds the Google account to the user as a login
+// method.
+func (s *Service) LinkGoogle(
+	ctx context.Context,
+	userID user.UserID,
+	idToken string,
+) (*auth.Auth, error) {
+	identity, _, err := s.verifyGoogleIDToken(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.linkIdentity(ctx, userID, identity, "", nil)
+}
+
+// LinkApple verifies an Apple identity token, then adds the Apple ID to the user as a login
+// method.
+func (s *Service) LinkApple(
+	ctx context.Context,
+	userID user.UserID,
+	idToken string,
+	nonce string,
+) (*auth.Auth, error) {
+	identity, err := s.verifyAppleIDToken(ctx, idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return s.linkIdentity(ctx, userID, identity, "", nil)
+}
+
+// LinkPasswordParams contains the parameters for adding a password login to a user.
+type LinkPasswordParams struct {
+	UserID     user.UserID
+	Identifier string // A username or email address
+	Password   string
+}
+
+// LinkPassword lets a user sign in with a username or email address and a password. Email
+// addresses are sent a verification link.
+func (s *Service) LinkPassword(ctx context.Context, params LinkPasswordParams) (*auth.Auth, error) {
+	identifier, isEmail, err := normalizeIdentifier(params.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validatePasswordStrength(params.Password, identifier); err != nil {
+		return nil, err
+	}
+	passwordHash, err := s.passwordHasher.Hash(params.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	identity := providerIdentity{Provider: auth.Password, ProviderID: identifier}
+	if isEmail {
+		identity.Email = identifier
+	}
+	a, err := s.linkIdentity(ctx, params.UserID, identity, passwordHash, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if isEmail {
+		if err := s.sendEmailVerification(ctx, a); err != nil {
+			s.logger.Warn("Failed to send email verification", "authID", a.ID, "error", err)
+		}
+	}
+	return a, nil
+}
+
+// UnlinkIdentity removes every identity of the provider from the user. The user must keep at
+// least one other way to sign in. Unlinking the phone provider also clears the user's phone
+// number, since phone login finds users by their number.
+func (s *Service) UnlinkIdentity(
+	ctx context.Context,
+	userID user.UserID,
+	provider auth.Provider,
+) error {
+	return s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		auths, err := work.Auths().ListByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		var matching []*auth.Auth
+		for _, a := range auths {
+			if a.Provider == provider {
+				matching = append(matching, a)
+			}
+		}
+		if len(matching) == 0 {
+			return ErrIdentityNotFound
+		}
+		if len(matching) == len(auths) {
+			return ErrLastIdentity
+		}
+
+		for _, a := range matching {
+			if err := work.Auths().Delete(ctx, a.ID); err != nil {
+				return err
+			}
+		}
+
+		if provider != auth.Phone {
+			return nil
+		}
+		u, err := work.Users().FindByID(ctx, userID)
+		if err != nil || u == nil {
+			return err
+		}
+		u.PhoneNumber = ""
+		u.UpdatedAt = time.Now()
+		return work.Users().Update(ctx, u)
+	})
+}
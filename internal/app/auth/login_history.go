@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+
+	appLoginHistory "github.com/moriverse/45-server/internal/app/loginhistory"
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+// recordLogin adds a login attempt to the login history. A login that is waiting for its
+// second factor is recorded once the second factor has been checked, and only failures caused
+// by wrong credentials are recorded.
+func (s *Service) recordLogin(
+	ctx context.Context,
+	attempt loginAttempt,
+	result *RegisterResult,
+	err error,
+) {
+	provider := attempt.Provider
+	if provider == "" {
+		provider = attempt.Method
+	}
+	params := appLoginHistory.RecordParams{
+		Provider:  provider,
+		IPAddress: attempt.Client.IPAddress,
+		UserAgent: attempt.Client.UserAgent,
+		DeviceID:  attempt.Client.DeviceID,
+		Source:    attempt.Source,
+	}
+
+	switch {
+	case err == nil && result.MFA != nil:
+		s.rememberMFALogin(ctx, result.MFA.Token, attempt.Source)
+		return
+	case err == nil:
+		params.UserID = result.User.ID
+		params.Success = true
+	case isCredentialFailure(err):
+		params.UserID = s.attemptedUserID(ctx, attempt)
+		params.FailureReason = err.Error()
+	default:
+		return
+	}
+	s.loginHistory.Record(ctx, params)
+}
+
+// attemptedUserID finds the account a failed login tried to sign in to, so that the failure
+// shows in its owner's login history.
+func (s *Service) attemptedUserID(ctx context.Context, attempt loginAttempt) user.UserID {
+	if attempt.UserID != "" || attempt.Identity == "" {
+		return attempt.UserID
+	}
+
+	provider := auth.Provider(attempt.Method)
+	var found *auth.Auth
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		var err error
+		found, err = work.Auths().FindByProvider(ctx, provider, attempt.Identity)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to find the account of a failed login", "error", err)
+		return ""
+	}
+	if found == nil {
+		return ""
+	}
+	return found.UserID
+}
+
+// rememberMFALogin stores the source of the first step of a login with its MFA challenge.
+func (s *Service) rememberMFALogin(ctx context.Context, token string, source user.Source) {
+	key := mfaChallengeKey(token)
+	err := s.redisClient.HSet(ctx, key, mfaChallengeSourceField, string(source)).Err()
+	if err != nil {
+		s.logger.Error("Failed to store mfa login details", "error", err)
+	}
+}
+
+// describeMFALogin fills in the user, provider and source of the login an MFA challenge
+// belongs to.
+func (s *Service) describeMFALogin(ctx context.Context, token string, attempt *loginAttempt) {
+	values, err := s.redisClient.HMGet(
+		ctx,
+		mfaChallengeKey(token),
+		mfaChallengeUserIDField,
+		mfaChallengeProviderField,
+		mfaChallengeSourceField,
+	).Result()
+	if err != nil {
+		s.logger.Error("Failed to load mfa login details", "error", err)
+		return
+	}
+	userID, _ := values[0].(string)
+	provider, _ := values[1].(string)
+	source, _ := values[2].(string)
+	attempt.UserID = user.UserID(userID)
+	attempt.Provider = provider
+	attempt.Source = user.Source(source)
+}
@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/accountmerge"
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+const (
+	mergeRequestKeyPrefix = "merge-request"
+	mergeRequestTTL       = 10 * time.Minute
+)
+
+// MergeRequiredError is returned when a user links an identity that belongs to another user.
+// Having proven they own the identity, the user may merge the other account into theirs by
+// confirming with MergeToken.
+type MergeRequiredError struct {
+	MergeToken string
+	ExpiresIn  time.Duration
+	// Providers are the login methods of the other account, to help the user recognize it.
+	Providers []auth.Provider
+	// MFARequired is set when the other account has two-factor authentication enabled, so
+	// that confirming the merge takes its second factor.
+	MFARequired bool
+}
+
+func (e *MergeRequiredError) Error() string {
+	return ErrIdentityLinkedToAnotherUser.Error()
+}
+
+func (e *MergeRequiredError) Unwrap() error {
+	return ErrIdentityLinkedToAnotherUser
+}
+
+// identityConflictError reports that an identity being linked belongs to another user.
+type identityConflictError struct {
+	ownerID user.UserID
+}
+
+func (e *identityConflictError) Error() string {
+	return ErrIdentityLinkedToAnotherUser.Error()
+}
+
+func mergeRequestKey(token string) string {
+	return fmt.Sprintf("%s:%s", mergeRequestKeyPrefix, utils.HashToken(token))
+}
+
+// requireMerge records that the user may merge the other user into their account, and returns
+// the MergeRequiredError that lets them confirm it.
+func (s *Service) requireMerge(
+	ctx context.Context,
+	userID user.UserID,
+	otherUserID user.UserID,
+) error {
+	var auths []*auth.Auth
+	var mfaRequired bool
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		var err error
+		auths, err = work.Auths().ListByUserID(ctx, otherUserID)
+		if err != nil {
+			return err
+		}
+		totp, err := work.MFA().FindTOTPByUserID(ctx, otherUserID)
+		mfaRequired = totp != nil && totp.IsEnabled()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate merge token: %w", err)
+	}
+	value := string(otherUserID) + ":" + string(userID)
+	err = s.redisClient.Set(ctx, mergeRequestKey(token), value, mergeRequestTTL).Err()
+	if err != nil {
+		return fmt.Errorf("failed to store merge request: %w", err)
+	}
+
+	providers := make([]auth.Provider, 0, len(auths))
+	seen := make(map[auth.Provider]bool)
+	for _, a := range auths {
+		if !seen[a.Provider] {
+			seen[a.Provider] = true
+			providers = append(providers, a.Provider)
+		}
+	}
+	return &MergeRequiredError{
+		MergeToken:  token,
+		ExpiresIn:   mergeRequestTTL,
+		Providers:   providers,
+		MFARequired: mfaRequired,
+	}
+}
+
+// ConfirmMerge merges the account named by a merge token into the current user's account. The
+// token can only be redeemed once, by the user it was issued to. The identity the user linked
+// proves only one factor, so an account with two-factor authentication enabled also takes its
+// second factor, mfaCode. A wrong code uses up the token.
+func (s *Service) ConfirmMerge(
+	ctx context.Context,
+	userID user.UserID,
+	mergeToken string,
+	mfaCode string,
+) error {
+	key := mergeRequestKey(mergeToken)
+	value, err := s.redisClient.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return ErrInvalidMergeToken
+	}
+	if err != nil {
+		return err
+	}
+
+	sourceID, targetID, ok := strings.Cut(value, ":")
+	if !ok || user.UserID(targetID) != userID {
+		return ErrInvalidMergeToken
+	}
+	mfaEnabled, err := s.isMFAEnabled(ctx, user.UserID(sourceID))
+	if err != nil {
+		return err
+	}
+	if mfaEnabled && strings.TrimSpace(mfaCode) == "" {
+		return ErrMFACodeRequired
+	}
+
+	// Only the first caller to delete the key may use the token.
+	deleted, err := s.redisClient.Del(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrInvalidMergeToken
+	}
+
+	if mfaEnabled {
+		err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+			return s.verifySecondFactor(ctx, work, user.UserID(sourceID), mfaCode)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.MergeUsers(ctx, MergeUsersParams{
+		SourceUserID: user.UserID(sourceID),
+		TargetUserID: userID,
+		InitiatedBy:  userID,
+		Reason:       accountmerge.SelfService,
+	})
+}
+
+// MergeUsersParams contains the parameters for merging one user into another.
+type MergeUsersParams struct {
+	SourceUserID user.UserID // The user to merge and delete
+	TargetUserID user.UserID // The user who remains
+	InitiatedBy  user.UserID
+	Reason       accountmerge.Reason
+}
+
+// MergeUsers merges the source user into the target user in a single transaction. The source
+// user's identities, pending verification tokens, roles, login history, OAuth grants and
+// consents, and API keys move to the target user, profile fields the target user lacks are
+// copied over, the source user is deleted and signed out everywhere, and the merge is recorded
+// for auditing. The target user takes over the source user's two-factor authentication unless
+// they have enabled their own. A user has one phone number, so the source user's phone
+// identity is dropped if the target user already has a different number. The source user's
+// uploaded avatar is deleted unless the target user takes it over.
+func (s *Service) MergeUsers(ctx context.Context, params MergeUsersParams) error {
+	if params.SourceUserID == params.TargetUserID {
+		return ErrCannotMergeSameUser
+	}
+
+	// The source user's uploaded avatar, unless the target user takes it over
+	var orphanedAvatar *user.User
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		source, err := work.Users().FindByID(ctx, params.SourceUserID)
+		if err != nil {
+			return err
+		}
+		target, err := work.Users().FindByID(ctx, params.TargetUserID)
+		if err != nil {
+			return err
+		}
+		if source == nil || source.DeletedAt != nil || target == nil || target.DeletedAt != nil {
+			return ErrMergeUserNotFound
+		}
+
+		now := time.Now()
+
+		// 1. Move the phone number, which must be unique, before anything else
+		phoneNumber := source.PhoneNumber
+		if phoneNumber != "" {
+			source.PhoneNumber = ""
+			source.UpdatedAt = now
+			if err := work.Users().Update(ctx, source); err != nil {
+				return err
+			}
+		}
+		if phoneNumber != "" && target.PhoneNumber != "" && target.PhoneNumber != phoneNumber {
+			phoneAuth, err := work.Auths().FindByProvider(ctx, auth.Phone, phoneNumber)
+			if err != nil {
+				return err
+			}
+			if phoneAuth != nil {
+				if err := work.Auths().Delete(ctx, phoneAuth.ID); err != nil {
+					return err
+				}
+			}
+		}
+
+		// 2. Copy over the profile fields the target user lacks
+		if target.PhoneNumber == "" {
+			target.PhoneNumber = phoneNumber
+		}
+		if target.Nickname == "" {
+			target.Nickname = source.Nickname
+		}
+		if target.AvatarURL == "" {
+			target.AvatarURL = source.AvatarURL
+		}
+		if target.OnboardedAt == nil {
+			target.OnboardedAt = source.OnboardedAt
+		}
+		target.IsGuest = target.IsGuest && source.IsGuest
+		target.UpdatedAt = now
+		if err := work.Users().Update(ctx, target); err != nil {
+			return err
+		}
+		if target.AvatarURL != source.AvatarURL {
+			orphanedAvatar = source
+		}
+
+		// 3. Re-parent the data the source user owns
+		if err := work.Auths().ReassignUser(ctx, source.ID, target.ID); err != nil {
+			return err
+		}
+		if err := work.VerificationTokens().ReassignUser(ctx, source.ID, target.ID); err != nil {
+			return err
+		}
+		if err := work.Roles().ReassignUser(ctx, source.ID, target.ID); err != nil {
+			return err
+		}
+		if err := work.LoginEvents().ReassignUser(ctx, source.ID, target.ID); err != nil {
+			return err
+		}
+		if err := work.OAuth().ReassignUser(ctx, source.ID, target.ID); err != nil {
+			return err
+		}
+		if err := work.APIKeys().ReassignCreator(ctx, source.ID, target.ID); err != nil {
+			return err
+		}
+		if err := mergeMFA(ctx, work, source.ID, target.ID); err != nil {
+			return err
+		}
+		if !target.IsGuest {
+			if err := s.removeGuestIdentities(ctx, work, target.ID); err != nil {
+				return err
+			}
+		}
+
+		// 4. Delete the source user and record the merge
+		if err := work.Users().Delete(ctx, source.ID); err != nil {
+			return err
+		}
+		return work.AccountMerges().Create(ctx, &accountmerge.AccountMerge{
+			ID:           accountmerge.AccountMergeID(uuid.New().String()),
+			SourceUserID: source.ID,
+			TargetUserID: target.ID,
+			InitiatedBy:  params.InitiatedBy,
+			Reason:       params.Reason,
+			CreatedAt:    now,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if orphanedAvatar != nil {
+		s.avatarService.DeleteUploaded(orphanedAvatar)
+	}
+
+	s.logger.Info(
+		"Users merged",
+		"sourceUserID", params.SourceUserID,
+		"targetUserID", params.TargetUserID,
+		"initiatedBy", params.InitiatedBy,
+		"reason", params.Reason,
+	)
+	return s.sessionService.RevokeAll(ctx, params.SourceUserID)
+}
+
+// mergeMFA keeps two-factor authentication on after a merge: the target user takes over the
+// source user's TOTP enrollment and recovery codes unless they have enabled their own, in
+// which case the source user's are deleted.
+func mergeMFA(
+	ctx context.Context,
+	work unitofwork.UserAuthWork,
+	sourceID user.UserID,
+	targetID user.UserID,
+) error {
+	sourceTOTP, err := work.MFA().FindTOTPByUserID(ctx, sourceID)
+	if err != nil || sourceTOTP == nil {
+		return err
+	}
+	targetTOTP, err := work.MFA().FindTOTPByUserID(ctx, targetID)
+	if err != nil {
+		return err
+	}
+	if sourceTOTP.IsEnabled() && (targetTOTP == nil || !targetTOTP.IsEnabled()) {
+		return work.MFA().ReassignUser(ctx, sourceID, targetID)
+	}
+	if err := work.MFA().DeleteTOTP(ctx, sourceID); err != nil {
+		return err
+	}
+	return work.MFA().ReplaceRecoveryCodes(ctx, sourceID, nil)
+}
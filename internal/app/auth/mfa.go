@@ -0,0 +1,403 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/mfa"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+const (
+	mfaChallengeKeyPrefix   = "mfa-challenge"
+	mfaChallengeTTL         = 5 * time.Minute
+	mfaChallengeMaxAttempts = 5
+
+	mfaChallengeUserIDField   = "user_id"
+	mfaChallengeAttemptsField = "attempts"
+	mfaChallengeProviderField = "provider" // The login method of the first step
+	mfaChallengeSourceField   = "source"   // The source of the first step, for the login history
+
+	// amrMFA is the authentication method recorded on sessions for a second factor.
+	amrMFA = "mfa"
+
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghijkmnpqrstuvwxyz23456789" // No 0, 1, l or o
+)
+
+// MFAChallenge is issued instead of tokens when a user who has enabled two-factor
+// authentication proves their first factor. Token is exchanged for real tokens together with
+// a second factor.
+type MFAChallenge struct {
+	Token     string
+	ExpiresIn time.Duration
+}
+
+// TOTPEnrollment contains what the user needs to add our account to their authenticator app.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string // The otpauth URI, usually shown as a QR code
+}
+
+func mfaChallengeKey(token string) string {
+	return fmt.Sprintf("%s:%s", mfaChallengeKeyPrefix, utils.HashToken(token))
+}
+
+// isMFAEnabled reports whether the user must enter a second factor to sign in.
+func (s *Service) isMFAEnabled(ctx context.Context, userID user.UserID) (bool, error) {
+	var enabled bool
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		totp, err := work.MFA().FindTOTPByUserID(ctx, userID)
+		enabled = totp != nil && totp.IsEnabled()
+		return err
+	})
+	return enabled, err
+}
+
+// createMFAChallenge issues a short-lived token that stands for a login which is waiting for
+// its second factor, after a first step made with the given provider.
+func (s *Service) createMFAChallenge(
+	ctx context.Context,
+	userID user.UserID,
+	provider auth.Provider,
+) (*MFAChallenge, error) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate mfa token: %w", err)
+	}
+
+	key := mfaChallengeKey(token)
+	_, err = s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(
+			ctx,
+			key,
+			mfaChallengeUserIDField, string(userID),
+			mfaChallengeProviderField, string(provider),
+			mfaChallengeAttemptsField, 0,
+		)
+		pipe.Expire(ctx, key, mfaChallengeTTL)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+	return &MFAChallenge{Token: token, ExpiresIn: mfaChallengeTTL}, nil
+}
+
+// VerifyMFAParams contains the parameters for completing a login with a second factor.
+type VerifyMFAParams struct {
+	MFAToken string // The token from the first step of the login
+	Code     string // A TOTP code or a recovery code
+	Client   ClientInfo
+}
+
+// VerifyMFA completes a login that is waiting for its second factor. The challenge is
+// discarded after too many attempts, so the user has to sign in again.
+func (s *Service) VerifyMFA(ctx context.Context, params VerifyMFAParams) (*RegisterResult, error) {
+	attempt := loginAttempt{Method: mfaLoginMethod, Client: params.Client}
+	s.describeMFALogin(ctx, params.MFAToken, &attempt)
+	return s.throttleLogin(ctx, attempt, func() (*RegisterResult, error) {
+		return s.verifyMFA(ctx, params)
+	})
+}
+
+// verifyMFA completes the login without counting failed attempts.
+func (s *Service) verifyMFA(ctx context.Context, params VerifyMFAParams) (*RegisterResult, error) {
+	key := mfaChallengeKey(params.MFAToken)
+
+	// Count the attempt before checking the code, so that concurrent guesses cannot all slip
+	// under the limit.
+	attempts, ok, err := s.recordAttempt(ctx, key, mfaChallengeAttemptsField)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFAToken
+	}
+	if attempts > mfaChallengeMaxAttempts {
+		s.redisClient.Del(ctx, key)
+		return nil, ErrTooManyVerificationAttempts
+	}
+
+	stored, err := s.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	userID := user.UserID(stored[mfaChallengeUserIDField])
+	if userID == "" {
+		return nil, ErrInvalidMFAToken
+	}
+
+	var u *user.User
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		if err := s.verifySecondFactor(ctx, work, userID, params.Code); err != nil {
+			return err
+		}
+		var err error
+		u, err = work.Users().FindByID(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.DeletedAt != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	// Only the first caller to delete the key may complete the login.
+	deleted, err := s.redisClient.Del(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, ErrInvalidMFAToken
+	}
+
+	amr := []string{stored[mfaChallengeProviderField], amrMFA}
+	return s.startSession(ctx, u, amr, params.Client)
+}
+
+// verifySecondFactor checks a TOTP code or a recovery code for a user with two-factor
+// authentication enabled. Each code is accepted only once.
+func (s *Service) verifySecondFactor(
+	ctx context.Context,
+	work unitofwork.UserAuthWork,
+	userID user.UserID,
+	code string,
+) error {
+	totp, err := work.MFA().FindTOTPByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if totp == nil || !totp.IsEnabled() {
+		return ErrMFANotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now()); ok {
+		if step <= totp.LastUsedStep {
+			return ErrInvalidMFACode
+		}
+		totp.LastUsedStep = step
+		totp.UpdatedAt = time.Now()
+		return work.MFA().SaveTOTP(ctx, totp)
+	}
+
+	used, err := work.MFA().UseRecoveryCode(ctx, userID, hashRecoveryCode(code), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	s.logger.Info("Recovery code used", "userID", userID)
+	return nil
+}
+
+// BeginTOTPEnrollment generates a new TOTP secret for the user. Two-factor authentication is
+// only enabled once the user confirms it with a code from their authenticator app.
+func (s *Service) BeginTOTPEnrollment(
+	ctx context.Context,
+	userID user.UserID,
+) (*TOTPEnrollment, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	var accountName string
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		existing, err := work.MFA().FindTOTPByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.IsEnabled() {
+			return ErrMFAAlreadyEnabled
+		}
+
+		u, err := work.Users().FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return errors.New("user not found")
+		}
+		accountName = mfaAccountName(u)
+
+		now := time.Now()
+		return work.MFA().SaveTOTP(ctx, &mfa.TOTP{
+			UserID:    userID,
+			Secret:    secret,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    utils.TOTPURI(s.mfaConfig.Issuer, accountName, secret),
+	}, nil
+}
+
+// mfaAccountName labels our entry in the user's authenticator app.
+func mfaAccountName(u *user.User) string {
+	switch {
+	case u.PhoneNumber != "":
+		return u.PhoneNumber
+	case u.Nickname != "":
+		return u.Nickname
+	default:
+		return string(u.ID)
+	}
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication once the user proves their
+// authenticator app produces valid codes, and returns the user's recovery codes. The codes are
+// only stored hashed, so this is the only time they can be shown.
+func (s *Service) ConfirmTOTPEnrollment(
+	ctx context.Context,
+	userID user.UserID,
+	code string,
+) ([]string, error) {
+	var recoveryCodes []string
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		totp, err := work.MFA().FindTOTPByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if totp == nil {
+			return ErrMFAEnrollmentNotFound
+		}
+		if totp.IsEnabled() {
+			return ErrMFAAlreadyEnabled
+		}
+
+		step, ok := utils.ValidateTOTP(totp.Secret, strings.TrimSpace(code), time.Now())
+		if !ok {
+			return ErrInvalidMFACode
+		}
+
+		now := time.Now()
+		totp.ConfirmedAt = &now
+		totp.LastUsedStep = step
+		totp.UpdatedAt = now
+		if err := work.MFA().SaveTOTP(ctx, totp); err != nil {
+			return err
+		}
+
+		recoveryCodes, err = s.replaceRecoveryCodes(ctx, work, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, invalidating the old ones. The
+// user must enter a current second factor.
+func (s *Service) RegenerateRecoveryCodes(
+	ctx context.Context,
+	userID user.UserID,
+	code string,
+) ([]string, error) {
+	var recoveryCodes []string
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		if err := s.verifySecondFactor(ctx, work, userID, code); err != nil {
+			return err
+		}
+		var err error
+		recoveryCodes, err = s.replaceRecoveryCodes(ctx, work, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// DisableTOTP turns off two-factor authentication. The user must enter a current second
+// factor, so that a stolen session alone cannot remove it.
+func (s *Service) DisableTOTP(ctx context.Context, userID user.UserID, code string) error {
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		if err := s.verifySecondFactor(ctx, work, userID, code); err != nil {
+			return err
+		}
+		if err := work.MFA().DeleteTOTP(ctx, userID); err != nil {
+			return err
+		}
+		return work.MFA().ReplaceRecoveryCodes(ctx, userID, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Two-factor authentication disabled", "userID", userID)
+	return nil
+}
+
+func (s *Service) replaceRecoveryCodes(
+	ctx context.Context,
+	work unitofwork.UserAuthWork,
+	userID user.UserID,
+) ([]string, error) {
+	now := time.Now()
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]*mfa.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		codes = append(codes, code)
+		records = append(records, &mfa.RecoveryCode{
+			ID:        mfa.RecoveryCodeID(uuid.New().String()),
+			UserID:    userID,
+			CodeHash:  hashRecoveryCode(code),
+			CreatedAt: now,
+		})
+	}
+
+	if err := work.MFA().ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random recovery code formatted as two dash-separated groups.
+func generateRecoveryCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := 0; i < recoveryCodeLength; i++ {
+		if i == recoveryCodeLength/2 {
+			sb.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// hashRecoveryCode hashes a recovery code for storage, ignoring case and separators.
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return utils.HashToken(normalized)
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatalf("generateRecoveryCode: %v", err)
+		}
+		if len(code) != recoveryCodeLength+1 || code[recoveryCodeLength/2] != '-' {
+			t.Fatalf("generateRecoveryCode = %q, want two dash-separated groups", code)
+		}
+		if strings.Trim(strings.Replace(code, "-", "", 1), recoveryCodeAlphabet) != "" {
+			t.Fatalf("generateRecoveryCode = %q, which has characters outside the alphabet", code)
+		}
+		if seen[code] {
+			t.Fatalf("generateRecoveryCode returned %q twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := hashRecoveryCode("abcde-fghjk")
+	tests := []struct {
+		name  string
+		code  string
+		equal bool
+	}{
+		{"same code", "abcde-fghjk", true},
+		{"uppercase", "ABCDE-FGHJK", true},
+		{"without the dash", "abcdefghjk", true},
+		{"with spaces", "abcde fghjk", true},
+		{"another code", "abcde-fghjm", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hashRecoveryCode(tt.code) == want; got != tt.equal {
+				t.Fatalf("hashRecoveryCode(%q) matches = %v, want %v", tt.code, got, tt.equal)
+			}
+		})
+	}
+}
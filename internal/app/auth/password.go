@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/notification"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+const (
+	// maxPasswordLength bounds the work spent hashing a password.
+	maxPasswordLength = 128
+
+	accountExistsEmailKeyPrefix = "account-exists-email"
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[a-z][a-z0-9_.]{2,31}$`)
+	emailPattern    = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+)
+
+// normalizeIdentifier lowercases a username or email address, so that the same account is
+// always found regardless of how the user typed it. isEmail reports which of the two it is.
+func normalizeIdentifier(identifier string) (normalized string, isEmail bool, err error) {
+	normalized = strings.ToLower(strings.TrimSpace(identifier))
+	if strings.Contains(normalized, "@") {
+		if len(normalized) > 254 || !emailPattern.MatchString(normalized) {
+			return "", false, ErrInvalidIdentifier
+		}
+		return normalized, true, nil
+	}
+	if !usernamePattern.MatchString(normalized) {
+		return "", false, ErrInvalidIdentifier
+	}
+	return normalized, false, nil
+}
+
+// validatePasswordStrength checks a new password against our strength rules: it must be long
+// enough, mix at least two kinds of characters and not contain the user's own name.
+func (s *Service) validatePasswordStrength(password, identifier string) error {
+	tooShort := utf8.RuneCountInString(password) < s.passwordConfig.MinLength
+	if tooShort || isPasswordTooLong(password) {
+		return ErrWeakPassword
+	}
+
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			classes++
+		}
+	}
+	if classes < 2 {
+		return ErrWeakPassword
+	}
+
+	name, _, _ := strings.Cut(identifier, "@")
+	if len(name) >= 3 && strings.Contains(strings.ToLower(password), name) {
+		return ErrWeakPassword
+	}
+	return nil
+}
+
+// RegisterWithPasswordParams contains the parameters for registering a user with a password.
+type RegisterWithPasswordParams struct {
+	Identifier string // A username or email address
+	Password   string
+	Source     user.Source
+	Client     ClientInfo
+}
+
+// RegisterWithPassword creates a new user who signs in with a username or email address and a
+// password. A new username signs the user in. An email address is not signed in, and instead is
+// sent a verification link, or a reminder that it already has an account, so that the response
+// does not reveal which addresses are registered. Usernames are public, so a taken one is
+// reported as ErrUserAlreadyExists.
+func (s *Service) RegisterWithPassword(
+	ctx context.Context,
+	params RegisterWithPasswordParams,
+) (*RegisterResult, error) {
+	identifier, isEmail, err := normalizeIdentifier(params.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validatePasswordStrength(params.Password, identifier); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(params.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var u *user.User
+	var passwordAuth, existingAuth *auth.Auth
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		existingAuth, err = work.Auths().FindByProvider(ctx, auth.Password, identifier)
+		if err != nil {
+			return err
+		}
+		if existingAuth != nil {
+			return ErrUserAlreadyExists
+		}
+
+		now := time.Now()
+		newUser := &user.User{
+			ID:        user.UserID(uuid.New().String()),
+			Source:    params.Source,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := work.Users().Create(ctx, newUser); err != nil {
+			return err
+		}
+
+		newAuth := &auth.Auth{
+			ID:           auth.AuthID(uuid.New().String()),
+			UserID:       newUser.ID,
+			Provider:     auth.Password,
+			ProviderID:   identifier,
+			PasswordHash: passwordHash,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		if isEmail {
+			newAuth.Email = identifier
+		}
+		if err := work.Auths().Create(ctx, newAuth); err != nil {
+			return err
+		}
+
+		u = newUser
+		passwordAuth = newAuth
+		return nil
+	})
+	if errors.Is(err, auth.ErrIdentityTaken) {
+		// Someone registered the same identifier at the same moment.
+		err = ErrUserAlreadyExists
+	}
+	if err == ErrUserAlreadyExists && isEmail {
+		if existingAuth != nil {
+			s.sendAccountExistsEmail(ctx, existingAuth)
+		}
+		return &RegisterResult{EmailSent: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !isEmail {
+		return s.completeLogin(ctx, u, auth.Password, params.Client)
+	}
+
+	// The account works without a verified address, so a failure here only means the user has
+	// to ask for another link.
+	if err := s.sendEmailVerification(ctx, passwordAuth); err != nil {
+		s.logger.Warn(
+			"Failed to send email verification",
+			"authID", passwordAuth.ID,
+			"error", err,
+		)
+	}
+	return &RegisterResult{EmailSent: true}, nil
+}
+
+// sendAccountExistsEmail tells the owner of an email address that someone tried to register it
+// again. Failures are logged rather than returned, since the caller must not learn about them.
+func (s *Service) sendAccountExistsEmail(ctx context.Context, a *auth.Auth) {
+	key := fmt.Sprintf("%s:%s", accountExistsEmailKeyPrefix, a.ID)
+	allowed, err := s.redisClient.SetNX(ctx, key, 1, verificationEmailResendInterval).Result()
+	if err == nil && allowed {
+		err = s.emailSender.SendEmail(ctx, notification.Email{
+			To:   a.ProviderID,
+			Type: notification.EmailAccountExists,
+		})
+	}
+	if err != nil {
+		s.logger.Warn("Failed to send account exists email", "authID", a.ID, "error", err)
+	}
+}
+
+// LoginWithPasswordParams contains the parameters for signing in a user with a password.
+type LoginWithPasswordParams struct {
+	Identifier string // A username or email address
+	Password   string
+	Client     ClientInfo
+}
+
+// LoginWithPassword signs in the user who owns the username or email address if the password
+// matches. An unknown account and a wrong password fail the same way, and take the same time,
+// so that callers cannot tell which accounts exist.
+func (s *Service) LoginWithPassword(
+	ctx context.Context,
+	params LoginWithPasswordParams,
+) (*RegisterResult, error) {
+	attempt := passwordLoginAttempt(params.Identifier, params.Client)
+	return s.throttleLogin(ctx, attempt, func() (*RegisterResult, error) {
+		return s.loginWithPassword(ctx, params)
+	})
+}
+
+// loginWithPassword signs the user in without counting failed attempts.
+func (s *Service) loginWithPassword(
+	ctx context.Context,
+	params LoginWithPasswordParams,
+) (*RegisterResult, error) {
+	if isPasswordTooLong(params.Password) {
+		// No password this long can be set, and hashing it would cost too much.
+		return nil, ErrInvalidCredentials
+	}
+
+	identifier, _, err := normalizeIdentifier(params.Identifier)
+	if err != nil {
+		s.passwordHasher.VerifyDummy(params.Password)
+		return nil, ErrInvalidCredentials
+	}
+
+	// 1. Find the password identity
+	var passwordAuth *auth.Auth
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		passwordAuth, err = work.Auths().FindByProvider(ctx, auth.Password, identifier)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if passwordAuth == nil || passwordAuth.PasswordHash == "" {
+		s.passwordHasher.VerifyDummy(params.Password)
+		return nil, ErrInvalidCredentials
+	}
+
+	// 2. Check the password
+	ok, needsRehash, err := s.passwordHasher.Verify(params.Password, passwordAuth.PasswordHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify password: %w", err)
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	// 3. Upgrade hashes made with an old algorithm or weaker parameters while we know the
+	// password. The user can still sign in if this fails.
+	if needsRehash {
+		s.rehashPassword(ctx, passwordAuth.ID, params.Password)
+	}
+
+	// 4. Generate tokens for the user
+	var u *user.User
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		u, err = work.Users().FindByID(ctx, passwordAuth.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		// This indicates data inconsistency and should not happen.
+		return nil, errors.New("auth record found but user is missing")
+	}
+	if u.DeletedAt != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return s.completeLogin(ctx, u, auth.Password, params.Client)
+}
+
+// isPasswordTooLong reports whether a password is longer than any we accept, so that it can be
+// rejected before it is hashed.
+func isPasswordTooLong(password string) bool {
+	return len(password) > maxPasswordLength*utf8.UTFMax ||
+		utf8.RuneCountInString(password) > maxPasswordLength
+}
+
+func (s *Service) rehashPassword(ctx context.Context, id auth.AuthID, password string) {
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err == nil {
+		err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+			return work.Auths().UpdatePasswordHash(ctx, id, passwordHash)
+		})
+	}
+	if err != nil {
+		s.logger.Warn("Failed to rehash password", "authID", id, "error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/notification"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/domain/verificationtoken"
+)
+
+// RequestPasswordReset emails a password reset link to the address of the password identity
+// with the given username or email address. It succeeds silently for unknown accounts,
+// accounts without an email address and repeated requests, so that callers cannot tell which
+// accounts exist.
+func (s *Service) RequestPasswordReset(ctx context.Context, identifier string) error {
+	identifier, _, err := normalizeIdentifier(identifier)
+	if err != nil {
+		return nil
+	}
+
+	var passwordAuth *auth.Auth
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		passwordAuth, err = work.Auths().FindByProvider(ctx, auth.Password, identifier)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if passwordAuth == nil || passwordAuth.Email == "" {
+		return nil
+	}
+
+	allowed, err := s.allowVerificationEmail(ctx, passwordAuth.ID, verificationtoken.PasswordReset)
+	if err != nil || !allowed {
+		return err
+	}
+
+	return s.sendVerificationToken(
+		ctx,
+		passwordAuth,
+		verificationtoken.PasswordReset,
+		notification.EmailPasswordReset,
+		passwordResetTokenTTL,
+	)
+}
+
+// ResetPasswordParams contains the parameters for choosing a new password with a reset token.
+type ResetPasswordParams struct {
+	Token    string // The token from the password reset email
+	Password string
+}
+
+// ResetPassword replaces the password of the identity a reset token was issued for. Redeeming
+// the token proves the user controls the email address, so it is marked as verified. The user
+// is signed out everywhere, which locks out anyone who knew the old password.
+func (s *Service) ResetPassword(ctx context.Context, params ResetPasswordParams) error {
+	var userID user.UserID
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		token, passwordAuth, err := redeemVerificationToken(
+			ctx,
+			work,
+			params.Token,
+			verificationtoken.PasswordReset,
+		)
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			return ErrInvalidResetToken
+		}
+
+		if err := s.validatePasswordStrength(params.Password, passwordAuth.ProviderID); err != nil {
+			return err
+		}
+		passwordHash, err := s.passwordHasher.Hash(params.Password)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+
+		now := time.Now()
+		if err := work.Auths().UpdatePasswordHash(ctx, passwordAuth.ID, passwordHash); err != nil {
+			return err
+		}
+		if passwordAuth.EmailVerifiedAt == nil {
+			if err := work.Auths().MarkEmailVerified(ctx, passwordAuth.ID, now); err != nil {
+				return err
+			}
+		}
+		if err := work.VerificationTokens().InvalidateAll(
+			ctx,
+			passwordAuth.ID,
+			verificationtoken.PasswordReset,
+			now,
+		); err != nil {
+			return err
+		}
+
+		userID = passwordAuth.UserID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.sessionService.RevokeAll(ctx, userID)
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/session"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+// reauthLoginMethod throttles attempts to prove the identity of a signed-in user again.
+const reauthLoginMethod = "reauth"
+
+// ReauthenticateParams contains the parameters for proving who the user is again on a session
+// they are already signed in to. Only the fields of the chosen provider are used.
+type ReauthenticateParams struct {
+	UserID    user.UserID
+	SessionID session.SessionID
+	Provider  auth.Provider
+	Code      string      // The code sent by SMS, or the code from Wechat OAuth
+	Source    user.Source // Selects the WeChat app the code was issued by
+	Password  string
+	IDToken   string // A Google or Apple ID token
+	Nonce     string // The nonce the Apple ID token was requested with
+	MFACode   string // A TOTP code or a recovery code, if two-factor authentication is enabled
+	Client    ClientInfo
+}
+
+// Reauthenticate lets a signed-in user prove their identity again with any login method linked
+// to their account, so that they may perform operations that require a recent login. Users
+// with two-factor authentication enabled must also enter a second factor. The session's
+// authentication time is updated, and a new access token that carries it is returned; the
+// refresh token stays the same.
+func (s *Service) Reauthenticate(
+	ctx context.Context,
+	params ReauthenticateParams,
+) (*Tokens, error) {
+	attempt := loginAttempt{
+		Method:   reauthLoginMethod,
+		Identity: string(params.UserID),
+		Client:   params.Client,
+		Source:   params.Source,
+		UserID:   params.UserID,
+		Provider: string(params.Provider),
+	}
+	result, err := s.throttleLogin(ctx, attempt, func() (*RegisterResult, error) {
+		return s.reauthenticate(ctx, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result.Tokens, nil
+}
+
+// reauthenticate checks the user's credentials without counting failed attempts.
+func (s *Service) reauthenticate(
+	ctx context.Context,
+	params ReauthenticateParams,
+) (*RegisterResult, error) {
+	// 1. Check the first factor against the user's own identities
+	if err := s.verifyLinkedIdentity(ctx, params); err != nil {
+		return nil, err
+	}
+
+	mfaEnabled, err := s.isMFAEnabled(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaEnabled && params.MFACode == "" {
+		return nil, ErrMFACodeRequired
+	}
+
+	// 2. Check the second factor and record the new authentication on the session
+	amr := []string{string(params.Provider)}
+	if mfaEnabled {
+		amr = append(amr, amrMFA)
+	}
+
+	var u *user.User
+	var current *session.Session
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		if mfaEnabled {
+			if err := s.verifySecondFactor(ctx, work, params.UserID, params.MFACode); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		current, err = work.Sessions().FindByID(ctx, params.SessionID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if current == nil || current.UserID != params.UserID || !current.IsActive(now) {
+			return ErrSessionNotActive
+		}
+		u, err = work.Users().FindByID(ctx, params.UserID)
+		if err != nil {
+			return err
+		}
+		if u == nil || u.DeletedAt != nil {
+			return ErrSessionNotActive
+		}
+
+		current.AuthTime = now
+		current.AMR = amr
+		return work.Sessions().UpdateAuthentication(ctx, current.ID, now, amr)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Issue an access token with the new authentication time
+	accessToken, err := s.issueAccessToken(u, current)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("User re-authenticated", "userID", u.ID, "provider", params.Provider)
+	return &RegisterResult{
+		User:   u,
+		Tokens: Tokens{AccessToken: accessToken, ExpiresIn: s.accessTokenTTL()},
+	}, nil
+}
+
+// verifyLinkedIdentity checks credentials for one of the user's identities with the chosen
+// provider. Valid credentials for somebody else's identity are treated as wrong credentials.
+func (s *Service) verifyLinkedIdentity(ctx context.Context, params ReauthenticateParams) error {
+	auths, err := s.ListIdentities(ctx, params.UserID)
+	if err != nil {
+		return err
+	}
+	var linked []*auth.Auth
+	for _, a := range auths {
+		if a.Provider == params.Provider {
+			linked = append(linked, a)
+		}
+	}
+	if len(linked) == 0 {
+		return ErrIdentityNotFound
+	}
+
+	var matches func(a *auth.Auth) bool
+	switch params.Provider {
+	case auth.Phone:
+		return s.verifySMSCode(ctx, linked[0].ProviderID, params.Code)
+
+	case auth.Password:
+		if isPasswordTooLong(params.Password) {
+			return ErrInvalidCredentials
+		}
+		if linked[0].PasswordHash == "" {
+			s.passwordHasher.VerifyDummy(params.Password)
+			return ErrInvalidCredentials
+		}
+		ok, _, err := s.passwordHasher.Verify(params.Password, linked[0].PasswordHash)
+		if err != nil {
+			return fmt.Errorf("failed to verify password: %w", err)
+		}
+		if !ok {
+			return ErrInvalidCredentials
+		}
+		return nil
+
+	case auth.Wechat:
+		wechatIdentity, err := s.exchangeWechatCode(ctx, params.Source, params.Code)
+		if err != nil {
+			return err
+		}
+		matches = func(a *auth.Auth) bool {
+			return a.ProviderID == wechatIdentity.OpenID ||
+				(wechatIdentity.UnionID != "" && a.UnionID == wechatIdentity.UnionID)
+		}
+
+	case auth.Google:
+		identity, _, err := s.verifyGoogleIDToken(ctx, params.IDToken)
+		if err != nil {
+			return err
+		}
+		matches = func(a *auth.Auth) bool { return a.ProviderID == identity.ProviderID }
+
+	case auth.Apple:
+		identity, err := s.verifyAppleIDToken(ctx, params.IDToken, params.Nonce)
+		if err != nil {
+			return err
+		}
+		matches = func(a *auth.Auth) bool { return a.ProviderID == identity.ProviderID }
+
+	default:
+		return ErrReauthProviderUnsupported
+	}
+
+	for _, a := range linked {
+		if matches(a) {
+			return nil
+		}
+	}
+	return ErrInvalidCredentials
+}
@@ -4,91 +4,353 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 
+	appAvatar "github.com/moriverse/45-server/internal/app/avatar"
+	appLoginHistory "github.com/moriverse/45-server/internal/app/loginhistory"
+	appSession "github.com/moriverse/45-server/internal/app/session"
 	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/notification"
 	"github.com/moriverse/45-server/internal/domain/unitofwork"
 	"github.com/moriverse/45-server/internal/domain/user"
 	"github.com/moriverse/45-server/internal/infrastructure/config"
+	"github.com/moriverse/45-server/internal/infrastructure/oidc"
 	"github.com/moriverse/45-server/internal/infrastructure/wechat"
 	"github.com/moriverse/45-server/internal/utils"
 )
 
 // Service is the application service for authentication-related operations.
 type Service struct {
-	uow          unitofwork.UnitOfWork
-	jwtConfig    config.JWTConfig
-	wechatClient *wechat.Client
+	uow                 unitofwork.UnitOfWork
+	jwtConfig           config.JWTConfig
+	keys                *utils.KeySet
+	sessionService      *appSession.Service
+	loginHistory        *appLoginHistory.Service
+	avatarService       *appAvatar.Service
+	wechatClient        wechat.Client
+	googleVerifier      *oidc.Verifier
+	appleVerifier       *oidc.Verifier
+	passwordConfig      config.PasswordConfig
+	passwordHasher      *utils.PasswordHasher
+	mfaConfig           config.MFAConfig
+	loginThrottleConfig config.LoginThrottleConfig
+	guestConfig         config.GuestConfig
+	smsSender           notification.SMSSender
+	emailSender         notification.EmailSender
+	redisClient         *redis.Client
+	logger              *slog.Logger
 }
 
 // NewService creates a new instance of the auth service.
 func NewService(
 	uow unitofwork.UnitOfWork,
 	jwtConfig config.JWTConfig,
-	wechatClient *wechat.Client,
+	keys *utils.KeySet,
+	sessionService *appSession.Service,
+	loginHistory *appLoginHistory.Service,
+	avatarService *appAvatar.Service,
+	wechatClient wechat.Client,
+	googleVerifier *oidc.Verifier,
+	appleVerifier *oidc.Verifier,
+	passwordConfig config.PasswordConfig,
+	passwordHasher *utils.PasswordHasher,
+	mfaConfig config.MFAConfig,
+	loginThrottleConfig config.LoginThrottleConfig,
+	guestConfig config.GuestConfig,
+	smsSender notification.SMSSender,
+	emailSender notification.EmailSender,
+	redisClient *redis.Client,
+	logger *slog.Logger,
 ) *Service {
 	return &Service{
-		uow:          uow,
-		jwtConfig:    jwtConfig,
-		wechatClient: wechatClient,
+		uow:                 uow,
+		jwtConfig:           jwtConfig,
+		keys:                keys,
+		sessionService:      sessionService,
+		loginHistory:        loginHistory,
+		avatarService:       avatarService,
+		wechatClient:        wechatClient,
+		googleVerifier:      googleVerifier,
+		appleVerifier:       appleVerifier,
+		passwordConfig:      passwordConfig,
+		passwordHasher:      passwordHasher,
+		mfaConfig:           mfaConfig,
+		loginThrottleConfig: loginThrottleConfig,
+		guestConfig:         guestConfig,
+		smsSender:           smsSender,
+		emailSender:         emailSender,
+		redisClient:         redisClient,
+		logger:              logger,
 	}
 }
 
 // RegisterResult contains the result of a successful user registration.
 type RegisterResult struct {
-	User  *user.User
-	Token string
+	User *user.User
+	Tokens
+	// MFA is set instead of Tokens when the user must still enter a second factor.
+	MFA *MFAChallenge
+	// EmailSent is set instead of User and Tokens when a registration was answered by email,
+	// and the user must sign in separately.
+	EmailSent bool
+	// GuestToken is the secret a newly created guest signs in again with.
+	GuestToken string
 }
 
-// RegisterWithPhoneParams contains the parameters for registering a new user with a phone number.
-type RegisterWithPhoneParams struct {
+// SendPhoneVerificationCodeResult contains the result of issuing a phone verification code.
+type SendPhoneVerificationCodeResult struct {
+	ExpiresIn time.Duration
+}
+
+// SendPhoneVerificationCode generates a one-time code for the phone number and delivers it to
+// the user. Any previously issued code for the same number is invalidated.
+func (s *Service) SendPhoneVerificationCode(
+	ctx context.Context,
+	phoneNumber string,
+) (*SendPhoneVerificationCodeResult, error) {
+	phoneNumber, err := normalizePhoneNumber(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := generateNumericCode(smsCodeLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate verification code: %w", err)
+	}
+
+	// The code is sent before it is stored so that a throttled request does not invalidate a
+	// code the user has already received.
+	err = s.smsSender.SendSMS(ctx, notification.SMS{
+		PhoneNumber: phoneNumber,
+		Type:        notification.SMSVerificationCode,
+		Params: map[string]string{
+			"code":               code,
+			"expires_in_minutes": strconv.Itoa(int(smsCodeTTL.Minutes())),
+		},
+	})
+	if errors.Is(err, notification.ErrSMSThrottled) {
+		return nil, ErrSMSThrottled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to send verification code: %w", err)
+	}
+
+	if err := s.storeSMSCode(ctx, phoneNumber, code); err != nil {
+		return nil, fmt.Errorf("failed to store verification code: %w", err)
+	}
+
+	return &SendPhoneVerificationCodeResult{ExpiresIn: smsCodeTTL}, nil
+}
+
+// LoginOrRegisterWithPhoneParams contains the parameters for signing in a user via phone number.
+type LoginOrRegisterWithPhoneParams struct {
 	PhoneNumber string
-	Code        string
+	Code        string // The verification code sent by SMS
 	Source      user.Source
+	Client      ClientInfo
 }
 
-// RegisterWithPhone creates a new user and an auth record for them using a phone number and
-// verification code.
-func (s *Service) RegisterWithPhone(
+// LoginOrRegisterWithPhone verifies the code sent to a phone number, then finds the user who
+// owns that number or creates a new one if they don't exist.
+func (s *Service) LoginOrRegisterWithPhone(
 	ctx context.Context,
-	params RegisterWithPhoneParams,
+	params LoginOrRegisterWithPhoneParams,
 ) (*RegisterResult, error) {
-	// TODO: Implement phone registration logic
+	attempt := phoneLoginAttempt(params.PhoneNumber, params.Client)
+	attempt.Source = params.Source
+	return s.throttleLogin(ctx, attempt, func() (*RegisterResult, error) {
+		return s.loginOrRegisterWithPhone(ctx, params)
+	})
+}
+
+// loginOrRegisterWithPhone signs the user in without counting failed attempts.
+func (s *Service) loginOrRegisterWithPhone(
+	ctx context.Context,
+	params LoginOrRegisterWithPhoneParams,
+) (*RegisterResult, error) {
+	phoneNumber, err := normalizePhoneNumber(params.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
 	// 1. Verify the code
-	// 2. Check if user with phone number already exists
-	// 3. Create user and auth records
-	// 4. Generate JWT
-	return nil, errors.New("phone registration not implemented")
+	if err := s.verifySMSCode(ctx, phoneNumber, params.Code); err != nil {
+		return nil, err
+	}
+
+	var u *user.User
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		// 2. Check if a user with this phone number already exists
+		foundUser, err := work.Users().FindByPhoneNumber(ctx, phoneNumber)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if foundUser == nil {
+			// 3. User does not exist, so we're creating them.
+			foundUser = &user.User{
+				ID:          user.UserID(uuid.New().String()),
+				PhoneNumber: phoneNumber,
+				Source:      params.Source,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			if err := work.Users().Create(ctx, foundUser); err != nil {
+				return err
+			}
+		}
+		if foundUser.DeletedAt != nil {
+			return ErrInvalidCredentials
+		}
+
+		// Users who bound their phone number through another provider may not have a phone
+		// auth record yet.
+		existingAuth, err := work.Auths().FindByProvider(ctx, auth.Phone, phoneNumber)
+		if err != nil {
+			return err
+		}
+		if existingAuth == nil {
+			newAuth := &auth.Auth{
+				ID:         auth.AuthID(uuid.New().String()),
+				UserID:     foundUser.ID,
+				Provider:   auth.Phone,
+				ProviderID: phoneNumber,
+				CreatedAt:  now,
+				UpdatedAt:  now,
+			}
+			if err := work.Auths().Create(ctx, newAuth); err != nil {
+				return err
+			}
+		} else if existingAuth.UserID != foundUser.ID {
+			// This indicates data inconsistency and should not happen.
+			return errors.New("phone auth record belongs to a different user")
+		}
+
+		u = foundUser
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Generate tokens for the found or created user
+	return s.completeLogin(ctx, u, auth.Phone, params.Client)
 }
 
 // LoginOrRegisterWithWechatParams contains the parameters for signing in a user via Wechat.
 type LoginOrRegisterWithWechatParams struct {
 	Code   string // The code from Wechat OAuth
 	Source user.Source
+	Client ClientInfo
 }
 
 // LoginOrRegisterWithWechat exchanges a Wechat code for an openid, then finds the corresponding
-// user or creates a new one if they don't exist.
+// user or creates a new one if they don't exist. A person has a different openid in each of our
+// WeChat apps, so an unknown openid is linked to the user who already owns its unionid.
 func (s *Service) LoginOrRegisterWithWechat(
 	ctx context.Context,
 	params LoginOrRegisterWithWechatParams,
+) (*RegisterResult, error) {
+	attempt := loginAttempt{Method: string(auth.Wechat), Client: params.Client}
+	attempt.Source = params.Source
+	return s.throttleLogin(ctx, attempt, func() (*RegisterResult, error) {
+		return s.loginOrRegisterWithWechat(ctx, params)
+	})
+}
+
+// loginOrRegisterWithWechat signs the user in without counting failed attempts.
+func (s *Service) loginOrRegisterWithWechat(
+	ctx context.Context,
+	params LoginOrRegisterWithWechatParams,
 ) (*RegisterResult, error) {
 	// 1. Exchange code for openID with Wechat API
-	openID, err := s.wechatClient.CodeToOpenID(ctx, params.Code)
+	identity, err := s.exchangeWechatCode(ctx, params.Source, params.Code)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.findOrCreateWechatUser(ctx, identity, params.Source)
+	if errors.Is(err, auth.ErrIdentityTaken) {
+		// Another request signed in with the same openid first, so its user can now be found.
+		u, err = s.findOrCreateWechatUser(ctx, identity, params.Source)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to exchange wechat code: %w", err)
+		return nil, err
 	}
 
+	if identity.SessionKey != "" {
+		if err := s.storeWechatSessionKey(ctx, u.ID, identity.SessionKey); err != nil {
+			return nil, fmt.Errorf("failed to store wechat session key: %w", err)
+		}
+	}
+
+	// 4. Generate tokens for the found or created user
+	return s.completeLogin(ctx, u, auth.Wechat, params.Client)
+}
+
+// findOrCreateWechatUser returns the user a WeChat identity belongs to, creating them on first
+// sign-in. Sign-ins with the same unionid are serialized, so that the first one through any of
+// our apps creates the user and the rest link to it.
+func (s *Service) findOrCreateWechatUser(
+	ctx context.Context,
+	identity *wechat.Identity,
+	source user.Source,
+) (*user.User, error) {
+	openID := identity.OpenID
+	unionID := identity.UnionID
+
 	var u *user.User
-	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		now := time.Now()
+
+		if unionID != "" {
+			if err := work.Auths().LockUnionID(ctx, auth.Wechat, unionID); err != nil {
+				return err
+			}
+		}
+
 		// 2. Check if an auth record with this openID already exists
 		existingAuth, err := work.Auths().FindByProvider(ctx, auth.Wechat, openID)
 		if err != nil {
 			return err
 		}
 
+		// Records created before the app was bound to our Open Platform account have no
+		// unionid yet.
+		if existingAuth != nil && existingAuth.UnionID == "" && unionID != "" {
+			if err := work.Auths().UpdateUnionID(ctx, existingAuth.ID, unionID); err != nil {
+				return err
+			}
+		}
+
+		// Otherwise, check if the same person has signed in through another of our WeChat apps
+		if existingAuth == nil && unionID != "" {
+			linkedAuth, err := work.Auths().FindByUnionID(ctx, auth.Wechat, unionID)
+			if err != nil {
+				return err
+			}
+			if linkedAuth != nil {
+				existingAuth = &auth.Auth{
+					ID:         auth.AuthID(uuid.New().String()),
+					UserID:     linkedAuth.UserID,
+					Provider:   auth.Wechat,
+					ProviderID: openID,
+					UnionID:    unionID,
+					CreatedAt:  now,
+					UpdatedAt:  now,
+				}
+				if err := work.Auths().Create(ctx, existingAuth); err != nil {
+					return err
+				}
+			}
+		}
+
 		if existingAuth != nil {
 			// User exists, so we're logging them in.
 			foundUser, err := work.Users().FindByID(ctx, existingAuth.UserID)
@@ -104,10 +366,9 @@ func (s *Service) LoginOrRegisterWithWechat(
 		}
 
 		// 3. User does not exist, so we're creating them.
-		now := time.Now()
 		newUser := &user.User{
 			ID:        user.UserID(uuid.New().String()),
-			Source:    params.Source,
+			Source:    source,
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
@@ -120,6 +381,7 @@ func (s *Service) LoginOrRegisterWithWechat(
 			UserID:     newUser.ID,
 			Provider:   auth.Wechat,
 			ProviderID: openID,
+			UnionID:    unionID,
 			CreatedAt:  now,
 			UpdatedAt:  now,
 		}
@@ -130,20 +392,8 @@ func (s *Service) LoginOrRegisterWithWechat(
 		u = newUser
 		return nil
 	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	// 4. Generate JWT for the found or created user
-	token, err := utils.GenerateToken(
-		string(u.ID),
-		s.jwtConfig.SecretKey,
-		s.jwtConfig.ExpiresInHours,
-	)
 	if err != nil {
 		return nil, err
 	}
-
-	return &RegisterResult{User: u, Token: token}, nil
+	return u, nil
 }
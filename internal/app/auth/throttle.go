@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+const (
+	loginFailuresKeyPrefix = "login-failures"
+	loginLockoutKeyPrefix  = "login-lockout"
+
+	// defaultThrottleRule applies to login methods without their own rule.
+	defaultThrottleRule = "default"
+	// mfaLoginMethod throttles the second step of a login with two-factor authentication.
+	mfaLoginMethod = "mfa"
+	// defaultMaxLockout applies to rules without a max_lockout_minutes.
+	defaultMaxLockout = time.Hour
+)
+
+// TooManyAttemptsError is returned when a login is refused because of too many recent failures
+// from the same address, device or account.
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *TooManyAttemptsError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// loginAttempt describes who is trying to sign in, for counting failed attempts and for the
+// login history.
+type loginAttempt struct {
+	Method   string // A provider, or mfaLoginMethod
+	Identity string // The phone number or username being signed in to, if known in advance
+	Client   ClientInfo
+	Source   user.Source
+	// UserID and Provider are known in advance for the second step of a login, which is
+	// recorded as a login with the provider of the first step.
+	UserID   user.UserID
+	Provider string
+}
+
+// throttleScope is one of the counters a login attempt is counted against.
+type throttleScope struct {
+	name        string
+	maxAttempts int
+	// resetOnSuccess is false for counters shared by many users, such as those of an IP address,
+	// so that an attacker cannot clear them by signing in to their own account.
+	resetOnSuccess bool
+}
+
+func (t throttleScope) failuresKey() string {
+	return fmt.Sprintf("%s:%s", loginFailuresKeyPrefix, t.name)
+}
+
+func (t throttleScope) lockoutKey() string {
+	return fmt.Sprintf("%s:%s", loginLockoutKeyPrefix, t.name)
+}
+
+func (s *Service) loginThrottleRule(method string) config.LoginThrottleRuleConfig {
+	if rule, ok := s.loginThrottleConfig.Rules[method]; ok {
+		return rule
+	}
+	return s.loginThrottleConfig.Rules[defaultThrottleRule]
+}
+
+func (s *Service) throttleScopes(
+	attempt loginAttempt,
+	rule config.LoginThrottleRuleConfig,
+) []throttleScope {
+	var scopes []throttleScope
+	add := func(kind, value string, maxAttempts int, resetOnSuccess bool) {
+		if value == "" || maxAttempts <= 0 {
+			return
+		}
+		scopes = append(scopes, throttleScope{
+			name:           fmt.Sprintf("%s:%s:%s", attempt.Method, kind, value),
+			maxAttempts:    maxAttempts,
+			resetOnSuccess: resetOnSuccess,
+		})
+	}
+	add("ip", attempt.Client.IPAddress, rule.IPMaxAttempts, false)
+	add("device", attempt.Client.DeviceID, rule.MaxAttempts, true)
+	add("identity", attempt.Identity, rule.MaxAttempts, true)
+	return scopes
+}
+
+// throttleLogin runs a login attempt unless its address, device or account is locked out, and
+// counts it if the credentials turn out to be wrong. Each failure past the limit locks the
+// login out for twice as long as the previous one.
+func (s *Service) throttleLogin(
+	ctx context.Context,
+	attempt loginAttempt,
+	login func() (*RegisterResult, error),
+) (*RegisterResult, error) {
+	rule := s.loginThrottleRule(attempt.Method)
+	scopes := s.throttleScopes(attempt, rule)
+
+	if err := s.checkLoginLockout(ctx, scopes); err != nil {
+		return nil, err
+	}
+
+	result, err := login()
+	s.recordLogin(ctx, attempt, result, err)
+	switch {
+	case err == nil:
+		s.resetLoginFailures(ctx, scopes)
+	case isCredentialFailure(err):
+		s.recordLoginFailure(ctx, scopes, rule)
+	}
+	return result, err
+}
+
+// checkLoginLockout returns a TooManyAttemptsError if any of the scopes is locked out.
+func (s *Service) checkLoginLockout(ctx context.Context, scopes []throttleScope) error {
+	if len(scopes) == 0 {
+		return nil
+	}
+
+	cmds := make([]*redis.DurationCmd, len(scopes))
+	_, err := s.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, scope := range scopes {
+			cmds[i] = pipe.PTTL(ctx, scope.lockoutKey())
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check login lockout: %w", err)
+	}
+
+	var retryAfter time.Duration
+	for _, cmd := range cmds {
+		if ttl := cmd.Val(); ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+	if retryAfter > 0 {
+		return &TooManyAttemptsError{RetryAfter: retryAfter.Round(time.Second)}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login against each scope, and locks out those that have
+// reached their limit. Errors are only logged, as they must not hide the failed login.
+func (s *Service) recordLoginFailure(
+	ctx context.Context,
+	scopes []throttleScope,
+	rule config.LoginThrottleRuleConfig,
+) {
+	window := time.Duration(rule.WindowMinutes) * time.Minute
+	for _, scope := range scopes {
+		failures, err := s.redisClient.Incr(ctx, scope.failuresKey()).Result()
+		if err != nil {
+			s.logger.Error("Failed to count login failure", "scope", scope.name, "error", err)
+			continue
+		}
+		if failures == 1 {
+			s.redisClient.Expire(ctx, scope.failuresKey(), window)
+		}
+		if failures < int64(scope.maxAttempts) {
+			continue
+		}
+
+		lockout := lockoutDuration(rule, int(failures)-scope.maxAttempts)
+		if lockout <= 0 {
+			// A zero TTL would lock the login out for good.
+			continue
+		}
+		if err := s.redisClient.Set(ctx, scope.lockoutKey(), 1, lockout).Err(); err != nil {
+			s.logger.Error("Failed to lock out login", "scope", scope.name, "error", err)
+			continue
+		}
+		// Keep counting until the lockout ends, so that the next one is longer.
+		if ttl := s.redisClient.TTL(ctx, scope.failuresKey()).Val(); ttl < lockout+window {
+			s.redisClient.Expire(ctx, scope.failuresKey(), lockout+window)
+		}
+		s.logger.Warn("Login locked out", "scope", scope.name, "failures", failures,
+			"lockout", lockout)
+	}
+}
+
+// lockoutDuration doubles the base lockout for each failure past the limit, up to the maximum.
+func lockoutDuration(rule config.LoginThrottleRuleConfig, excess int) time.Duration {
+	lockout := time.Duration(rule.LockoutSeconds) * time.Second
+	maxLockout := time.Duration(rule.MaxLockoutMinutes) * time.Minute
+	if maxLockout <= 0 {
+		maxLockout = defaultMaxLockout
+	}
+	for i := 0; i < excess && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > maxLockout {
+		lockout = maxLockout
+	}
+	return lockout
+}
+
+// resetLoginFailures clears the failures of the scopes that belong to the user who has just
+// signed in.
+func (s *Service) resetLoginFailures(ctx context.Context, scopes []throttleScope) {
+	var keys []string
+	for _, scope := range scopes {
+		if scope.resetOnSuccess {
+			keys = append(keys, scope.failuresKey(), scope.lockoutKey())
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := s.redisClient.Del(ctx, keys...).Err(); err != nil {
+		s.logger.Error("Failed to reset login failures", "error", err)
+	}
+}
+
+// isCredentialFailure reports whether a login failed because the client proved nothing, as
+// opposed to failing for a reason outside their control.
+func isCredentialFailure(err error) bool {
+	for _, target := range []error{
+		ErrInvalidCredentials,
+		ErrInvalidVerificationCode,
+		ErrTooManyVerificationAttempts,
+		ErrInvalidWechatCode,
+		ErrInvalidIDToken,
+		ErrInvalidMFAToken,
+		ErrInvalidMFACode,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// phoneLoginAttempt describes an attempt to sign in with a phone number.
+func phoneLoginAttempt(phoneNumber string, client ClientInfo) loginAttempt {
+	normalized, _ := normalizePhoneNumber(phoneNumber)
+	return loginAttempt{Method: string(auth.Phone), Identity: normalized, Client: client}
+}
+
+// passwordLoginAttempt describes an attempt to sign in with a username or email address.
+func passwordLoginAttempt(identifier string, client ClientInfo) loginAttempt {
+	normalized, _, _ := normalizeIdentifier(identifier)
+	return loginAttempt{Method: string(auth.Password), Identity: normalized, Client: client}
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/refreshtoken"
+	"github.com/moriverse/45-server/internal/domain/session"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+// Tokens contains the credentials issued to a signed-in user.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration // Lifetime of the access token
+}
+
+// ClientInfo describes the device a request was made from.
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+	DeviceID  string
+}
+
+func (s *Service) accessTokenTTL() time.Duration {
+	return time.Duration(s.jwtConfig.AccessTokenExpiresInMinutes) * time.Minute
+}
+
+func (s *Service) refreshTokenTTL() time.Duration {
+	return time.Duration(s.jwtConfig.RefreshTokenExpiresInDays) * 24 * time.Hour
+}
+
+// issueAccessToken generates an access token for a session. The token carries the time and
+// methods of the session's last authentication. Guests get an access token marked as such,
+// which only grants access to what they can use before signing in.
+func (s *Service) issueAccessToken(u *user.User, sess *session.Session) (string, error) {
+	claims := utils.Claims{Guest: u.IsGuest, AMR: sess.AMR}
+	claims.Subject = string(u.ID)
+	claims.ID = string(sess.ID)
+	if !sess.AuthTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(sess.AuthTime)
+	}
+	return utils.GenerateToken(claims, s.keys, s.accessTokenTTL())
+}
+
+// issueTokens generates an access token and a refresh token for a session. The refresh token
+// joins the family named after the session.
+func (s *Service) issueTokens(
+	ctx context.Context,
+	work unitofwork.UserAuthWork,
+	u *user.User,
+	sess *session.Session,
+) (*Tokens, error) {
+	accessToken, err := s.issueAccessToken(u, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := work.RefreshTokens().Create(ctx, &refreshtoken.RefreshToken{
+		ID:        refreshtoken.RefreshTokenID(uuid.New().String()),
+		UserID:    u.ID,
+		FamilyID:  refreshtoken.FamilyID(sess.ID),
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: now.Add(s.refreshTokenTTL()),
+		CreatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.accessTokenTTL(),
+	}, nil
+}
+
+// completeLogin finishes signing in a user who has just proved their identity with the given
+// provider. Users with two-factor authentication enabled get an MFA challenge instead of
+// tokens. Deleted users cannot sign in.
+func (s *Service) completeLogin(
+	ctx context.Context,
+	u *user.User,
+	provider auth.Provider,
+	client ClientInfo,
+) (*RegisterResult, error) {
+	if u.DeletedAt != nil {
+		return nil, ErrInvalidCredentials
+	}
+	enabled, err := s.isMFAEnabled(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		challenge, err := s.createMFAChallenge(ctx, u.ID, provider)
+		if err != nil {
+			return nil, err
+		}
+		return &RegisterResult{User: u, MFA: challenge}, nil
+	}
+	return s.startSession(ctx, u, []string{string(provider)}, client)
+}
+
+// startSession starts a new session for a fully authenticated user and issues its first token
+// pair. amr lists the authentication methods the user has just used.
+func (s *Service) startSession(
+	ctx context.Context,
+	u *user.User,
+	amr []string,
+	client ClientInfo,
+) (*RegisterResult, error) {
+	now := time.Now()
+	newSession := &session.Session{
+		ID:         session.SessionID(uuid.New().String()),
+		UserID:     u.ID,
+		DeviceID:   client.DeviceID,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		AuthTime:   now,
+		AMR:        amr,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.refreshTokenTTL()),
+	}
+
+	var tokens *Tokens
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		if err := work.Sessions().Create(ctx, newSession); err != nil {
+			return err
+		}
+		var err error
+		tokens, err = s.issueTokens(ctx, work, u, newSession)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegisterResult{User: u, Tokens: *tokens}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token. Each refresh
+// token can be used only once; presenting one that was already used revokes its whole session,
+// since it means the token has been stolen or replayed.
+func (s *Service) Refresh(
+	ctx context.Context,
+	refreshToken string,
+	client ClientInfo,
+) (*Tokens, error) {
+	tokenHash := utils.HashToken(refreshToken)
+
+	var tokens *Tokens
+	var reused *refreshtoken.RefreshToken
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		existing, err := work.RefreshTokens().FindByTokenHash(ctx, tokenHash)
+		if err != nil {
+			return err
+		}
+		if existing == nil || existing.RevokedAt != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		now := time.Now()
+		if existing.UsedAt != nil {
+			// The revocation must be committed, so the error is returned after the transaction.
+			reused = existing
+			return work.RefreshTokens().RevokeFamily(ctx, existing.FamilyID, now)
+		}
+		if existing.IsExpired(now) {
+			return ErrInvalidRefreshToken
+		}
+
+		sessionID := session.SessionID(existing.FamilyID)
+		current, err := work.Sessions().FindByID(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		if current == nil || !current.IsActive(now) {
+			return ErrInvalidRefreshToken
+		}
+
+		u, err := work.Users().FindByID(ctx, existing.UserID)
+		if err != nil {
+			return err
+		}
+		if u == nil || u.DeletedAt != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := work.RefreshTokens().MarkUsed(ctx, existing.ID, now); err != nil {
+			return err
+		}
+		if err := work.Sessions().UpdateLastSeen(
+			ctx,
+			sessionID,
+			client.IPAddress,
+			now,
+			now.Add(s.refreshTokenTTL()),
+		); err != nil {
+			return err
+		}
+		tokens, err = s.issueTokens(ctx, work, u, current)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if reused != nil {
+		err := s.sessionService.Revoke(
+			ctx,
+			reused.UserID,
+			session.SessionID(reused.FamilyID),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	return tokens, nil
+}
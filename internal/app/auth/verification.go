@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	smsCodeCacheKeyPrefix = "sms-code"
+	smsCodeLength         = 6
+	smsCodeTTL            = 5 * time.Minute
+	smsCodeMaxAttempts    = 5
+
+	smsCodeField     = "code"
+	smsAttemptsField = "attempts"
+)
+
+var (
+	phoneNumberPattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	// chinaMobilePattern matches a mainland China mobile number entered without a country code,
+	// by the prefixes allocated to mobile carriers. US numbers entered without their +1 look
+	// alike, and the prefixes rule out those whose area code no Chinese carrier uses.
+	chinaMobilePattern = regexp.MustCompile(
+		`^1(3[0-9]|4[5-9]|5[0-35-9]|6[2567]|7[0-8]|8[0-9]|9[0-35-9])[0-9]{8}$`,
+	)
+)
+
+// normalizePhoneNumber strips formatting characters from a phone number and converts it to
+// E.164, so that the same number is always stored the same way. Numbers must be in E.164 form,
+// or be mainland China mobile numbers without the +86; any other number without a + is
+// ambiguous, since it may or may not start with a country code.
+func normalizePhoneNumber(phoneNumber string) (string, error) {
+	normalized := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phoneNumber)
+	if chinaMobilePattern.MatchString(normalized) {
+		normalized = "+86" + normalized
+	}
+	if !phoneNumberPattern.MatchString(normalized) {
+		return "", ErrInvalidPhoneNumber
+	}
+	return normalized, nil
+}
+
+// generateNumericCode returns a cryptographically random numeric code of the given length.
+func generateNumericCode(length int) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(n.String())
+	}
+	return sb.String(), nil
+}
+
+func smsCodeKey(phoneNumber string) string {
+	return fmt.Sprintf("%s:%s", smsCodeCacheKeyPrefix, phoneNumber)
+}
+
+// storeSMSCode saves a verification code for the phone number, replacing any previous code and
+// resetting its attempt counter.
+func (s *Service) storeSMSCode(ctx context.Context, phoneNumber, code string) error {
+	key := smsCodeKey(phoneNumber)
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, smsCodeField, code, smsAttemptsField, 0)
+		pipe.Expire(ctx, key, smsCodeTTL)
+		return nil
+	})
+	return err
+}
+
+// recordAttemptScript increments an attempt counter in a hash, unless the hash has expired or
+// been deleted, in which case it returns -1 rather than creating a hash without a TTL.
+var recordAttemptScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+return redis.call("HINCRBY", KEYS[1], ARGV[1], 1)
+`)
+
+// recordAttempt atomically counts an attempt against the hash at key, and returns the number of
+// attempts made so far, including this one. ok is false if the key does not exist.
+func (s *Service) recordAttempt(ctx context.Context, key, field string) (int64, bool, error) {
+	attempts, err := recordAttemptScript.Run(ctx, s.redisClient, []string{key}, field).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	if attempts < 0 {
+		return 0, false, nil
+	}
+	return attempts, true, nil
+}
+
+// verifySMSCode checks the given code against the one stored for the phone number. A code can be
+// used only once, and is discarded after too many attempts.
+func (s *Service) verifySMSCode(ctx context.Context, phoneNumber, code string) error {
+	key := smsCodeKey(phoneNumber)
+
+	// Count the attempt before checking the code, so that concurrent guesses cannot all slip
+	// under the limit.
+	attempts, ok, err := s.recordAttempt(ctx, key, smsAttemptsField)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidVerificationCode
+	}
+	if attempts > smsCodeMaxAttempts {
+		s.redisClient.Del(ctx, key)
+		return ErrTooManyVerificationAttempts
+	}
+
+	expected, err := s.redisClient.HGet(ctx, key, smsCodeField).Result()
+	if err == redis.Nil {
+		return ErrInvalidVerificationCode
+	}
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+		return ErrInvalidVerificationCode
+	}
+
+	// Only the first caller to delete the key may use the code.
+	deleted, err := s.redisClient.Del(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrInvalidVerificationCode
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package auth
+
+import "testing"
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string // Empty when the number is rejected
+	}{
+		{"E.164", "+8613800138000", "+8613800138000"},
+		{"E.164 with formatting", "+1 (415) 555-0123", "+14155550123"},
+		{"China mobile without country code", "13800138000", "+8613800138000"},
+		{"China mobile with formatting", "138 0013 8000", "+8613800138000"},
+		{"country code without plus", "8613800138000", ""},
+		{"US number without plus", "14155550123", ""},
+		{"unallocated China prefix", "12012345678", ""},
+		{"China landline without country code", "01012345678", ""},
+		{"too short", "+12345", ""},
+		{"letters", "+86138abc38000", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizePhoneNumber(tt.input)
+			if tt.want == "" {
+				if err != ErrInvalidPhoneNumber {
+					t.Fatalf("normalizePhoneNumber(%q) = %q, %v; want ErrInvalidPhoneNumber",
+						tt.input, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("normalizePhoneNumber(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/notification"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/verificationtoken"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+const (
+	passwordResetTokenTTL     = 30 * time.Minute
+	emailVerificationTokenTTL = 24 * time.Hour
+
+	verificationEmailResendKeyPrefix = "verification-email-resend"
+	verificationEmailResendInterval  = time.Minute
+)
+
+// sendVerificationToken issues a verification token for the identity and emails it to the
+// identity's address. Earlier tokens with the same purpose stop working.
+func (s *Service) sendVerificationToken(
+	ctx context.Context,
+	a *auth.Auth,
+	purpose verificationtoken.Purpose,
+	emailType notification.EmailType,
+	ttl time.Duration,
+) error {
+	rawToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	now := time.Now()
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		if err := work.VerificationTokens().InvalidateAll(ctx, a.ID, purpose, now); err != nil {
+			return err
+		}
+		return work.VerificationTokens().Create(ctx, &verificationtoken.VerificationToken{
+			ID:        verificationtoken.VerificationTokenID(uuid.New().String()),
+			UserID:    a.UserID,
+			AuthID:    a.ID,
+			Purpose:   purpose,
+			TokenHash: utils.HashToken(rawToken),
+			Email:     a.Email,
+			ExpiresAt: now.Add(ttl),
+			CreatedAt: now,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.emailSender.SendEmail(ctx, notification.Email{
+		To:   a.Email,
+		Type: emailType,
+		Params: map[string]string{
+			"token":              rawToken,
+			"expires_in_minutes": strconv.Itoa(int(ttl.Minutes())),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	return nil
+}
+
+// allowVerificationEmail reports whether another email with the given purpose may be sent for
+// the identity, limiting each identity to one such email per interval.
+func (s *Service) allowVerificationEmail(
+	ctx context.Context,
+	authID auth.AuthID,
+	purpose verificationtoken.Purpose,
+) (bool, error) {
+	key := fmt.Sprintf("%s:%s:%s", verificationEmailResendKeyPrefix, purpose, authID)
+	return s.redisClient.SetNX(ctx, key, 1, verificationEmailResendInterval).Result()
+}
+
+// redeemVerificationToken marks a verification token as used and returns it, or returns nil if
+// the token is unknown, has a different purpose, or is expired or already used. The identity
+// must still have the email address the token was sent to.
+func redeemVerificationToken(
+	ctx context.Context,
+	work unitofwork.UserAuthWork,
+	rawToken string,
+	purpose verificationtoken.Purpose,
+) (*verificationtoken.VerificationToken, *auth.Auth, error) {
+	now := time.Now()
+	token, err := work.VerificationTokens().FindByTokenHash(ctx, utils.HashToken(rawToken))
+	if err != nil {
+		return nil, nil, err
+	}
+	if token == nil || token.Purpose != purpose || !token.IsUsable(now) {
+		return nil, nil, nil
+	}
+
+	a, err := work.Auths().FindByID(ctx, token.AuthID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a == nil || a.Email != token.Email {
+		return nil, nil, nil
+	}
+
+	if err := work.VerificationTokens().MarkUsed(ctx, token.ID, now); err != nil {
+		return nil, nil, err
+	}
+	return token, a, nil
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/wechat"
+)
+
+const (
+	wechatSessionKeyCacheKeyPrefix = "wechat-session-key"
+	wechatSessionKeyTTL            = 3 * 24 * time.Hour
+)
+
+// exchangeWechatCode exchanges a login code with the WeChat app that the source signs in
+// through, translating WeChat errors into application errors. Clients that do not send a
+// source predate the other apps, so they are the mini program.
+func (s *Service) exchangeWechatCode(
+	ctx context.Context,
+	source user.Source,
+	code string,
+) (*wechat.Identity, error) {
+	var platform wechat.Platform
+	switch source {
+	case user.WechatIOS, user.WechatAndroid, "":
+		platform = wechat.MiniProgram
+	case user.IOS, user.Android:
+		platform = wechat.MobileApp
+	default:
+		platform = wechat.Website
+	}
+
+	identity, err := s.wechatClient.ExchangeCode(ctx, platform, code)
+	switch {
+	case err == nil:
+		return identity, nil
+	case errors.Is(err, wechat.ErrInvalidCode), errors.Is(err, wechat.ErrCodeUsed):
+		return nil, ErrInvalidWechatCode
+	case errors.Is(err, wechat.ErrSystemBusy), errors.Is(err, wechat.ErrRateLimited):
+		return nil, ErrWechatUnavailable
+	default:
+		return nil, fmt.Errorf("failed to exchange wechat code: %w", err)
+	}
+}
+
+func wechatSessionKeyKey(userID user.UserID) string {
+	return fmt.Sprintf("%s:%s", wechatSessionKeyCacheKeyPrefix, userID)
+}
+
+// storeWechatSessionKey keeps the session key from the user's latest mini program login, which
+// is needed to decrypt data the mini program sends later.
+func (s *Service) storeWechatSessionKey(
+	ctx context.Context,
+	userID user.UserID,
+	sessionKey string,
+) error {
+	return s.redisClient.Set(
+		ctx,
+		wechatSessionKeyKey(userID),
+		sessionKey,
+		wechatSessionKeyTTL,
+	).Err()
+}
+
+// BindWechatPhoneNumberParams contains the parameters for binding the phone number a user shared
+// through the mini program. Either Code, or EncryptedData and IV, must be set.
+type BindWechatPhoneNumberParams struct {
+	UserID        user.UserID
+	Code          string // The phone code from newer versions of getPhoneNumber
+	EncryptedData string
+	IV            string
+}
+
+// BindWechatPhoneNumber verifies a phone number shared through the mini program and binds it to
+// the user, so that they can also log in with it.
+func (s *Service) BindWechatPhoneNumber(
+	ctx context.Context,
+	params BindWechatPhoneNumberParams,
+) (*user.User, error) {
+	info, err := s.fetchWechatPhoneNumber(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	phoneNumber, err := normalizePhoneNumber("+" + info.CountryCode + info.PurePhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var u *user.User
+	err = s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		foundUser, err := work.Users().FindByID(ctx, params.UserID)
+		if err != nil {
+			return err
+		}
+		if foundUser == nil {
+			return errors.New("authenticated user is missing")
+		}
+		u = foundUser
+
+		if u.PhoneNumber == phoneNumber {
+			return nil
+		}
+		if u.PhoneNumber != "" {
+			return ErrPhoneNumberAlreadyBound
+		}
+
+		owner, err := work.Users().FindByPhoneNumber(ctx, phoneNumber)
+		if err != nil {
+			return err
+		}
+		existingAuth, err := work.Auths().FindByProvider(ctx, auth.Phone, phoneNumber)
+		if err != nil {
+			return err
+		}
+		if owner != nil || existingAuth != nil {
+			return ErrPhoneNumberTaken
+		}
+
+		now := time.Now()
+		u.PhoneNumber = phoneNumber
+		u.UpdatedAt = now
+		if err := work.Users().Update(ctx, u); err != nil {
+			if errors.Is(err, user.ErrPhoneNumberTaken) {
+				return ErrPhoneNumberTaken
+			}
+			return err
+		}
+
+		return work.Auths().Create(ctx, &auth.Auth{
+			ID:         auth.AuthID(uuid.New().String()),
+			UserID:     u.ID,
+			Provider:   auth.Phone,
+			ProviderID: phoneNumber,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// fetchWechatPhoneNumber obtains the phone number either by exchanging the phone code or by
+// decrypting the encrypted data with the user's stored session key.
+func (s *Service) fetchWechatPhoneNumber(
+	ctx context.Context,
+	params BindWechatPhoneNumberParams,
+) (*wechat.PhoneInfo, error) {
+	if params.Code != "" {
+		info, err := s.wechatClient.GetPhoneNumber(ctx, params.Code)
+		switch {
+		case err == nil:
+			return info, nil
+		case errors.Is(err, wechat.ErrInvalidCode), errors.Is(err, wechat.ErrCodeUsed):
+			return nil, ErrInvalidWechatCode
+		case errors.Is(err, wechat.ErrSystemBusy), errors.Is(err, wechat.ErrRateLimited):
+			return nil, ErrWechatUnavailable
+		default:
+			return nil, fmt.Errorf("failed to get wechat phone number: %w", err)
+		}
+	}
+
+	sessionKey, err := s.redisClient.Get(ctx, wechatSessionKeyKey(params.UserID)).Result()
+	if err == redis.Nil {
+		return nil, ErrWechatSessionExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.wechatClient.DecryptPhoneNumber(sessionKey, params.EncryptedData, params.IV)
+	if errors.Is(err, wechat.ErrInvalidEncryptedData) {
+		// The mini program has most likely called wx.login again since we stored the key.
+		return nil, ErrWechatSessionExpired
+	}
+	return info, err
+}
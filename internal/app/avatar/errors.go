@@ -0,0 +1,12 @@
+package avatar
+
+import "errors"
+
+var (
+	ErrUserNotFound         = errors.New("user not found")
+	ErrAvatarNotFound       = errors.New("avatar not found")
+	ErrFileTooLarge         = errors.New("avatar file is too large")
+	ErrUnsupportedImageType = errors.New("avatar must be a jpeg, png or gif image")
+	ErrInvalidImage         = errors.New("avatar image cannot be decoded")
+	ErrImageTooLarge        = errors.New("avatar image has too many pixels")
+)
@@ -0,0 +1,142 @@
+package avatar
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Registers the GIF decoder
+	"image/jpeg"
+	_ "image/png" // Registers the PNG decoder
+	"net/http"
+)
+
+// maxImagePixels bounds the memory an upload can make us allocate when decoded.
+const maxImagePixels = 40_000_000
+
+// supportedImageTypes are the sniffed MIME types that avatars may be uploaded as.
+var supportedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// decodeImage checks the type of an uploaded image by its content, not by its name or declared
+// type, and decodes it upright onto a white background. Only the pixels are kept, so EXIF and
+// any other metadata are dropped.
+func decodeImage(data []byte) (*image.RGBA, error) {
+	if !supportedImageTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedImageType
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	flat := flatten(img)
+	if format == "jpeg" {
+		flat = orient(flat, jpegOrientation(data))
+	}
+	return flat, nil
+}
+
+// flatten draws an image over a white background, since thumbnails are JPEGs without alpha.
+func flatten(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+	return dst
+}
+
+// orient turns an image upright according to its EXIF orientation.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	// source maps a pixel of the upright image to the pixel of src it comes from.
+	var source func(x, y int) (int, int)
+	switch orientation {
+	case 2: // Mirrored horizontally
+		source = func(x, y int) (int, int) { return w - 1 - x, y }
+	case 3: // Rotated 180°
+		source = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4: // Mirrored vertically
+		source = func(x, y int) (int, int) { return x, h - 1 - y }
+	case 5: // Transposed
+		source = func(x, y int) (int, int) { return y, x }
+	case 6: // Needs rotating 90° clockwise
+		source = func(x, y int) (int, int) { return y, h - 1 - x }
+	case 7: // Transversed
+		source = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case 8: // Needs rotating 90° counterclockwise
+		source = func(x, y int) (int, int) { return w - 1 - y, x }
+	default:
+		return src
+	}
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := source(x, y)
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], src.Pix[src.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
+
+// squareThumbnail crops the largest centered square out of an image and scales it to size
+// pixels. Each thumbnail pixel is the average of the pixels it covers, which keeps downscaled
+// images smooth.
+func squareThumbnail(src *image.RGBA, size int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	side := min(w, h)
+	left, top := (w-side)/2, (h-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0 := top + y*side/size
+		y1 := max(top+(y+1)*side/size, y0+1)
+		for x := 0; x < size; x++ {
+			x0 := left + x*side/size
+			x1 := max(left+(x+1)*side/size, x0+1)
+
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[src.PixOffset(x0, sy):src.PixOffset(x1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					r += int(row[i])
+					g += int(row[i+1])
+					b += int(row[i+2])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = 0xFF
+		}
+	}
+	return dst
+}
+
+// encodeJPEG encodes a thumbnail. The encoder writes no metadata.
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,243 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var (
+	red   = color.RGBA{R: 0xFF, A: 0xFF}
+	green = color.RGBA{G: 0xFF, A: 0xFF}
+	blue  = color.RGBA{B: 0xFF, A: 0xFF}
+)
+
+// halves returns a w×h image whose left half is red and right half is blue.
+func halves(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+// near reports whether a pixel is close to the color, allowing for JPEG compression.
+func near(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	diff := func(a uint32, b uint8) bool {
+		d := int(a>>8) - int(b)
+		return d > -40 && d < 40
+	}
+	return diff(r, want.R) && diff(g, want.G) && diff(b, want.B)
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF segment with the orientation right after the JPEG's start
+// of image marker.
+func withOrientation(t *testing.T, jpegData []byte, orientation uint16) []byte {
+	t.Helper()
+	tiff := []byte("II\x2A\x00\x08\x00\x00\x00") // Little endian, first IFD at offset 8
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0) // Padding, then no next IFD
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpegData[:2]...)
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+func TestDecodeImageFormats(t *testing.T) {
+	var jpegData, gifData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, halves(40, 20), &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(&gifData, halves(40, 20), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]byte{
+		"png":  encodePNG(t, halves(40, 20)),
+		"jpeg": jpegData.Bytes(),
+		"gif":  gifData.Bytes(),
+	}
+	for name, data := range tests {
+		img, err := decodeImage(data)
+		if err != nil {
+			t.Errorf("%s: decodeImage: %v", name, err)
+			continue
+		}
+		if got := img.Bounds().Size(); got != image.Pt(40, 20) {
+			t.Errorf("%s: size = %v, want 40x20", name, got)
+		}
+		if !near(img.At(5, 10), red) || !near(img.At(35, 10), blue) {
+			t.Errorf("%s: left and right = %v and %v, want red and blue",
+				name, img.At(5, 10), img.At(35, 10))
+		}
+	}
+}
+
+func TestDecodeImageFlattensTransparency(t *testing.T) {
+	img, err := decodeImage(encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 4, 4))))
+	if err != nil {
+		t.Fatalf("decodeImage: %v", err)
+	}
+	if got := img.RGBAAt(1, 1); got != (color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}) {
+		t.Errorf("transparent pixel = %v, want white", got)
+	}
+}
+
+func TestDecodeImageTurnsJPEGUpright(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, halves(40, 20), &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Orientation 6 means the image must be turned 90° clockwise, which puts the left half on
+	// top.
+	img, err := decodeImage(withOrientation(t, buf.Bytes(), 6))
+	if err != nil {
+		t.Fatalf("decodeImage: %v", err)
+	}
+	if got := img.Bounds().Size(); got != image.Pt(20, 40) {
+		t.Fatalf("size = %v, want 20x40", got)
+	}
+	if !near(img.At(10, 5), red) || !near(img.At(10, 35), blue) {
+		t.Errorf("top and bottom = %v and %v, want red and blue", img.At(10, 5), img.At(10, 35))
+	}
+}
+
+func TestDecodeImageRejects(t *testing.T) {
+	// A PNG header that claims more pixels than we are willing to decode.
+	huge := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	ihdr := huge[12:29] // Chunk type and data of the IHDR chunk
+	binary.BigEndian.PutUint32(ihdr[4:], 10_000)
+	binary.BigEndian.PutUint32(ihdr[8:], 10_000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(ihdr))
+
+	pngData := encodePNG(t, halves(4, 4))
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"text", []byte("definitely not an image"), ErrUnsupportedImageType},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), ErrUnsupportedImageType},
+		{"truncated png", pngData[:len(pngData)/2], ErrInvalidImage},
+		{"too many pixels", huge, ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		if _, err := decodeImage(tt.data); err != tt.want {
+			t.Errorf("%s: decodeImage = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 3x2 image whose pixels are numbered in their red channel:
+	//   1 2 3
+	//   4 5 6
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		src.Set(i%3, i/3, color.RGBA{R: uint8(i + 1), A: 0xFF})
+	}
+
+	tests := map[int][]string{
+		1: {"123", "456"},
+		2: {"321", "654"},
+		3: {"654", "321"},
+		4: {"456", "123"},
+		5: {"14", "25", "36"},
+		6: {"41", "52", "63"},
+		7: {"63", "52", "41"},
+		8: {"36", "25", "14"},
+	}
+	for orientation, want := range tests {
+		got := orient(src, orientation)
+		var rows []string
+		for y := 0; y < got.Bounds().Dy(); y++ {
+			row := ""
+			for x := 0; x < got.Bounds().Dx(); x++ {
+				row += string(rune('0' + got.RGBAAt(x, y).R))
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) != len(want) {
+			t.Errorf("orientation %d: rows = %v, want %v", orientation, rows, want)
+			continue
+		}
+		for i := range rows {
+			if rows[i] != want[i] {
+				t.Errorf("orientation %d: rows = %v, want %v", orientation, rows, want)
+				break
+			}
+		}
+	}
+}
+
+func TestSquareThumbnailCropsTheCenter(t *testing.T) {
+	// A 30x10 image with a green square in the middle of red sides.
+	src := image.NewRGBA(image.Rect(0, 0, 30, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 30; x++ {
+			if x >= 10 && x < 20 {
+				src.Set(x, y, green)
+			} else {
+				src.Set(x, y, red)
+			}
+		}
+	}
+
+	for _, size := range []int{4, 10, 25} {
+		thumb := squareThumbnail(src, size)
+		if got := thumb.Bounds().Size(); got != image.Pt(size, size) {
+			t.Errorf("size %d: thumbnail is %v", size, got)
+			continue
+		}
+		for _, p := range []image.Point{{0, 0}, {size - 1, size - 1}, {size / 2, size / 2}} {
+			if got := thumb.RGBAAt(p.X, p.Y); got != green {
+				t.Errorf("size %d: pixel %v = %v, want green", size, p, got)
+			}
+		}
+	}
+}
+
+func TestSquareThumbnailAverages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.RGBA{A: 0xFF})
+	src.Set(1, 0, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF})
+	src.Set(0, 1, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF})
+	src.Set(1, 1, color.RGBA{A: 0xFF})
+
+	want := color.RGBA{R: 0x7F, G: 0x7F, B: 0x7F, A: 0xFF}
+	if got := squareThumbnail(src, 1).RGBAAt(0, 0); got != want {
+		t.Errorf("averaged pixel = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,241 @@
+package avatar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/storage"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+const (
+	avatarKeyPrefix = "avatars"
+	// avatarPath is where this server redirects avatar links to signed URLs.
+	avatarPath = "/avatars"
+)
+
+var defaultSizes = []int{512, 256, 128, 64}
+
+// Service is the application service for uploading and serving user avatars. Uploads are
+// stored as square JPEG thumbnails in the blob store. The user's avatar URL points at this
+// server, which redirects to a signed URL for the thumbnail, so the link never expires.
+type Service struct {
+	blobStore storage.BlobStore
+	userRepo  user.Repository
+	config    config.AvatarConfig
+	sizes     []int // Largest first
+	logger    *slog.Logger
+}
+
+// NewService creates a new instance of the avatar service.
+func NewService(
+	blobStore storage.BlobStore,
+	userRepo user.Repository,
+	avatarConfig config.AvatarConfig,
+	logger *slog.Logger,
+) *Service {
+	var sizes []int
+	for _, size := range avatarConfig.Sizes {
+		if size > 0 {
+			sizes = append(sizes, size)
+		}
+	}
+	if len(sizes) == 0 {
+		sizes = defaultSizes
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+
+	return &Service{
+		blobStore: blobStore,
+		userRepo:  userRepo,
+		config:    avatarConfig,
+		sizes:     sizes,
+		logger:    logger,
+	}
+}
+
+// MaxUploadBytes returns the largest avatar file that may be uploaded.
+func (s *Service) MaxUploadBytes() int64 {
+	if s.config.MaxUploadBytes <= 0 {
+		return 5 << 20
+	}
+	return s.config.MaxUploadBytes
+}
+
+func (s *Service) urlTTL() time.Duration {
+	if s.config.URLExpiresInMinutes <= 0 {
+		return time.Hour
+	}
+	return time.Duration(s.config.URLExpiresInMinutes) * time.Minute
+}
+
+func avatarKey(userID user.UserID, avatarID string, size int) string {
+	return fmt.Sprintf("%s/%s/%s/%d.jpg", avatarKeyPrefix, userID, avatarID, size)
+}
+
+func (s *Service) avatarURLPrefix(userID user.UserID) string {
+	return strings.TrimSuffix(s.config.BaseURL, "/") + avatarPath + "/" + string(userID) + "/"
+}
+
+// uploadedAvatarID returns the ID of the user's avatar if it was uploaded to us, rather than
+// taken from a login provider or set to an outside URL.
+func (s *Service) uploadedAvatarID(u *user.User) string {
+	avatarID, ok := strings.CutPrefix(u.AvatarURL, s.avatarURLPrefix(u.ID))
+	if !ok {
+		return ""
+	}
+	if _, err := uuid.Parse(avatarID); err != nil {
+		return ""
+	}
+	return avatarID
+}
+
+// Upload replaces the user's avatar with an uploaded image, and returns the updated user. The
+// image is checked, turned upright and stripped of its metadata, then stored as a square
+// thumbnail in each configured size. The previous uploaded avatar is deleted.
+func (s *Service) Upload(ctx context.Context, userID user.UserID, r io.Reader) (*user.User, error) {
+	maxBytes := s.MaxUploadBytes()
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrFileTooLarge
+	}
+	img, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+
+	// 1. Store the thumbnails under a new ID, so that cached links to the old avatar stay valid
+	// until it is deleted.
+	avatarID := uuid.New().String()
+	for _, size := range s.sizes {
+		thumbnail, err := encodeJPEG(squareThumbnail(img, size))
+		if err == nil {
+			err = s.blobStore.Put(ctx, avatarKey(userID, avatarID, size), thumbnail, "image/jpeg")
+		}
+		if err != nil {
+			s.deleteThumbnails(userID, avatarID)
+			return nil, fmt.Errorf("failed to store avatar: %w", err)
+		}
+	}
+
+	// 2. Point the user at the new avatar
+	previousID := s.uploadedAvatarID(u)
+	u.AvatarURL = s.avatarURLPrefix(userID) + avatarID
+	u.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, u); err != nil {
+		s.deleteThumbnails(userID, avatarID)
+		return nil, err
+	}
+
+	// 3. Clean up the previous avatar
+	if previousID != "" {
+		s.deleteThumbnails(userID, previousID)
+	}
+	s.logger.Info("Avatar uploaded", "userID", userID, "avatarID", avatarID)
+	return u, nil
+}
+
+// Remove clears the user's avatar, deleting it from the blob store if it was uploaded.
+func (s *Service) Remove(ctx context.Context, userID user.UserID) (*user.User, error) {
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	if u.AvatarURL == "" {
+		return u, nil
+	}
+
+	previousID := s.uploadedAvatarID(u)
+	u.AvatarURL = ""
+	u.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	if previousID != "" {
+		s.deleteThumbnails(userID, previousID)
+	}
+	return u, nil
+}
+
+// SignedURL returns a signed URL for the thumbnail of an avatar that best fits the requested
+// size: the smallest one at least that large, or the largest one. A size of 0 asks for the
+// largest. The URL expires after the returned duration.
+func (s *Service) SignedURL(
+	ctx context.Context,
+	userID user.UserID,
+	avatarID string,
+	size int,
+) (string, time.Duration, error) {
+	if _, err := uuid.Parse(string(userID)); err != nil {
+		return "", 0, ErrAvatarNotFound
+	}
+	if _, err := uuid.Parse(avatarID); err != nil {
+		return "", 0, ErrAvatarNotFound
+	}
+
+	chosen := s.sizes[0]
+	for _, candidate := range s.sizes {
+		if candidate >= size {
+			chosen = candidate
+		}
+	}
+	if size <= 0 {
+		chosen = s.sizes[0]
+	}
+
+	ttl := s.urlTTL()
+	signedURL, err := s.blobStore.SignedURL(ctx, avatarKey(userID, avatarID, chosen), ttl)
+	if err != nil {
+		return "", 0, err
+	}
+	return signedURL, ttl, nil
+}
+
+// DeleteUploaded deletes the user's avatar from the blob store if it was uploaded, once the user
+// has been deleted or their avatar replaced. The user is not updated.
+func (s *Service) DeleteUploaded(u *user.User) {
+	if avatarID := s.uploadedAvatarID(u); avatarID != "" {
+		s.deleteThumbnails(u.ID, avatarID)
+	}
+}
+
+// deleteThumbnails deletes every size of an avatar. Failures only leave unused files behind,
+// so they are logged rather than returned.
+func (s *Service) deleteThumbnails(userID user.UserID, avatarID string) {
+	// The request may already be cancelled when cleaning up after a failure.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, size := range s.sizes {
+		if err := s.blobStore.Delete(ctx, avatarKey(userID, avatarID, size)); err != nil {
+			s.logger.Warn(
+				"Failed to delete avatar thumbnail",
+				"userID", userID,
+				"avatarID", avatarID,
+				"size", size,
+				"error", err,
+			)
+		}
+	}
+}
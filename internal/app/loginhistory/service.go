@@ -0,0 +1,148 @@
+package loginhistory
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/loginevent"
+	"github.com/moriverse/45-server/internal/domain/notification"
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// Service is the application service for the login history of users.
+type Service struct {
+	loginEventRepo loginevent.Repository
+	locator        loginevent.Locator
+	alerter        notification.LoginAlerter
+	logger         *slog.Logger
+}
+
+// NewService creates a new instance of the login history service.
+func NewService(
+	loginEventRepo loginevent.Repository,
+	locator loginevent.Locator,
+	alerter notification.LoginAlerter,
+	logger *slog.Logger,
+) *Service {
+	return &Service{
+		loginEventRepo: loginEventRepo,
+		locator:        locator,
+		alerter:        alerter,
+		logger:         logger,
+	}
+}
+
+// RecordParams contains the parameters for recording a login.
+type RecordParams struct {
+	UserID        user.UserID // Empty for a failed login whose account is not known
+	Provider      string
+	Success       bool
+	FailureReason string
+	IPAddress     string
+	UserAgent     string
+	DeviceID      string
+	Source        user.Source
+}
+
+// Record adds a login to the history. A successful login from a device or country the user
+// has not signed in from before is flagged and passed to the login alert hook; a user's first
+// login is not. Errors are only logged, as they must not fail the login.
+func (s *Service) Record(ctx context.Context, params RecordParams) {
+	event := &loginevent.LoginEvent{
+		ID:            loginevent.LoginEventID(uuid.New().String()),
+		UserID:        params.UserID,
+		Provider:      params.Provider,
+		Success:       params.Success,
+		FailureReason: params.FailureReason,
+		IPAddress:     params.IPAddress,
+		UserAgent:     params.UserAgent,
+		DeviceID:      params.DeviceID,
+		Source:        params.Source,
+		Country:       s.locator.Country(params.IPAddress),
+		CreatedAt:     time.Now(),
+	}
+
+	if event.Success && event.UserID != "" {
+		if err := s.flagUnfamiliar(ctx, event); err != nil {
+			s.logger.Error("Failed to compare login with history", "userID", event.UserID,
+				"error", err)
+		}
+	}
+	if err := s.loginEventRepo.Create(ctx, event); err != nil {
+		s.logger.Error("Failed to record login event", "userID", event.UserID, "error", err)
+		return
+	}
+
+	if event.IsSuspicious() {
+		s.alert(event)
+	}
+}
+
+// flagUnfamiliar sets NewDevice and NewCountry by comparing the login with the user's earlier
+// successful logins. Logins without a device ID or a known country are not flagged for them.
+func (s *Service) flagUnfamiliar(ctx context.Context, event *loginevent.LoginEvent) error {
+	seen, err := s.loginEventRepo.HasSucceeded(ctx, event.UserID)
+	if err != nil || !seen {
+		return err
+	}
+
+	if event.DeviceID != "" {
+		seen, err := s.loginEventRepo.HasSucceededFromDevice(ctx, event.UserID, event.DeviceID)
+		if err != nil {
+			return err
+		}
+		event.NewDevice = !seen
+	}
+	if event.Country != "" {
+		seen, err := s.loginEventRepo.HasSucceededFromCountry(ctx, event.UserID, event.Country)
+		if err != nil {
+			return err
+		}
+		event.NewCountry = !seen
+	}
+	return nil
+}
+
+// alert passes a suspicious login to the alert hook without holding up the login.
+func (s *Service) alert(event *loginevent.LoginEvent) {
+	login := notification.SuspiciousLogin{
+		UserID:     string(event.UserID),
+		Provider:   event.Provider,
+		IPAddress:  event.IPAddress,
+		UserAgent:  event.UserAgent,
+		Country:    event.Country,
+		NewDevice:  event.NewDevice,
+		NewCountry: event.NewCountry,
+		At:         event.CreatedAt,
+	}
+	go func() {
+		// The request context may be cancelled before the hook finishes.
+		if err := s.alerter.AlertSuspiciousLogin(context.Background(), login); err != nil {
+			s.logger.Error("Failed to send login alert", "userID", login.UserID, "error", err)
+		}
+	}()
+}
+
+// List lists the user's most recent logins, newest first. The limit defaults to 20 and is at
+// most 100.
+func (s *Service) List(
+	ctx context.Context,
+	userID user.UserID,
+	limit int,
+) ([]*loginevent.LoginEvent, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+	return s.loginEventRepo.ListByUserID(ctx, userID, limit)
+}
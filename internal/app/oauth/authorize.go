@@ -0,0 +1,266 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/oauth"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+const (
+	authorizationCodeCacheKeyPrefix = "oauth-code"
+	authorizationCodeTTL            = 5 * time.Minute
+
+	responseTypeCode = "code"
+	// codeChallengeMethodS256 is the only PKCE method accepted; "plain" offers no protection if
+	// the authorization request is seen.
+	codeChallengeMethodS256 = "S256"
+	// codeChallengeLength is the length of a base64url encoded SHA-256 hash.
+	codeChallengeLength = 43
+)
+
+func authorizationCodeKey(codeHash string) string {
+	return fmt.Sprintf("%s:%s", authorizationCodeCacheKeyPrefix, codeHash)
+}
+
+// authorizationCode is what an authorization code stands for until the client exchanges it.
+type authorizationCode struct {
+	ClientID      oauth.ClientID `json:"client_id"`
+	UserID        user.UserID    `json:"user_id"`
+	RedirectURI   string         `json:"redirect_uri"`
+	Scope         string         `json:"scope"`
+	CodeChallenge string         `json:"code_challenge"`
+}
+
+// AuthorizeParams contains an authorization request (RFC 6749, section 4.1.1) with its PKCE
+// code challenge (RFC 7636), made on behalf of a signed-in user.
+type AuthorizeParams struct {
+	UserID              user.UserID
+	ClientID            oauth.ClientID
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// AuthorizationRequest is a valid authorization request, for showing the user a consent
+// screen.
+type AuthorizationRequest struct {
+	Client *oauth.Client
+	Scopes []oauth.Scope
+	// ConsentRequired is false if the user already allowed the client these scopes, so the
+	// request can be approved without asking again.
+	ConsentRequired bool
+}
+
+// PrepareAuthorization validates an authorization request and reports whether the user must
+// be asked for consent. An invalid client or redirect URI is reported as an error to show the
+// user; other problems are reported as a RedirectError, to send the user back to the client.
+func (s *Service) PrepareAuthorization(
+	ctx context.Context,
+	params AuthorizeParams,
+) (*AuthorizationRequest, error) {
+	client, scopes, err := s.validateAuthorization(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	consent, err := s.oauthRepo.FindConsent(ctx, params.UserID, client.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthorizationRequest{
+		Client:          client,
+		Scopes:          scopes,
+		ConsentRequired: consent == nil || !oauth.ContainsAll(consent.Scopes, scopes),
+	}, nil
+}
+
+// Authorize completes an authorization request with the user's decision. It returns the URI
+// to send the user back to: with an authorization code if they approved, or with an
+// access_denied error if they did not.
+func (s *Service) Authorize(
+	ctx context.Context,
+	params AuthorizeParams,
+	approved bool,
+) (string, error) {
+	client, scopes, err := s.validateAuthorization(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	if !approved {
+		s.logger.Info("OAuth authorization denied", "userID", params.UserID, "clientID", client.ID)
+		denied := &Error{Code: CodeAccessDenied, Description: "the user denied the request"}
+		return errorRedirect(params.RedirectURI, params.State, denied), nil
+	}
+
+	if err := s.saveConsent(ctx, params.UserID, client.ID, scopes); err != nil {
+		return "", err
+	}
+
+	code, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(authorizationCode{
+		ClientID:      client.ID,
+		UserID:        params.UserID,
+		RedirectURI:   params.RedirectURI,
+		Scope:         oauth.FormatScopes(scopes),
+		CodeChallenge: params.CodeChallenge,
+	})
+	if err != nil {
+		return "", err
+	}
+	key := authorizationCodeKey(utils.HashToken(code))
+	if err := s.redisClient.Set(ctx, key, data, authorizationCodeTTL).Err(); err != nil {
+		return "", err
+	}
+
+	s.logger.Info(
+		"OAuth authorization granted",
+		"userID", params.UserID,
+		"clientID", client.ID,
+		"scopes", scopes,
+	)
+	return redirectWithQuery(params.RedirectURI, map[string]string{
+		"code":  code,
+		"state": params.State,
+	}), nil
+}
+
+// validateAuthorization checks an authorization request, returning the client and the
+// requested scopes.
+func (s *Service) validateAuthorization(
+	ctx context.Context,
+	params AuthorizeParams,
+) (*oauth.Client, []oauth.Scope, error) {
+	client, err := s.findActiveClient(ctx, params.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if client == nil {
+		return nil, nil, ErrClientNotFound
+	}
+	// Redirect URIs must match exactly, so that codes are only ever sent to the client.
+	if !client.AllowsRedirectURI(params.RedirectURI) {
+		return nil, nil, ErrRedirectURIMismatch
+	}
+
+	redirectError := func(code, description string) error {
+		oauthErr := &Error{Code: code, Description: description}
+		return &RedirectError{
+			Err:        oauthErr,
+			RedirectTo: errorRedirect(params.RedirectURI, params.State, oauthErr),
+		}
+	}
+	if params.ResponseType != responseTypeCode {
+		return nil, nil, redirectError(
+			CodeUnsupportedResponseType,
+			"response_type must be code",
+		)
+	}
+	if params.CodeChallengeMethod != codeChallengeMethodS256 {
+		return nil, nil, redirectError(CodeInvalidRequest, "code_challenge_method must be S256")
+	}
+	if len(params.CodeChallenge) != codeChallengeLength {
+		return nil, nil, redirectError(CodeInvalidRequest, "code_challenge is missing or invalid")
+	}
+
+	scopes := oauth.ParseScopes(params.Scope)
+	if len(scopes) == 0 {
+		return nil, nil, redirectError(CodeInvalidScope, "scope is required")
+	}
+	if !oauth.ContainsAll(client.Scopes, scopes) {
+		return nil, nil, redirectError(CodeInvalidScope, "the client may not request this scope")
+	}
+	return client, scopes, nil
+}
+
+// saveConsent adds the scopes to those the user has allowed the client.
+func (s *Service) saveConsent(
+	ctx context.Context,
+	userID user.UserID,
+	clientID oauth.ClientID,
+	scopes []oauth.Scope,
+) error {
+	now := time.Now()
+	consent, err := s.oauthRepo.FindConsent(ctx, userID, clientID)
+	if err != nil {
+		return err
+	}
+	if consent == nil {
+		consent = &oauth.Consent{UserID: userID, ClientID: clientID, CreatedAt: now}
+	}
+	for _, scope := range scopes {
+		if !oauth.ContainsAll(consent.Scopes, []oauth.Scope{scope}) {
+			consent.Scopes = append(consent.Scopes, scope)
+		}
+	}
+	consent.UpdatedAt = now
+	return s.oauthRepo.SaveConsent(ctx, consent)
+}
+
+// findActiveClient finds a client that has not been revoked. Client IDs come from third
+// parties, so malformed ones are treated as unknown rather than sent to the database.
+func (s *Service) findActiveClient(
+	ctx context.Context,
+	id oauth.ClientID,
+) (*oauth.Client, error) {
+	if _, err := uuid.Parse(string(id)); err != nil {
+		return nil, nil
+	}
+	client, err := s.oauthRepo.FindClientByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || !client.IsActive() {
+		return nil, nil
+	}
+	return client, nil
+}
+
+// verifyCodeChallenge checks a PKCE code verifier against the S256 code challenge.
+func verifyCodeChallenge(verifier, challenge string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// errorRedirect adds an OAuth error to a redirect URI.
+func errorRedirect(redirectURI, state string, oauthErr *Error) string {
+	return redirectWithQuery(redirectURI, map[string]string{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+		"state":             state,
+	})
+}
+
+// redirectWithQuery adds the non-empty values to the query of a registered redirect URI.
+func redirectWithQuery(redirectURI string, values map[string]string) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		// Registered redirect URIs were validated when the client was created.
+		return redirectURI
+	}
+	query := u.Query()
+	for k, v := range values {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,53 @@
+package oauth
+
+import "errors"
+
+var (
+	ErrClientNotFound      = errors.New("oauth client does not exist or is revoked")
+	ErrRedirectURIMismatch = errors.New("redirect uri is not registered for the client")
+	ErrNameRequired        = errors.New("oauth client name is required")
+	ErrRedirectURIRequired = errors.New("oauth client needs at least one redirect uri")
+	ErrInvalidRedirectURI  = errors.New("redirect uri must be an absolute uri without a fragment")
+	ErrInvalidScope        = errors.New("unknown oauth scope")
+	ErrScopesRequired      = errors.New("oauth client needs at least one scope")
+	ErrConsentNotFound     = errors.New("user has not authorized this client")
+	ErrUserNotFound        = errors.New("user does not exist")
+)
+
+// Error codes from RFC 6749, section 4.1.2.1 and section 5.2.
+const (
+	CodeInvalidRequest          = "invalid_request"
+	CodeInvalidClient           = "invalid_client"
+	CodeInvalidGrant            = "invalid_grant"
+	CodeUnauthorizedClient      = "unauthorized_client"
+	CodeUnsupportedGrantType    = "unsupported_grant_type"
+	CodeUnsupportedResponseType = "unsupported_response_type"
+	CodeInvalidScope            = "invalid_scope"
+	CodeAccessDenied            = "access_denied"
+)
+
+// Error is an error in an OAuth request, reported to the client with an error code from the
+// OAuth specification rather than with the API's own error codes.
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// RedirectError is an error in an authorization request that is reported by sending the user
+// back to the client, because the client and redirect URI were valid.
+type RedirectError struct {
+	Err        *Error
+	RedirectTo string // The client's redirect URI with the error in its query
+}
+
+func (e *RedirectError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RedirectError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,295 @@
+package oauth
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	appSession "github.com/moriverse/45-server/internal/app/session"
+	"github.com/moriverse/45-server/internal/domain/oauth"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+// Service is the application service for the OAuth 2.0 authorization server, which lets
+// third-party apps sign users in with their 45 account and call the API on their behalf.
+type Service struct {
+	oauthRepo      oauth.Repository
+	userRepo       user.Repository
+	sessionService *appSession.Service
+	keys           *utils.KeySet
+	oauthConfig    config.OAuthConfig
+	redisClient    *redis.Client
+	logger         *slog.Logger
+}
+
+// NewService creates a new instance of the OAuth service.
+func NewService(
+	oauthRepo oauth.Repository,
+	userRepo user.Repository,
+	sessionService *appSession.Service,
+	keys *utils.KeySet,
+	oauthConfig config.OAuthConfig,
+	redisClient *redis.Client,
+	logger *slog.Logger,
+) *Service {
+	return &Service{
+		oauthRepo:      oauthRepo,
+		userRepo:       userRepo,
+		sessionService: sessionService,
+		keys:           keys,
+		oauthConfig:    oauthConfig,
+		redisClient:    redisClient,
+		logger:         logger,
+	}
+}
+
+func (s *Service) accessTokenTTL() time.Duration {
+	return time.Duration(s.oauthConfig.AccessTokenExpiresInMinutes) * time.Minute
+}
+
+func (s *Service) refreshTokenTTL() time.Duration {
+	return time.Duration(s.oauthConfig.RefreshTokenExpiresInDays) * 24 * time.Hour
+}
+
+// CreateClientParams contains the parameters for registering a client.
+type CreateClientParams struct {
+	Name         string
+	RedirectURIs []string
+	Scopes       []oauth.Scope
+	// Confidential clients get a secret. Apps that run on the user's device cannot keep one
+	// and should be public.
+	Confidential bool
+	CreatedBy    user.UserID
+}
+
+// CreateClientResult contains a newly registered client.
+type CreateClientResult struct {
+	Client *oauth.Client
+	Secret string // Empty for public clients; cannot be shown again
+}
+
+// CreateClient registers a third-party app. Only the hash of a confidential client's secret
+// is stored.
+func (s *Service) CreateClient(
+	ctx context.Context,
+	params CreateClientParams,
+) (*CreateClientResult, error) {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+	if len(params.RedirectURIs) == 0 {
+		return nil, ErrRedirectURIRequired
+	}
+	for _, uri := range params.RedirectURIs {
+		if !isValidRedirectURI(uri) {
+			return nil, ErrInvalidRedirectURI
+		}
+	}
+	if len(params.Scopes) == 0 {
+		return nil, ErrScopesRequired
+	}
+	for _, scope := range params.Scopes {
+		if !scope.IsValid() {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	client := &oauth.Client{
+		ID:           oauth.ClientID(uuid.New().String()),
+		Name:         name,
+		RedirectURIs: params.RedirectURIs,
+		Scopes:       params.Scopes,
+		CreatedBy:    params.CreatedBy,
+		CreatedAt:    time.Now(),
+	}
+	var secret string
+	if params.Confidential {
+		var err error
+		secret, err = utils.GenerateOpaqueToken()
+		if err != nil {
+			return nil, err
+		}
+		client.SecretHash = utils.HashToken(secret)
+	}
+	if err := s.oauthRepo.CreateClient(ctx, client); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info(
+		"OAuth client created",
+		"clientID", client.ID,
+		"name", client.Name,
+		"confidential", params.Confidential,
+		"createdBy", params.CreatedBy,
+	)
+	return &CreateClientResult{Client: client, Secret: secret}, nil
+}
+
+// isValidRedirectURI accepts absolute URIs without a fragment (RFC 6749, section 3.1.2). Plain
+// HTTP is only allowed for loopback addresses, which native apps listen on; native apps may
+// also use a private scheme.
+func isValidRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Fragment != "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+	return true
+}
+
+// ListClients lists every client, newest first.
+func (s *Service) ListClients(ctx context.Context) ([]*oauth.Client, error) {
+	return s.oauthRepo.ListClients(ctx)
+}
+
+// RevokeClient stops a client from working, revoking every grant users gave it.
+func (s *Service) RevokeClient(
+	ctx context.Context,
+	id oauth.ClientID,
+	revokedBy user.UserID,
+) error {
+	if _, err := uuid.Parse(string(id)); err != nil {
+		return ErrClientNotFound
+	}
+	now := time.Now()
+	revoked, err := s.oauthRepo.RevokeClient(ctx, id, now)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrClientNotFound
+	}
+	grantIDs, err := s.oauthRepo.RevokeGrantsByClientID(ctx, id, now)
+	if err != nil {
+		return err
+	}
+	if err := s.denylist(ctx, grantIDs...); err != nil {
+		return err
+	}
+
+	s.logger.Info(
+		"OAuth client revoked",
+		"clientID", id,
+		"revokedGrants", len(grantIDs),
+		"revokedBy", revokedBy,
+	)
+	return nil
+}
+
+// AuthorizedApp is a client a user has allowed to access their account.
+type AuthorizedApp struct {
+	Client       *oauth.Client
+	Scopes       []oauth.Scope
+	AuthorizedAt time.Time
+}
+
+// ListAuthorizedApps lists the clients a user has allowed, most recently authorized first.
+func (s *Service) ListAuthorizedApps(
+	ctx context.Context,
+	userID user.UserID,
+) ([]*AuthorizedApp, error) {
+	consents, err := s.oauthRepo.ListConsentsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := make([]*AuthorizedApp, 0, len(consents))
+	for _, consent := range consents {
+		client, err := s.oauthRepo.FindClientByID(ctx, consent.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if client == nil || !client.IsActive() {
+			continue
+		}
+		apps = append(apps, &AuthorizedApp{
+			Client:       client,
+			Scopes:       consent.Scopes,
+			AuthorizedAt: consent.UpdatedAt,
+		})
+	}
+	return apps, nil
+}
+
+// RevokeAuthorizedApp withdraws a user's consent for a client and revokes the client's tokens
+// for the user. The client must ask again to access the account.
+func (s *Service) RevokeAuthorizedApp(
+	ctx context.Context,
+	userID user.UserID,
+	clientID oauth.ClientID,
+) error {
+	if _, err := uuid.Parse(string(clientID)); err != nil {
+		return ErrConsentNotFound
+	}
+	deleted, err := s.oauthRepo.DeleteConsent(ctx, userID, clientID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrConsentNotFound
+	}
+	grantIDs, err := s.oauthRepo.RevokeGrantsByUserAndClient(ctx, userID, clientID, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := s.denylist(ctx, grantIDs...); err != nil {
+		return err
+	}
+
+	s.logger.Info("OAuth consent revoked", "userID", userID, "clientID", clientID)
+	return nil
+}
+
+// denylist rejects the access tokens of revoked grants, whose token ID is the grant ID, until
+// they expire.
+func (s *Service) denylist(ctx context.Context, grantIDs ...oauth.GrantID) error {
+	for _, id := range grantIDs {
+		if err := s.sessionService.DenylistTokens(ctx, string(id), s.accessTokenTTL()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UserInfo is what a client learns about a user, limited by the scopes it was granted.
+type UserInfo struct {
+	Subject     user.UserID
+	Nickname    string
+	AvatarURL   string
+	PhoneNumber string // Only with the phone scope
+}
+
+// GetUserInfo returns the user's profile as the given scopes allow.
+func (s *Service) GetUserInfo(
+	ctx context.Context,
+	userID user.UserID,
+	scopes []oauth.Scope,
+) (*UserInfo, error) {
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+
+	info := &UserInfo{Subject: u.ID, Nickname: u.Nickname, AvatarURL: u.AvatarURL}
+	if oauth.ContainsAll(scopes, []oauth.Scope{oauth.ScopePhone}) {
+		info.PhoneNumber = u.PhoneNumber
+	}
+	return info, nil
+}
@@ -0,0 +1,375 @@
+package oauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/oauth"
+	"github.com/moriverse/45-server/internal/domain/session"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+
+	TokenTypeAccessToken  = "access_token"
+	TokenTypeRefreshToken = "refresh_token"
+
+	// Bounds on the length of a PKCE code verifier (RFC 7636, section 4.1).
+	minCodeVerifierLength = 43
+	maxCodeVerifierLength = 128
+)
+
+var errInvalidClient = &Error{Code: CodeInvalidClient, Description: "client authentication failed"}
+
+// ClientCredentials identify the client making a request to the token, introspection or
+// revocation endpoint. Public clients have no secret.
+type ClientCredentials struct {
+	ID     oauth.ClientID
+	Secret string
+}
+
+// TokenParams contains a token request (RFC 6749, section 4.1.3 and section 6).
+type TokenParams struct {
+	Client       ClientCredentials
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string // Optionally narrows the scopes of a refreshed access token
+}
+
+// Tokens are the tokens issued to a client.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+	Scopes       []oauth.Scope
+}
+
+// Token handles a request to the token endpoint. Failures are reported as an *Error.
+func (s *Service) Token(ctx context.Context, params TokenParams) (*Tokens, error) {
+	client, err := s.authenticateClient(ctx, params.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	switch params.GrantType {
+	case GrantTypeAuthorizationCode:
+		return s.exchangeCode(ctx, client, params)
+	case GrantTypeRefreshToken:
+		return s.refresh(ctx, client, params)
+	}
+	return nil, &Error{
+		Code:        CodeUnsupportedGrantType,
+		Description: "grant_type must be authorization_code or refresh_token",
+	}
+}
+
+// exchangeCode redeems an authorization code for a new grant. A code can be used once, by the
+// client it was issued to, with the redirect URI and code verifier of the original request.
+func (s *Service) exchangeCode(
+	ctx context.Context,
+	client *oauth.Client,
+	params TokenParams,
+) (*Tokens, error) {
+	if params.Code == "" || params.RedirectURI == "" {
+		return nil, &Error{
+			Code:        CodeInvalidRequest,
+			Description: "code and redirect_uri are required",
+		}
+	}
+	if len(params.CodeVerifier) < minCodeVerifierLength ||
+		len(params.CodeVerifier) > maxCodeVerifierLength {
+		return nil, &Error{Code: CodeInvalidRequest, Description: "code_verifier is invalid"}
+	}
+
+	invalidGrant := &Error{
+		Code:        CodeInvalidGrant,
+		Description: "the authorization code is invalid, expired or already used",
+	}
+	key := authorizationCodeKey(utils.HashToken(params.Code))
+	data, err := s.redisClient.GetDel(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, invalidGrant
+	}
+	if err != nil {
+		return nil, err
+	}
+	var code authorizationCode
+	if err := json.Unmarshal(data, &code); err != nil {
+		return nil, err
+	}
+	if code.ClientID != client.ID || code.RedirectURI != params.RedirectURI {
+		return nil, invalidGrant
+	}
+	if !verifyCodeChallenge(params.CodeVerifier, code.CodeChallenge) {
+		return nil, &Error{
+			Code:        CodeInvalidGrant,
+			Description: "the code verifier does not match the code challenge",
+		}
+	}
+	if err := s.checkUser(ctx, code.UserID); err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	grant := &oauth.Grant{
+		ID:               oauth.GrantID(uuid.New().String()),
+		ClientID:         client.ID,
+		UserID:           code.UserID,
+		Scopes:           oauth.ParseScopes(code.Scope),
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		ExpiresAt:        now.Add(s.refreshTokenTTL()),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.oauthRepo.CreateGrant(ctx, grant); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.issueAccessToken(grant, grant.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info(
+		"OAuth grant created",
+		"grantID", grant.ID,
+		"clientID", client.ID,
+		"userID", grant.UserID,
+	)
+	return &Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.accessTokenTTL(),
+		Scopes:       grant.Scopes,
+	}, nil
+}
+
+// refresh issues a new access token for a grant, replacing its refresh token.
+func (s *Service) refresh(
+	ctx context.Context,
+	client *oauth.Client,
+	params TokenParams,
+) (*Tokens, error) {
+	if params.RefreshToken == "" {
+		return nil, &Error{Code: CodeInvalidRequest, Description: "refresh_token is required"}
+	}
+
+	invalidGrant := &Error{
+		Code:        CodeInvalidGrant,
+		Description: "the refresh token is invalid, expired or revoked",
+	}
+	oldHash := utils.HashToken(params.RefreshToken)
+	grant, err := s.oauthRepo.FindGrantByRefreshTokenHash(ctx, oldHash)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if grant == nil || grant.ClientID != client.ID || !grant.IsActive(now) {
+		return nil, invalidGrant
+	}
+
+	scopes := grant.Scopes
+	if params.Scope != "" {
+		scopes = oauth.ParseScopes(params.Scope)
+		if !oauth.ContainsAll(grant.Scopes, scopes) {
+			return nil, &Error{
+				Code:        CodeInvalidScope,
+				Description: "the scope exceeds what the user granted",
+			}
+		}
+	}
+	if err := s.checkUser(ctx, grant.UserID); err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.oauthRepo.RotateRefreshToken(
+		ctx,
+		grant.ID,
+		oldHash,
+		utils.HashToken(refreshToken),
+		now.Add(s.refreshTokenTTL()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another request used the refresh token first.
+		return nil, invalidGrant
+	}
+
+	accessToken, err := s.issueAccessToken(grant, scopes)
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.accessTokenTTL(),
+		Scopes:       scopes,
+	}, nil
+}
+
+// issueAccessToken signs an access token for a grant. Its token ID is the grant ID, so that
+// revoking the grant rejects every access token issued under it.
+func (s *Service) issueAccessToken(grant *oauth.Grant, scopes []oauth.Scope) (string, error) {
+	claims := utils.Claims{
+		ClientID: string(grant.ClientID),
+		Scope:    oauth.FormatScopes(scopes),
+	}
+	claims.Subject = string(grant.UserID)
+	claims.ID = string(grant.ID)
+	return utils.GenerateToken(claims, s.keys, s.accessTokenTTL())
+}
+
+// checkUser rejects grants for users who have since been deleted.
+func (s *Service) checkUser(ctx context.Context, userID user.UserID) error {
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil || u.DeletedAt != nil {
+		return &Error{Code: CodeInvalidGrant, Description: "the user no longer exists"}
+	}
+	return nil
+}
+
+// authenticateClient checks the client's credentials (RFC 6749, section 2.3). Confidential
+// clients must give their secret and public clients must not give one.
+func (s *Service) authenticateClient(
+	ctx context.Context,
+	creds ClientCredentials,
+) (*oauth.Client, error) {
+	client, err := s.findActiveClient(ctx, creds.ID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errInvalidClient
+	}
+	if !client.IsConfidential() {
+		if creds.Secret != "" {
+			return nil, errInvalidClient
+		}
+		return client, nil
+	}
+	secretHash := utils.HashToken(creds.Secret)
+	if subtle.ConstantTimeCompare([]byte(secretHash), []byte(client.SecretHash)) != 1 {
+		return nil, errInvalidClient
+	}
+	return client, nil
+}
+
+// Introspection describes a token to the client it was issued to (RFC 7662).
+type Introspection struct {
+	Active    bool
+	TokenType string
+	ClientID  oauth.ClientID
+	UserID    user.UserID
+	Scopes    []oauth.Scope
+	ExpiresAt time.Time
+}
+
+// Introspect reports whether a token is active. Tokens issued to other clients are reported as
+// inactive, so that a client cannot learn about tokens that are not its own.
+func (s *Service) Introspect(
+	ctx context.Context,
+	creds ClientCredentials,
+	token string,
+) (*Introspection, error) {
+	client, err := s.authenticateClient(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, &Error{Code: CodeInvalidRequest, Description: "token is required"}
+	}
+
+	claims, err := utils.ValidateToken(token, s.keys)
+	if err == nil && claims.ClientID == string(client.ID) {
+		revoked, err := s.sessionService.IsRevoked(ctx, session.SessionID(claims.ID))
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return &Introspection{Active: false}, nil
+		}
+		return &Introspection{
+			Active:    true,
+			TokenType: TokenTypeAccessToken,
+			ClientID:  client.ID,
+			UserID:    user.UserID(claims.Subject),
+			Scopes:    oauth.ParseScopes(claims.Scope),
+			ExpiresAt: claims.ExpiresAt.Time,
+		}, nil
+	}
+
+	grant, err := s.oauthRepo.FindGrantByRefreshTokenHash(ctx, utils.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil || grant.ClientID != client.ID || !grant.IsActive(time.Now()) {
+		return &Introspection{Active: false}, nil
+	}
+	return &Introspection{
+		Active:    true,
+		TokenType: TokenTypeRefreshToken,
+		ClientID:  client.ID,
+		UserID:    grant.UserID,
+		Scopes:    grant.Scopes,
+		ExpiresAt: grant.ExpiresAt,
+	}, nil
+}
+
+// Revoke revokes the grant an access or refresh token belongs to (RFC 7009), which ends the
+// client's access until the user authorizes it again. Unknown tokens are ignored, as the RFC
+// requires.
+func (s *Service) Revoke(ctx context.Context, creds ClientCredentials, token string) error {
+	client, err := s.authenticateClient(ctx, creds)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return &Error{Code: CodeInvalidRequest, Description: "token is required"}
+	}
+
+	var grant *oauth.Grant
+	claims, err := utils.ValidateToken(token, s.keys)
+	if err == nil && claims.ClientID == string(client.ID) {
+		grant, err = s.oauthRepo.FindGrantByID(ctx, oauth.GrantID(claims.ID))
+	} else {
+		grant, err = s.oauthRepo.FindGrantByRefreshTokenHash(ctx, utils.HashToken(token))
+	}
+	if err != nil {
+		return err
+	}
+	if grant == nil || grant.ClientID != client.ID || grant.RevokedAt != nil {
+		return nil
+	}
+
+	if err := s.oauthRepo.RevokeGrant(ctx, grant.ID, time.Now()); err != nil {
+		return err
+	}
+	if err := s.denylist(ctx, grant.ID); err != nil {
+		return err
+	}
+	s.logger.Info("OAuth grant revoked", "grantID", grant.ID, "clientID", client.ID)
+	return nil
+}
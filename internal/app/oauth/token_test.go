@@ -0,0 +1,392 @@
+package oauth
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/oauth"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+// The code verifier and code challenge of RFC 7636, appendix B.
+const (
+	testCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	testRedirectURI   = "https://app.example.com/callback"
+)
+
+// fakeOAuthRepository keeps the clients, consents and grants the token flow needs in memory.
+type fakeOAuthRepository struct {
+	oauth.Repository
+	clients  map[oauth.ClientID]*oauth.Client
+	consents map[string]*oauth.Consent
+	grants   []*oauth.Grant
+}
+
+func (r *fakeOAuthRepository) FindClientByID(
+	_ context.Context,
+	id oauth.ClientID,
+) (*oauth.Client, error) {
+	return r.clients[id], nil
+}
+
+func (r *fakeOAuthRepository) FindConsent(
+	_ context.Context,
+	userID user.UserID,
+	clientID oauth.ClientID,
+) (*oauth.Consent, error) {
+	return r.consents[string(userID)+":"+string(clientID)], nil
+}
+
+func (r *fakeOAuthRepository) SaveConsent(_ context.Context, consent *oauth.Consent) error {
+	r.consents[string(consent.UserID)+":"+string(consent.ClientID)] = consent
+	return nil
+}
+
+func (r *fakeOAuthRepository) CreateGrant(_ context.Context, grant *oauth.Grant) error {
+	r.grants = append(r.grants, grant)
+	return nil
+}
+
+type fakeUserRepository struct {
+	user.Repository
+	users map[user.UserID]*user.User
+}
+
+func (r *fakeUserRepository) FindByID(_ context.Context, id user.UserID) (*user.User, error) {
+	return r.users[id], nil
+}
+
+// fakeRedis answers the few Redis commands the authorization code flow uses, so that the tests
+// need no Redis server.
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newFakeRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeRedis{values: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go fake.serve(conn)
+		}
+	}()
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String()})
+	t.Cleanup(func() {
+		client.Close()
+		listener.Close()
+	})
+	return client
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, f.execute(args)); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) execute(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch strings.ToLower(args[0]) {
+	case "set":
+		f.values[args[1]] = args[2]
+		return "+OK\r\n"
+	case "getdel":
+		value, ok := f.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		delete(f.values, args[1])
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	readLine := func(prefix byte) (int, error) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return 0, err
+		}
+		if len(line) < 3 || line[0] != prefix {
+			return 0, errors.New("unexpected reply")
+		}
+		return strconv.Atoi(strings.TrimSpace(line[1:]))
+	}
+	n, err := readLine('*')
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		length, err := readLine('$')
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, length+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:length])
+	}
+	return args, nil
+}
+
+type testOAuth struct {
+	service   *Service
+	repo      *fakeOAuthRepository
+	client    *oauth.Client
+	other     *oauth.Client
+	userID    user.UserID
+	authorize AuthorizeParams
+}
+
+func newTestOAuth(t *testing.T) *testOAuth {
+	t.Helper()
+	keys, err := utils.LoadKeySet(config.JWTConfig{SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newClient := func() *oauth.Client {
+		return &oauth.Client{
+			ID:           oauth.ClientID(uuid.New().String()),
+			RedirectURIs: []string{testRedirectURI},
+			Scopes:       []oauth.Scope{oauth.ScopeProfile},
+		}
+	}
+	client, other := newClient(), newClient()
+	userID := user.UserID(uuid.New().String())
+	repo := &fakeOAuthRepository{
+		clients:  map[oauth.ClientID]*oauth.Client{client.ID: client, other.ID: other},
+		consents: make(map[string]*oauth.Consent),
+	}
+	users := &fakeUserRepository{users: map[user.UserID]*user.User{userID: {ID: userID}}}
+
+	service := NewService(
+		repo,
+		users,
+		nil,
+		keys,
+		config.OAuthConfig{AccessTokenExpiresInMinutes: 60, RefreshTokenExpiresInDays: 30},
+		newFakeRedis(t),
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	return &testOAuth{
+		service: service,
+		repo:    repo,
+		client:  client,
+		other:   other,
+		userID:  userID,
+		authorize: AuthorizeParams{
+			UserID:              userID,
+			ClientID:            client.ID,
+			RedirectURI:         testRedirectURI,
+			ResponseType:        responseTypeCode,
+			Scope:               string(oauth.ScopeProfile),
+			State:               "state",
+			CodeChallenge:       testCodeChallenge,
+			CodeChallengeMethod: codeChallengeMethodS256,
+		},
+	}
+}
+
+// issueCode approves the authorization request and returns the code sent to the client.
+func (o *testOAuth) issueCode(t *testing.T) string {
+	t.Helper()
+	redirectTo, err := o.service.Authorize(context.Background(), o.authorize, true)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	parsed, err := url.Parse(redirectTo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := parsed.Query().Get("state"); state != "state" {
+		t.Fatalf("Authorize returned state %q", state)
+	}
+	return parsed.Query().Get("code")
+}
+
+func (o *testOAuth) exchange(code, verifier string) (*Tokens, error) {
+	return o.service.Token(context.Background(), TokenParams{
+		Client:       ClientCredentials{ID: o.client.ID},
+		GrantType:    GrantTypeAuthorizationCode,
+		Code:         code,
+		RedirectURI:  testRedirectURI,
+		CodeVerifier: verifier,
+	})
+}
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier string
+		want     bool
+	}{
+		{"RFC 7636 example", testCodeVerifier, true},
+		{"another verifier", strings.Repeat("a", minCodeVerifierLength), false},
+		{"the challenge itself", testCodeChallenge, false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyCodeChallenge(tt.verifier, testCodeChallenge); got != tt.want {
+				t.Fatalf("verifyCodeChallenge(%q) = %v, want %v", tt.verifier, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeRequiresS256Challenge(t *testing.T) {
+	o := newTestOAuth(t)
+	tests := []struct {
+		name   string
+		modify func(*AuthorizeParams)
+	}{
+		{"plain method", func(p *AuthorizeParams) { p.CodeChallengeMethod = "plain" }},
+		{"no method", func(p *AuthorizeParams) { p.CodeChallengeMethod = "" }},
+		{"no challenge", func(p *AuthorizeParams) { p.CodeChallenge = "" }},
+		{"short challenge", func(p *AuthorizeParams) { p.CodeChallenge = testCodeChallenge[1:] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := o.authorize
+			tt.modify(&params)
+			_, err := o.service.Authorize(context.Background(), params, true)
+			var redirectErr *RedirectError
+			if !errors.As(err, &redirectErr) || redirectErr.Err.Code != CodeInvalidRequest {
+				t.Fatalf("Authorize error = %v, want an invalid_request redirect", err)
+			}
+		})
+	}
+}
+
+func TestExchangeCode(t *testing.T) {
+	tests := []struct {
+		name string
+		// exchange redeems the code, and returns the error code of the last attempt.
+		exchange func(o *testOAuth, code string) error
+		want     string // Empty when the last attempt succeeds
+	}{
+		{
+			"valid verifier",
+			func(o *testOAuth, code string) error {
+				_, err := o.exchange(code, testCodeVerifier)
+				return err
+			},
+			"",
+		},
+		{
+			"code used twice",
+			func(o *testOAuth, code string) error {
+				if _, err := o.exchange(code, testCodeVerifier); err != nil {
+					return err
+				}
+				_, err := o.exchange(code, testCodeVerifier)
+				return err
+			},
+			CodeInvalidGrant,
+		},
+		{
+			"wrong verifier",
+			func(o *testOAuth, code string) error {
+				_, err := o.exchange(code, strings.Repeat("a", minCodeVerifierLength))
+				return err
+			},
+			CodeInvalidGrant,
+		},
+		{
+			"right verifier after a wrong one",
+			func(o *testOAuth, code string) error {
+				o.exchange(code, strings.Repeat("a", minCodeVerifierLength))
+				_, err := o.exchange(code, testCodeVerifier)
+				return err
+			},
+			CodeInvalidGrant,
+		},
+		{
+			"short verifier",
+			func(o *testOAuth, code string) error {
+				_, err := o.exchange(code, testCodeVerifier[:minCodeVerifierLength-1])
+				return err
+			},
+			CodeInvalidRequest,
+		},
+		{
+			"another client",
+			func(o *testOAuth, code string) error {
+				_, err := o.service.Token(context.Background(), TokenParams{
+					Client:       ClientCredentials{ID: o.other.ID},
+					GrantType:    GrantTypeAuthorizationCode,
+					Code:         code,
+					RedirectURI:  testRedirectURI,
+					CodeVerifier: testCodeVerifier,
+				})
+				return err
+			},
+			CodeInvalidGrant,
+		},
+		{
+			"unknown code",
+			func(o *testOAuth, code string) error {
+				_, err := o.exchange(code+"x", testCodeVerifier)
+				return err
+			},
+			CodeInvalidGrant,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestOAuth(t)
+			err := tt.exchange(o, o.issueCode(t))
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Token: %v", err)
+				}
+				if len(o.repo.grants) != 1 || o.repo.grants[0].UserID != o.userID {
+					t.Fatalf("grants = %+v, want one for the user", o.repo.grants)
+				}
+				return
+			}
+			var oauthErr *Error
+			if !errors.As(err, &oauthErr) || oauthErr.Code != tt.want {
+				t.Fatalf("Token error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
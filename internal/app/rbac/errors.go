@@ -0,0 +1,10 @@
+package rbac
+
+import "errors"
+
+var (
+	ErrRoleNotFound        = errors.New("role does not exist")
+	ErrUserNotFound        = errors.New("user does not exist")
+	ErrRoleAlreadyAssigned = errors.New("user already has this role")
+	ErrRoleNotAssigned     = errors.New("user does not have this role")
+)
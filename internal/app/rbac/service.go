@@ -0,0 +1,194 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/moriverse/45-server/internal/domain/rbac"
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+const (
+	permissionsCacheKeyPrefix = "user-permissions"
+	// permissionsCacheTTL bounds how long a change made outside this service, such as directly
+	// in the database, takes to apply.
+	permissionsCacheTTL = 5 * time.Minute
+)
+
+// Service is the application service for roles and permissions.
+type Service struct {
+	rbacRepo    rbac.Repository
+	userRepo    user.Repository
+	redisClient *redis.Client
+	logger      *slog.Logger
+}
+
+// NewService creates a new instance of the RBAC service.
+func NewService(
+	rbacRepo rbac.Repository,
+	userRepo user.Repository,
+	redisClient *redis.Client,
+	logger *slog.Logger,
+) *Service {
+	return &Service{
+		rbacRepo:    rbacRepo,
+		userRepo:    userRepo,
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+func permissionsCacheKey(userID user.UserID) string {
+	return fmt.Sprintf("%s:%s", permissionsCacheKeyPrefix, userID)
+}
+
+// HasPermission reports whether any of the user's roles grants the permission.
+func (s *Service) HasPermission(
+	ctx context.Context,
+	userID user.UserID,
+	permission rbac.Permission,
+) (bool, error) {
+	permissions, err := s.permissions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// permissions returns the user's permissions, from the cache if possible. Most users have none,
+// so the empty list is cached too.
+func (s *Service) permissions(
+	ctx context.Context,
+	userID user.UserID,
+) ([]rbac.Permission, error) {
+	key := permissionsCacheKey(userID)
+	cached, err := s.redisClient.Get(ctx, key).Bytes()
+	if err == nil {
+		var permissions []rbac.Permission
+		if err := json.Unmarshal(cached, &permissions); err == nil {
+			return permissions, nil
+		}
+	} else if err != redis.Nil {
+		s.logger.Warn("Failed to read cached permissions", "userID", userID, "error", err)
+	}
+
+	permissions, err := s.rbacRepo.ListPermissionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if permissions == nil {
+		permissions = []rbac.Permission{}
+	}
+	encoded, _ := json.Marshal(permissions)
+	if err := s.redisClient.Set(ctx, key, encoded, permissionsCacheTTL).Err(); err != nil {
+		s.logger.Warn("Failed to cache permissions", "userID", userID, "error", err)
+	}
+	return permissions, nil
+}
+
+// ListRoles lists every role that can be granted.
+func (s *Service) ListRoles(ctx context.Context) ([]*rbac.Role, error) {
+	return s.rbacRepo.ListRoles(ctx)
+}
+
+// ListUserRoles lists the roles of a user.
+func (s *Service) ListUserRoles(ctx context.Context, userID user.UserID) ([]*rbac.Role, error) {
+	return s.rbacRepo.ListRolesByUserID(ctx, userID)
+}
+
+// AssignRoleParams contains the parameters for granting a role to a user.
+type AssignRoleParams struct {
+	UserID    user.UserID
+	RoleName  string
+	GrantedBy user.UserID
+}
+
+// AssignRole grants a role to a user. The new permissions apply to their next request.
+func (s *Service) AssignRole(ctx context.Context, params AssignRoleParams) (*rbac.Role, error) {
+	role, err := s.rbacRepo.FindRoleByName(ctx, params.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	u, err := s.userRepo.FindByID(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+
+	err = s.rbacRepo.AssignRole(ctx, &rbac.UserRole{
+		UserID:    params.UserID,
+		RoleID:    role.ID,
+		GrantedBy: params.GrantedBy,
+		CreatedAt: time.Now(),
+	})
+	if errors.Is(err, rbac.ErrRoleAlreadyAssigned) {
+		return nil, ErrRoleAlreadyAssigned
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.invalidatePermissions(ctx, params.UserID); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info(
+		"Role assigned",
+		"userID", params.UserID,
+		"role", role.Name,
+		"grantedBy", params.GrantedBy,
+	)
+	return role, nil
+}
+
+// RevokeRole takes a role away from a user. The permissions are lost on their next request.
+func (s *Service) RevokeRole(
+	ctx context.Context,
+	userID user.UserID,
+	roleName string,
+	revokedBy user.UserID,
+) error {
+	role, err := s.rbacRepo.FindRoleByName(ctx, roleName)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return ErrRoleNotFound
+	}
+
+	revoked, err := s.rbacRepo.RevokeRole(ctx, userID, role.ID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrRoleNotAssigned
+	}
+	if err := s.invalidatePermissions(ctx, userID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Role revoked", "userID", userID, "role", role.Name, "revokedBy", revokedBy)
+	return nil
+}
+
+func (s *Service) invalidatePermissions(ctx context.Context, userID user.UserID) error {
+	if err := s.redisClient.Del(ctx, permissionsCacheKey(userID)).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate cached permissions: %w", err)
+	}
+	return nil
+}
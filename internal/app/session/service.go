@@ -0,0 +1,146 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"github.com/moriverse/45-server/internal/domain/refreshtoken"
+	"github.com/moriverse/45-server/internal/domain/session"
+	"github.com/moriverse/45-server/internal/domain/unitofwork"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+const (
+	revokedSessionCacheKeyPrefix = "revoked-session"
+)
+
+// Service is the application service for managing a user's signed-in sessions.
+type Service struct {
+	uow         unitofwork.UnitOfWork
+	jwtConfig   config.JWTConfig
+	redisClient *redis.Client
+}
+
+// NewService creates a new instance of the session service.
+func NewService(
+	uow unitofwork.UnitOfWork,
+	jwtConfig config.JWTConfig,
+	redisClient *redis.Client,
+) *Service {
+	return &Service{
+		uow:         uow,
+		jwtConfig:   jwtConfig,
+		redisClient: redisClient,
+	}
+}
+
+func revokedSessionKey(sessionID session.SessionID) string {
+	return fmt.Sprintf("%s:%s", revokedSessionCacheKeyPrefix, sessionID)
+}
+
+// List returns the user's active sessions, most recently seen first.
+func (s *Service) List(ctx context.Context, userID user.UserID) ([]*session.Session, error) {
+	var sessions []*session.Session
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		var err error
+		sessions, err = work.Sessions().ListActiveByUserID(ctx, userID)
+		return err
+	})
+	return sessions, err
+}
+
+// Revoke signs the user out of one of their sessions. Its refresh tokens stop working
+// immediately, and its access tokens are rejected until they expire.
+func (s *Service) Revoke(
+	ctx context.Context,
+	userID user.UserID,
+	sessionID session.SessionID,
+) error {
+	if _, err := uuid.Parse(string(sessionID)); err != nil {
+		return ErrSessionNotFound
+	}
+
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		existing, err := work.Sessions().FindByID(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		if existing == nil || existing.UserID != userID {
+			return ErrSessionNotFound
+		}
+		return s.revoke(ctx, work, sessionID)
+	})
+	if err != nil {
+		return err
+	}
+	return s.denylist(ctx, sessionID)
+}
+
+// RevokeAll signs the user out of all of their sessions.
+func (s *Service) RevokeAll(ctx context.Context, userID user.UserID) error {
+	var revoked []session.SessionID
+	err := s.uow.Execute(ctx, func(work unitofwork.UserAuthWork) error {
+		sessions, err := work.Sessions().ListActiveByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, existing := range sessions {
+			if err := s.revoke(ctx, work, existing.ID); err != nil {
+				return err
+			}
+			revoked = append(revoked, existing.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range revoked {
+		if err := s.denylist(ctx, sessionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsRevoked reports whether access tokens for the session must be rejected.
+func (s *Service) IsRevoked(ctx context.Context, sessionID session.SessionID) (bool, error) {
+	n, err := s.redisClient.Exists(ctx, revokedSessionKey(sessionID)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *Service) revoke(
+	ctx context.Context,
+	work unitofwork.UserAuthWork,
+	sessionID session.SessionID,
+) error {
+	now := time.Now()
+	if err := work.Sessions().Revoke(ctx, sessionID, now); err != nil {
+		return err
+	}
+	// Every refresh token of a session belongs to the family named after it.
+	return work.RefreshTokens().RevokeFamily(ctx, refreshtoken.FamilyID(sessionID), now)
+}
+
+// denylist rejects the session's access tokens for as long as any of them can still be valid.
+func (s *Service) denylist(ctx context.Context, sessionID session.SessionID) error {
+	ttl := time.Duration(s.jwtConfig.AccessTokenExpiresInMinutes) * time.Minute
+	return s.redisClient.Set(ctx, revokedSessionKey(sessionID), "revoked", ttl).Err()
+}
+
+// DenylistTokens rejects access tokens with the given token ID for the given time. It is for
+// tokens that do not belong to a session, such as those issued to OAuth clients, so that
+// AuthMiddleware has a single denylist to check.
+func (s *Service) DenylistTokens(ctx context.Context, tokenID string, ttl time.Duration) error {
+	key := revokedSessionKey(session.SessionID(tokenID))
+	return s.redisClient.Set(ctx, key, "revoked", ttl).Err()
+}
@@ -0,0 +1,13 @@
+package user
+
+import "errors"
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrInvalidNickname  = errors.New("nickname must be 1-32 characters without control characters")
+	ErrInvalidAvatarURL = errors.New("avatar url must be an http or https url")
+	ErrInvalidBio       = errors.New("bio is too long")
+	ErrInvalidGender    = errors.New("gender is not supported")
+	ErrInvalidBirthday  = errors.New("birthday must be a past date in the format yyyy-mm-dd")
+	ErrInvalidLocale    = errors.New("locale must be a valid language tag")
+)
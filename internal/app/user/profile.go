@@ -0,0 +1,161 @@
+package user
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+const (
+	maxNicknameLength  = 32
+	maxBioLength       = 160
+	maxAvatarURLLength = 2048
+)
+
+// earliestBirthday rules out birthdays that are surely typing mistakes.
+var earliestBirthday = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// GetProfile returns the user with the given ID. Deleted users are not found.
+func (s *Service) GetProfile(ctx context.Context, userID user.UserID) (*user.User, error) {
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+// UpdateProfileParams contains the profile fields to change. Nil fields are left as they are,
+// and empty strings clear the field, except for the nickname, which cannot be cleared.
+type UpdateProfileParams struct {
+	UserID    user.UserID
+	Nickname  *string
+	AvatarURL *string
+	Bio       *string
+	Gender    *user.Gender
+	Birthday  *string // A date in the format yyyy-mm-dd
+	Locale    *string // A BCP 47 language tag
+}
+
+// UpdateProfile validates and saves changes to the user's profile, and returns the updated user.
+// An uploaded avatar that is replaced by another URL is deleted.
+func (s *Service) UpdateProfile(
+	ctx context.Context,
+	params UpdateProfileParams,
+) (*user.User, error) {
+	u, err := s.GetProfile(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Nickname != nil {
+		nickname := strings.TrimSpace(*params.Nickname)
+		if !isValidNickname(nickname) {
+			return nil, ErrInvalidNickname
+		}
+		u.Nickname = nickname
+	}
+	var previous *user.User
+	if params.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*params.AvatarURL)
+		if avatarURL != "" && !isValidAvatarURL(avatarURL) {
+			return nil, ErrInvalidAvatarURL
+		}
+		if avatarURL != u.AvatarURL {
+			unchanged := *u
+			previous = &unchanged
+		}
+		u.AvatarURL = avatarURL
+	}
+	if params.Bio != nil {
+		bio := strings.TrimSpace(*params.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			return nil, ErrInvalidBio
+		}
+		u.Bio = bio
+	}
+	if params.Gender != nil {
+		if !params.Gender.IsValid() {
+			return nil, ErrInvalidGender
+		}
+		u.Gender = *params.Gender
+	}
+	if params.Birthday != nil {
+		birthday, err := parseBirthday(*params.Birthday)
+		if err != nil {
+			return nil, err
+		}
+		u.Birthday = birthday
+	}
+	if params.Locale != nil {
+		locale, err := canonicalLocale(*params.Locale)
+		if err != nil {
+			return nil, err
+		}
+		u.Locale = locale
+	}
+
+	u.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	if previous != nil {
+		s.avatarService.DeleteUploaded(previous)
+	}
+	return u, nil
+}
+
+func isValidNickname(nickname string) bool {
+	length := utf8.RuneCountInString(nickname)
+	if length == 0 || length > maxNicknameLength {
+		return false
+	}
+	return strings.IndexFunc(nickname, unicode.IsControl) < 0
+}
+
+func isValidAvatarURL(avatarURL string) bool {
+	if len(avatarURL) > maxAvatarURLLength {
+		return false
+	}
+	parsed, err := url.Parse(avatarURL)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != ""
+}
+
+// parseBirthday parses a date in the format yyyy-mm-dd. An empty string clears the birthday.
+func parseBirthday(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	birthday, err := time.Parse(time.DateOnly, value)
+	if err != nil || birthday.Before(earliestBirthday) || birthday.After(time.Now()) {
+		return nil, ErrInvalidBirthday
+	}
+	return &birthday, nil
+}
+
+// canonicalLocale returns the canonical form of a BCP 47 language tag, so that "zh_cn" is
+// stored as "zh-CN". An empty string clears the locale.
+func canonicalLocale(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	tag, err := language.Parse(strings.ReplaceAll(value, "_", "-"))
+	if err != nil {
+		return "", ErrInvalidLocale
+	}
+	return tag.String(), nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	appAvatar "github.com/moriverse/45-server/internal/app/avatar"
 	"github.com/moriverse/45-server/internal/domain/user"
 )
 
@@ -17,21 +18,24 @@ const (
 
 // Service is the application service for user-related operations.
 type Service struct {
-	userRepo    user.Repository
-	redisClient *redis.Client
-	logger      *slog.Logger
+	userRepo      user.Repository
+	avatarService *appAvatar.Service
+	redisClient   *redis.Client
+	logger        *slog.Logger
 }
 
 // NewService creates a new instance of the user service.
 func NewService(
 	userRepo user.Repository,
+	avatarService *appAvatar.Service,
 	redisClient *redis.Client,
 	logger *slog.Logger,
 ) *Service {
 	return &Service{
-		userRepo:    userRepo,
-		redisClient: redisClient,
-		logger:      logger,
+		userRepo:      userRepo,
+		avatarService: avatarService,
+		redisClient:   redisClient,
+		logger:        logger,
 	}
 }
 
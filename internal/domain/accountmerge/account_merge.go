@@ -0,0 +1,31 @@
+package accountmerge
+
+import (
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type AccountMergeID string
+
+// Reason records how a merge was started.
+type Reason string
+
+const (
+	// SelfService merges are confirmed by a user who proved they own an identity of the other
+	// account.
+	SelfService Reason = "self_service"
+	// Support merges are performed by staff on behalf of the user.
+	Support Reason = "support"
+)
+
+// AccountMerge is the audit record of merging one user into another. The source user's
+// identities and data now belong to the target user, and the source user is deleted.
+type AccountMerge struct {
+	ID           AccountMergeID
+	SourceUserID user.UserID
+	TargetUserID user.UserID
+	InitiatedBy  user.UserID
+	Reason       Reason
+	CreatedAt    time.Time
+}
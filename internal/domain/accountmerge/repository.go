@@ -0,0 +1,12 @@
+package accountmerge
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, merge *AccountMerge) error
+	WithTx(tx *gorm.DB) Repository
+}
@@ -0,0 +1,44 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/rbac"
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type APIKeyID string
+
+// APIKey lets a server-to-server client call the API as a service principal. Only the hash of
+// the secret part of the key is stored.
+type APIKey struct {
+	ID     APIKeyID
+	Name   string // The service or partner the key was issued to
+	Prefix string // The public part of the key, used to look it up and to recognize it
+	// KeyHash is the hash of the whole key.
+	KeyHash    string
+	Scopes     []rbac.Permission // What the key may do
+	CreatedBy  user.UserID
+	ExpiresAt  *time.Time // Nil for keys that never expire
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// IsActive reports whether the key can still be used.
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// HasScope reports whether the key grants the permission.
+func (k *APIKey) HasScope(scope rbac.Permission) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type Repository interface {
+	Create(ctx context.Context, key *APIKey) error
+	FindByID(ctx context.Context, id APIKeyID) (*APIKey, error)
+	FindByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	// List lists every API key, newest first.
+	List(ctx context.Context) ([]*APIKey, error)
+	// Revoke revokes an active key, reporting whether there was one.
+	Revoke(ctx context.Context, id APIKeyID, t time.Time) (bool, error)
+	UpdateLastUsedAt(ctx context.Context, id APIKeyID, t time.Time) error
+	// ReassignCreator records another user as the creator of every key one user created.
+	ReassignCreator(ctx context.Context, from user.UserID, to user.UserID) error
+	WithTx(tx *gorm.DB) Repository
+}
@@ -14,14 +14,28 @@ const (
 	Phone  Provider = "phone"
 	Wechat Provider = "wechat"
 	Google Provider = "google"
+	Apple  Provider = "apple"
+	// Password identities are keyed by the lowercased username or email address the user
+	// signs in with.
+	Password Provider = "password"
+	// Guest identities are keyed by the hash of the secret issued to a device on which a user
+	// tried the app before signing in.
+	Guest Provider = "guest"
 )
 
 type Auth struct {
-	ID           AuthID
-	UserID       user.UserID
-	Provider     Provider
-	ProviderID   string
-	PasswordHash string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID         AuthID
+	UserID     user.UserID
+	Provider   Provider
+	ProviderID string
+	UnionID    string // Groups WeChat identities of the same person across our WeChat apps
+	Email      string // The email address asserted by the provider, if any
+	// EmailIsPrivate marks relay addresses such as Apple's "Hide My Email", which only forward
+	// to the user and must not be treated as their real address.
+	EmailIsPrivate  bool
+	EmailVerifiedAt *time.Time // When the user proved they control Email
+	PasswordHash    string
+	DeviceID        string // The device a guest identity is bound to
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
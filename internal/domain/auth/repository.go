@@ -2,12 +2,32 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/user"
 )
 
+// ErrIdentityTaken is returned when saving an auth record whose provider identity belongs to
+// another auth record.
+var ErrIdentityTaken = errors.New("identity is already taken")
+
 type Repository interface {
 	Create(ctx context.Context, auth *Auth) error
+	FindByID(ctx context.Context, id AuthID) (*Auth, error)
 	FindByProvider(ctx context.Context, provider Provider, providerUserID string) (*Auth, error)
+	FindByUnionID(ctx context.Context, provider Provider, unionID string) (*Auth, error)
+	// LockUnionID holds a lock on a provider's union ID until the transaction ends, so that two
+	// first sign-ins of the same person cannot each create a user.
+	LockUnionID(ctx context.Context, provider Provider, unionID string) error
+	UpdateUnionID(ctx context.Context, id AuthID, unionID string) error
+	UpdatePasswordHash(ctx context.Context, id AuthID, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, id AuthID, t time.Time) error
+	ListByUserID(ctx context.Context, userID user.UserID) ([]*Auth, error)
+	Delete(ctx context.Context, id AuthID) error
+	// ReassignUser moves every auth record of one user to another.
+	ReassignUser(ctx context.Context, from user.UserID, to user.UserID) error
 	WithTx(tx *gorm.DB) Repository
 }
@@ -0,0 +1,22 @@
+package impersonation
+
+import (
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type ImpersonationID string
+
+// Impersonation is the audit record of a staff member acting as a user. Its ID is the ID of
+// the access token issued for it, so requests made with the token can be traced back to it.
+type Impersonation struct {
+	ID           ImpersonationID
+	ActorID      user.UserID // The staff member
+	TargetUserID user.UserID
+	Reason       string
+	IPAddress    string
+	UserAgent    string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
@@ -0,0 +1,12 @@
+package impersonation
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, impersonation *Impersonation) error
+	WithTx(tx *gorm.DB) Repository
+}
@@ -0,0 +1,41 @@
+package loginevent
+
+import (
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type LoginEventID string
+
+// LoginEvent records a successful or failed login. Failed logins are recorded even when the
+// account could not be told, in which case UserID is empty.
+type LoginEvent struct {
+	ID            LoginEventID
+	UserID        user.UserID
+	Provider      string // The login method, such as phone, password or wechat
+	Success       bool
+	FailureReason string // Only for failed logins
+	IPAddress     string
+	UserAgent     string
+	DeviceID      string
+	Source        user.Source
+	Country       string // ISO 3166-1 alpha-2 code, empty if unknown
+	// NewDevice and NewCountry flag a successful login from a device or country the user had
+	// not signed in from before.
+	NewDevice  bool
+	NewCountry bool
+	CreatedAt  time.Time
+}
+
+// IsSuspicious reports whether the user should be told about the login.
+func (e *LoginEvent) IsSuspicious() bool {
+	return e.Success && (e.NewDevice || e.NewCountry)
+}
+
+// Locator finds the country an IP address is in.
+type Locator interface {
+	// Country returns the ISO 3166-1 alpha-2 code of the country, or an empty string if it is
+	// not known.
+	Country(ipAddress string) string
+}
@@ -0,0 +1,26 @@
+package loginevent
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type Repository interface {
+	Create(ctx context.Context, event *LoginEvent) error
+	// ListByUserID lists the user's most recent logins, newest first.
+	ListByUserID(ctx context.Context, userID user.UserID, limit int) ([]*LoginEvent, error)
+	// HasSucceeded reports whether the user has logged in successfully before.
+	HasSucceeded(ctx context.Context, userID user.UserID) (bool, error)
+	// HasSucceededFromDevice reports whether the user has logged in successfully from the
+	// device before.
+	HasSucceededFromDevice(ctx context.Context, userID user.UserID, deviceID string) (bool, error)
+	// HasSucceededFromCountry reports whether the user has logged in successfully from the
+	// country before.
+	HasSucceededFromCountry(ctx context.Context, userID user.UserID, country string) (bool, error)
+	// ReassignUser moves every login event of one user to another.
+	ReassignUser(ctx context.Context, from user.UserID, to user.UserID) error
+	WithTx(tx *gorm.DB) Repository
+}
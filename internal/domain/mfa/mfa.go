@@ -0,0 +1,36 @@
+package mfa
+
+import (
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+// TOTP is a user's authenticator app enrollment. It only protects logins once confirmed.
+type TOTP struct {
+	UserID      user.UserID
+	Secret      string // Base32-encoded shared secret
+	ConfirmedAt *time.Time
+	// LastUsedStep is the time step of the last accepted code, so that a code cannot be used
+	// twice.
+	LastUsedStep int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// IsEnabled reports whether the enrollment has been confirmed.
+func (t *TOTP) IsEnabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+type RecoveryCodeID string
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the user has lost
+// their authenticator. Only the hash of the code is stored.
+type RecoveryCode struct {
+	ID        RecoveryCodeID
+	UserID    user.UserID
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
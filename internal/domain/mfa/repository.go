@@ -0,0 +1,31 @@
+package mfa
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type Repository interface {
+	// FindTOTPByUserID finds a user's TOTP enrollment and locks it for the rest of the
+	// transaction.
+	FindTOTPByUserID(ctx context.Context, userID user.UserID) (*TOTP, error)
+	SaveTOTP(ctx context.Context, totp *TOTP) error
+	DeleteTOTP(ctx context.Context, userID user.UserID) error
+	// ReplaceRecoveryCodes deletes a user's recovery codes and stores the given ones.
+	ReplaceRecoveryCodes(ctx context.Context, userID user.UserID, codes []*RecoveryCode) error
+	// UseRecoveryCode marks an unused recovery code as used, reporting whether there was one.
+	UseRecoveryCode(
+		ctx context.Context,
+		userID user.UserID,
+		codeHash string,
+		t time.Time,
+	) (bool, error)
+	// ReassignUser moves the TOTP enrollment and recovery codes of one user to another,
+	// replacing the other user's.
+	ReassignUser(ctx context.Context, from user.UserID, to user.UserID) error
+	WithTx(tx *gorm.DB) Repository
+}
@@ -0,0 +1,24 @@
+package notification
+
+import "context"
+
+type EmailType string
+
+const (
+	EmailPasswordReset     EmailType = "password_reset"
+	EmailEmailVerification EmailType = "email_verification"
+	EmailAccountExists     EmailType = "account_exists"
+)
+
+// Email is a message to be delivered to an email address. The subject and body are rendered by
+// the sender from templates selected by Type, using Params as their data.
+type Email struct {
+	To     string
+	Type   EmailType
+	Params map[string]string
+}
+
+// EmailSender is the port for delivering email to users.
+type EmailSender interface {
+	SendEmail(ctx context.Context, email Email) error
+}
@@ -0,0 +1,25 @@
+package notification
+
+import (
+	"context"
+	"time"
+)
+
+// SuspiciousLogin describes a login from a device or country the user has not signed in from
+// before.
+type SuspiciousLogin struct {
+	UserID     string
+	Provider   string
+	IPAddress  string
+	UserAgent  string
+	Country    string
+	NewDevice  bool
+	NewCountry bool
+	At         time.Time
+}
+
+// LoginAlerter is the hook that is told about suspicious logins, so that the user can be
+// warned.
+type LoginAlerter interface {
+	AlertSuspiciousLogin(ctx context.Context, login SuspiciousLogin) error
+}
@@ -0,0 +1,28 @@
+package notification
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrSMSThrottled is returned when too many messages have been sent to a phone number.
+var ErrSMSThrottled = errors.New("sms sending is throttled for this phone number")
+
+type SMSType string
+
+const (
+	SMSVerificationCode SMSType = "verification_code"
+)
+
+// SMS is a text message to be delivered to a phone number. The content of the message is
+// rendered by the sender from a template selected by Type, using Params as its data.
+type SMS struct {
+	PhoneNumber string
+	Type        SMSType
+	Params      map[string]string
+}
+
+// SMSSender is the port for delivering text messages to users.
+type SMSSender interface {
+	SendSMS(ctx context.Context, sms SMS) error
+}
@@ -0,0 +1,128 @@
+package oauth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+// Scope names what a third-party client may access on behalf of a user.
+type Scope string
+
+const (
+	ScopeProfile Scope = "profile" // The user's ID, nickname and avatar
+	ScopePhone   Scope = "phone"   // The user's phone number
+)
+
+// IsValid reports whether the scope is one of the known scopes.
+func (s Scope) IsValid() bool {
+	switch s {
+	case ScopeProfile, ScopePhone:
+		return true
+	}
+	return false
+}
+
+// ParseScopes splits a space-delimited OAuth scope string.
+func ParseScopes(scope string) []Scope {
+	fields := strings.Fields(scope)
+	scopes := make([]Scope, 0, len(fields))
+	for _, f := range fields {
+		scopes = append(scopes, Scope(f))
+	}
+	return scopes
+}
+
+// FormatScopes joins scopes into a space-delimited OAuth scope string.
+func FormatScopes(scopes []Scope) string {
+	parts := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		parts = append(parts, string(s))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ContainsAll reports whether every scope in want is in have.
+func ContainsAll(have, want []Scope) bool {
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if h == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type ClientID string
+
+// Client is a third-party app registered to let users "Log in with 45". Confidential clients,
+// which run on a server, authenticate with a secret; public clients, such as mobile apps,
+// cannot keep one and rely on PKCE alone. Only the hash of the secret is stored.
+type Client struct {
+	ID           ClientID
+	Name         string
+	SecretHash   string // Empty for public clients
+	RedirectURIs []string
+	Scopes       []Scope // The scopes the client may request
+	CreatedBy    user.UserID
+	RevokedAt    *time.Time
+	CreatedAt    time.Time
+}
+
+// IsConfidential reports whether the client must authenticate with a secret.
+func (c *Client) IsConfidential() bool {
+	return c.SecretHash != ""
+}
+
+// IsActive reports whether the client may still be used.
+func (c *Client) IsActive() bool {
+	return c.RevokedAt == nil
+}
+
+// AllowsRedirectURI reports whether the URI exactly matches one of the registered ones.
+func (c *Client) AllowsRedirectURI(uri string) bool {
+	for _, registered := range c.RedirectURIs {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// Consent records the scopes a user has allowed a client, so that they are not asked again.
+type Consent struct {
+	UserID    user.UserID
+	ClientID  ClientID
+	Scopes    []Scope
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type GrantID string
+
+// Grant is the authorization a user gave a client by completing the authorization code flow.
+// Its ID is the ID of every access token issued under it, and it holds the current refresh
+// token, which is replaced on each use.
+type Grant struct {
+	ID               GrantID
+	ClientID         ClientID
+	UserID           user.UserID
+	Scopes           []Scope
+	RefreshTokenHash string
+	ExpiresAt        time.Time // When the refresh token expires
+	RevokedAt        *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// IsActive reports whether the grant's refresh token can still be used.
+func (g *Grant) IsActive(now time.Time) bool {
+	return g.RevokedAt == nil && now.Before(g.ExpiresAt)
+}
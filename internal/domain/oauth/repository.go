@@ -0,0 +1,53 @@
+package oauth
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type Repository interface {
+	CreateClient(ctx context.Context, client *Client) error
+	FindClientByID(ctx context.Context, id ClientID) (*Client, error)
+	// ListClients lists every client, newest first.
+	ListClients(ctx context.Context) ([]*Client, error)
+	// RevokeClient revokes an active client, reporting whether there was one.
+	RevokeClient(ctx context.Context, id ClientID, t time.Time) (bool, error)
+
+	FindConsent(ctx context.Context, userID user.UserID, clientID ClientID) (*Consent, error)
+	SaveConsent(ctx context.Context, consent *Consent) error
+	// ListConsentsByUserID lists the clients a user has allowed, most recently updated first.
+	ListConsentsByUserID(ctx context.Context, userID user.UserID) ([]*Consent, error)
+	DeleteConsent(ctx context.Context, userID user.UserID, clientID ClientID) (bool, error)
+
+	CreateGrant(ctx context.Context, grant *Grant) error
+	FindGrantByID(ctx context.Context, id GrantID) (*Grant, error)
+	FindGrantByRefreshTokenHash(ctx context.Context, tokenHash string) (*Grant, error)
+	// RotateRefreshToken replaces the refresh token of an active grant, but only if it is
+	// still oldHash, so that a refresh token can be used once.
+	RotateRefreshToken(
+		ctx context.Context,
+		id GrantID,
+		oldHash string,
+		newHash string,
+		expiresAt time.Time,
+	) (bool, error)
+	RevokeGrant(ctx context.Context, id GrantID, t time.Time) error
+	// RevokeGrantsByClientID revokes every active grant of a client, returning their IDs.
+	RevokeGrantsByClientID(ctx context.Context, clientID ClientID, t time.Time) ([]GrantID, error)
+	// RevokeGrantsByUserAndClient revokes the active grants a user gave a client, returning
+	// their IDs.
+	RevokeGrantsByUserAndClient(
+		ctx context.Context,
+		userID user.UserID,
+		clientID ClientID,
+		t time.Time,
+	) ([]GrantID, error)
+	// ReassignUser moves every grant and consent of one user to another. Consents to clients
+	// the other user has already allowed are dropped.
+	ReassignUser(ctx context.Context, from user.UserID, to user.UserID) error
+	WithTx(tx *gorm.DB) Repository
+}
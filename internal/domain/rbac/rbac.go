@@ -0,0 +1,47 @@
+package rbac
+
+import (
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type RoleID string
+
+// Permission names an action, as "<resource>:<action>". Routes require permissions, and users
+// get them through their roles.
+type Permission string
+
+const (
+	UsersRead          Permission = "users:read"
+	UsersBan           Permission = "users:ban"
+	UsersImpersonate   Permission = "users:impersonate"
+	RolesManage        Permission = "roles:manage"
+	APIKeysManage      Permission = "api_keys:manage"
+	OAuthClientsManage Permission = "oauth_clients:manage"
+)
+
+// IsValid reports whether the permission is one of the known permissions.
+func (p Permission) IsValid() bool {
+	switch p {
+	case UsersRead, UsersBan, UsersImpersonate, RolesManage, APIKeysManage, OAuthClientsManage:
+		return true
+	}
+	return false
+}
+
+type Role struct {
+	ID          RoleID
+	Name        string
+	Description string
+	Permissions []Permission
+	CreatedAt   time.Time
+}
+
+// UserRole grants a role to a user.
+type UserRole struct {
+	UserID    user.UserID
+	RoleID    RoleID
+	GrantedBy user.UserID
+	CreatedAt time.Time
+}
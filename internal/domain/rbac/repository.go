@@ -0,0 +1,28 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+// ErrRoleAlreadyAssigned is returned when granting a role the user already has.
+var ErrRoleAlreadyAssigned = errors.New("user already has this role")
+
+type Repository interface {
+	FindRoleByName(ctx context.Context, name string) (*Role, error)
+	ListRoles(ctx context.Context) ([]*Role, error)
+	ListRolesByUserID(ctx context.Context, userID user.UserID) ([]*Role, error)
+	// ListPermissionsByUserID lists the permissions a user has through any of their roles.
+	ListPermissionsByUserID(ctx context.Context, userID user.UserID) ([]Permission, error)
+	AssignRole(ctx context.Context, userRole *UserRole) error
+	// RevokeRole takes a role away from a user, reporting whether they had it.
+	RevokeRole(ctx context.Context, userID user.UserID, roleID RoleID) (bool, error)
+	// ReassignUser moves every role of one user to another, dropping those the other user
+	// already has.
+	ReassignUser(ctx context.Context, from user.UserID, to user.UserID) error
+	WithTx(tx *gorm.DB) Repository
+}
@@ -0,0 +1,28 @@
+package refreshtoken
+
+import (
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type RefreshTokenID string
+
+// FamilyID identifies a chain of refresh tokens that were issued by rotating a single login.
+type FamilyID string
+
+type RefreshToken struct {
+	ID        RefreshTokenID
+	UserID    user.UserID
+	FamilyID  FamilyID
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// IsExpired reports whether the token has expired at the given time.
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
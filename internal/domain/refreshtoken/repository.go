@@ -0,0 +1,17 @@
+package refreshtoken
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, token *RefreshToken) error
+	// FindByTokenHash finds a token by its hash and locks it for the rest of the transaction.
+	FindByTokenHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	MarkUsed(ctx context.Context, id RefreshTokenID, t time.Time) error
+	RevokeFamily(ctx context.Context, familyID FamilyID, t time.Time) error
+	WithTx(tx *gorm.DB) Repository
+}
@@ -0,0 +1,31 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type Repository interface {
+	Create(ctx context.Context, session *Session) error
+	FindByID(ctx context.Context, id SessionID) (*Session, error)
+	ListActiveByUserID(ctx context.Context, userID user.UserID) ([]*Session, error)
+	UpdateLastSeen(
+		ctx context.Context,
+		id SessionID,
+		ipAddress string,
+		lastSeenAt time.Time,
+		expiresAt time.Time,
+	) error
+	UpdateAuthentication(
+		ctx context.Context,
+		id SessionID,
+		authTime time.Time,
+		amr []string,
+	) error
+	Revoke(ctx context.Context, id SessionID, t time.Time) error
+	WithTx(tx *gorm.DB) Repository
+}
@@ -0,0 +1,30 @@
+package session
+
+import (
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type SessionID string
+
+// Session represents a signed-in device. A session is created on every login and lives for as
+// long as its refresh tokens keep being rotated.
+type Session struct {
+	ID         SessionID
+	UserID     user.UserID
+	DeviceID   string // Client-provided device identifier, if any
+	UserAgent  string
+	IPAddress  string
+	AuthTime   time.Time // When the user last proved their identity on this session
+	AMR        []string  // Authentication methods used at AuthTime, e.g. "password" and "mfa"
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// IsActive reports whether the session can still be used at the given time.
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidKey is returned for keys that are empty, absolute or contain "." or ".." segments.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore is the port for storing files, such as uploaded images, outside the database. Keys
+// are slash-separated paths, such as "avatars/<user>/<avatar>/128.jpg".
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes a blob. Deleting a blob that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that lets anyone download the blob until it expires, so that
+	// clients can fetch blobs without our credentials.
+	SignedURL(ctx context.Context, key string, expiresIn time.Duration) (string, error)
+}
@@ -3,14 +3,34 @@ package unitofwork
 import (
 	"context"
 
+	"github.com/moriverse/45-server/internal/domain/accountmerge"
+	"github.com/moriverse/45-server/internal/domain/apikey"
 	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/impersonation"
+	"github.com/moriverse/45-server/internal/domain/loginevent"
+	"github.com/moriverse/45-server/internal/domain/mfa"
+	"github.com/moriverse/45-server/internal/domain/oauth"
+	"github.com/moriverse/45-server/internal/domain/rbac"
+	"github.com/moriverse/45-server/internal/domain/refreshtoken"
+	"github.com/moriverse/45-server/internal/domain/session"
 	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/domain/verificationtoken"
 )
 
 // UserAuthWork defines the repositories that can be used in a user-auth transaction.
 type UserAuthWork interface {
 	Users() user.Repository
 	Auths() auth.Repository
+	RefreshTokens() refreshtoken.Repository
+	Sessions() session.Repository
+	VerificationTokens() verificationtoken.Repository
+	AccountMerges() accountmerge.Repository
+	MFA() mfa.Repository
+	Impersonations() impersonation.Repository
+	Roles() rbac.Repository
+	LoginEvents() loginevent.Repository
+	OAuth() oauth.Repository
+	APIKeys() apikey.Repository
 }
 
 // UnitOfWork is an interface for managing transactional units of work.
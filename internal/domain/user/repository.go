@@ -2,17 +2,25 @@ package user
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// ErrPhoneNumberTaken is returned when saving a user whose phone number belongs to another user.
+var ErrPhoneNumberTaken = errors.New("phone number is already taken")
+
 type Repository interface {
 	Create(ctx context.Context, user *User) error
 	FindByID(ctx context.Context, id UserID) (*User, error)
 	FindByPhoneNumber(ctx context.Context, phoneNumber string) (*User, error)
 	Update(ctx context.Context, user *User) error
+	// Delete marks a user as deleted and releases their phone number.
 	Delete(ctx context.Context, id UserID) error
 	UpdateLastActiveAt(ctx context.Context, id UserID, t time.Time) error
+	// DeleteStaleGuests permanently deletes up to limit guest users who have not been active
+	// since the given time, and returns the deleted users.
+	DeleteStaleGuests(ctx context.Context, inactiveSince time.Time, limit int) ([]*User, error)
 	WithTx(tx *gorm.DB) Repository
 }
@@ -16,11 +16,44 @@ const (
 	Web           Source = "web"
 )
 
+// IsValid reports whether the source is one of the known client sources.
+func (s Source) IsValid() bool {
+	switch s {
+	case WechatIOS, WechatAndroid, IOS, Android, Web:
+		return true
+	}
+	return false
+}
+
+// Gender is the gender a user chose to show on their profile. It is empty if they did not say.
+type Gender string
+
+const (
+	GenderMale   Gender = "male"
+	GenderFemale Gender = "female"
+	GenderOther  Gender = "other"
+)
+
+// IsValid reports whether the gender is one of the known genders or unset.
+func (g Gender) IsValid() bool {
+	switch g {
+	case "", GenderMale, GenderFemale, GenderOther:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	ID           UserID
 	PhoneNumber  string
+	Nickname     string
 	AvatarURL    string
+	Bio          string
+	Gender       Gender
+	Birthday     *time.Time // A date, at midnight UTC
+	Locale       string     // A BCP 47 language tag, such as "zh-CN"
 	Source       Source
+	IsGuest      bool // Created on a device without signing in; has only a guest identity
 	OnboardedAt  *time.Time
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
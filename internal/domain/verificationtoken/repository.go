@@ -0,0 +1,23 @@
+package verificationtoken
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type Repository interface {
+	Create(ctx context.Context, token *VerificationToken) error
+	// FindByTokenHash finds a token by its hash and locks it for the rest of the transaction.
+	FindByTokenHash(ctx context.Context, tokenHash string) (*VerificationToken, error)
+	MarkUsed(ctx context.Context, id VerificationTokenID, t time.Time) error
+	// InvalidateAll marks every unused token of the identity with the given purpose as used.
+	InvalidateAll(ctx context.Context, authID auth.AuthID, purpose Purpose, t time.Time) error
+	// ReassignUser moves every token of one user to another.
+	ReassignUser(ctx context.Context, from user.UserID, to user.UserID) error
+	WithTx(tx *gorm.DB) Repository
+}
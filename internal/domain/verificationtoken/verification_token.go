@@ -0,0 +1,37 @@
+package verificationtoken
+
+import (
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/user"
+)
+
+type VerificationTokenID string
+
+// Purpose is the action a verification token authorizes.
+type Purpose string
+
+const (
+	PasswordReset     Purpose = "password_reset"
+	EmailVerification Purpose = "email_verification"
+)
+
+// VerificationToken is a single-use token sent to the email address of an identity to prove
+// that the user controls it. Only the hash of the token is stored.
+type VerificationToken struct {
+	ID        VerificationTokenID
+	UserID    user.UserID
+	AuthID    auth.AuthID
+	Purpose   Purpose
+	TokenHash string
+	Email     string // The address the token was sent to
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// IsUsable reports whether the token can still be redeemed at the given time.
+func (t *VerificationToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,155 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// LocalStore keeps blobs as files under a directory, and serves them itself at the signed URLs
+// it hands out. It suits development and single-server deployments.
+type LocalStore struct {
+	root       string
+	baseURL    *url.URL
+	signingKey []byte
+}
+
+// NewLocalStore creates a new LocalStore, creating its root directory if needed.
+func NewLocalStore(cfg config.LocalBlobStoreConfig) (*LocalStore, error) {
+	if cfg.SigningKey == "" {
+		return nil, errors.New("local blob store needs a signing key")
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+	if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid local blob store base url: %q", cfg.BaseURL)
+	}
+	// The files are served under the base URL's path, which must not take over the whole server.
+	if baseURL.Path == "" {
+		return nil, fmt.Errorf("local blob store base url has no path: %q", cfg.BaseURL)
+	}
+	if err := os.MkdirAll(cfg.Root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob store directory: %w", err)
+	}
+	return &LocalStore{root: cfg.Root, baseURL: baseURL, signingKey: []byte(cfg.SigningKey)}, nil
+}
+
+// Put writes the blob to a temporary file first, so that readers never see a partial file.
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	target := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// Delete removes the blob's file.
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// SignedURL returns a URL on this server that serves the blob until it expires.
+func (s *LocalStore) SignedURL(
+	ctx context.Context,
+	key string,
+	expiresIn time.Duration,
+) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(time.Now().Add(expiresIn).Unix(), 10)
+
+	u := *s.baseURL
+	u.Path = s.baseURL.Path + "/" + key
+	u.RawQuery = url.Values{
+		"expires":   {expires},
+		"signature": {s.sign(key, expires)},
+	}.Encode()
+	return u.String(), nil
+}
+
+// ServeHTTP serves a blob to the holder of a signed URL that has not expired. The handler must
+// be mounted at MountPath.
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := strings.CutPrefix(r.URL.Path, s.baseURL.Path+"/")
+	if !ok || validateKey(key) != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	expires := query.Get("expires")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	signature := []byte(query.Get("signature"))
+	if err != nil || time.Now().Unix() > expiresAt ||
+		!hmac.Equal(signature, []byte(s.sign(key, expires))) {
+		http.Error(w, "invalid or expired signature", http.StatusForbidden)
+		return
+	}
+
+	f, err := os.Open(s.path(key))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	maxAge := expiresAt - time.Now().Unix()
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// MountPath returns the path of the base URL, under which ServeHTTP expects its requests.
+func (s *LocalStore) MountPath() string {
+	return s.baseURL.Path
+}
+
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+// sign returns the signature of a download URL for the key that expires at the given time.
+func (s *LocalStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,127 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/storage"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+func newTestLocalStore(t *testing.T) *LocalStore {
+	t.Helper()
+	store, err := NewLocalStore(config.LocalBlobStoreConfig{
+		Root:       t.TempDir(),
+		BaseURL:    "http://localhost:8080/files/",
+		SigningKey: "test-signing-key",
+	})
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	return store
+}
+
+// serve sends a request for the URL to the store's handler.
+func serve(store *LocalStore, rawURL string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, rawURL, nil))
+	return rec
+}
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	store := newTestLocalStore(t)
+	ctx := context.Background()
+	key := "avatars/u1/a1/256.jpg"
+
+	if got := store.MountPath(); got != "/files" {
+		t.Errorf("MountPath = %q, want /files", got)
+	}
+	if err := store.Put(ctx, key, []byte("thumbnail"), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	signedURL, err := store.SignedURL(ctx, key, time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	if !strings.HasPrefix(signedURL, "http://localhost:8080/files/"+key+"?") {
+		t.Errorf("SignedURL = %s, want it under the base URL", signedURL)
+	}
+	rec := serve(store, signedURL)
+	if rec.Code != http.StatusOK || rec.Body.String() != "thumbnail" {
+		t.Errorf("GET signed URL = %d %q, want 200 with the blob", rec.Code, rec.Body)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if rec := serve(store, signedURL); rec.Code != http.StatusNotFound {
+		t.Errorf("GET deleted blob = %d, want 404", rec.Code)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing blob = %v, want nil", err)
+	}
+}
+
+func TestLocalStoreRejectsBadSignatures(t *testing.T) {
+	store := newTestLocalStore(t)
+	ctx := context.Background()
+	for _, key := range []string{"a.jpg", "b.jpg"} {
+		if err := store.Put(ctx, key, []byte(key), "image/jpeg"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	signedURL, err := store.SignedURL(ctx, "a.jpg", time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	expiredURL, err := store.SignedURL(ctx, "a.jpg", -time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+
+	tests := map[string]string{
+		"another key":       strings.Replace(signedURL, "/a.jpg?", "/b.jpg?", 1),
+		"expired":           expiredURL,
+		"no signature":      strings.Split(signedURL, "?")[0],
+		"changed signature": strings.Replace(signedURL, "signature=", "signature=0", 1),
+	}
+	for name, rawURL := range tests {
+		if rec := serve(store, rawURL); rec.Code == http.StatusOK {
+			t.Errorf("%s: GET = 200, want the blob to be refused", name)
+		}
+	}
+}
+
+func TestLocalStoreRejectsKeysOutsideRoot(t *testing.T) {
+	store := newTestLocalStore(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "a/../../outside", "a//b"} {
+		err := store.Put(ctx, key, []byte("x"), "text/plain")
+		if !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(store.root), "outside")); err == nil {
+		t.Error("a blob was written outside the root directory")
+	}
+}
+
+func TestNewLocalStoreNeedsBaseURLPath(t *testing.T) {
+	_, err := NewLocalStore(config.LocalBlobStoreConfig{
+		Root:       t.TempDir(),
+		BaseURL:    "http://localhost:8080",
+		SigningKey: "test-signing-key",
+	})
+	if err == nil {
+		t.Error("NewLocalStore accepted a base URL without a path")
+	}
+}
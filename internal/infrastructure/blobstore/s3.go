@@ -0,0 +1,281 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	amzDateLayout   = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// maxPresignExpiry is the longest lifetime S3 accepts for a presigned URL.
+	maxPresignExpiry = 7 * 24 * time.Hour
+)
+
+// S3Store keeps blobs in a bucket of Amazon S3 or of an S3-compatible server, such as MinIO.
+// Requests are signed with AWS Signature Version 4, and downloads use presigned URLs.
+type S3Store struct {
+	endpoint        *url.URL
+	region          string
+	bucket          string
+	accessKeyID     string
+	secretAccessKey string
+	usePathStyle    bool
+	httpClient      *http.Client
+}
+
+// NewS3Store creates a new S3Store.
+func NewS3Store(cfg config.S3BlobStoreConfig) (*S3Store, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3 blob store needs a bucket")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	return &S3Store{
+		endpoint:        endpoint,
+		region:          region,
+		bucket:          cfg.Bucket,
+		accessKeyID:     cfg.AccessKeyID,
+		secretAccessKey: cfg.SecretAccessKey,
+		usePathStyle:    cfg.UsePathStyle,
+		httpClient:      &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Put uploads the blob as an object.
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPut,
+		s.objectURL(key).String(),
+		bytes.NewReader(data),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.signRequest(req, sha256Hex(data), time.Now())
+	return s.do(req)
+}
+
+// Delete removes the object.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	s.signRequest(req, sha256Hex(nil), time.Now())
+	return s.do(req)
+}
+
+// SignedURL returns a presigned URL for downloading the object. S3 limits presigned URLs to
+// seven days.
+func (s *S3Store) SignedURL(
+	ctx context.Context,
+	key string,
+	expiresIn time.Duration,
+) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return s.presign(key, expiresIn, time.Now()), nil
+}
+
+// presign builds a presigned GET URL (query string authentication) signed at the given time.
+func (s *S3Store) presign(key string, expiresIn time.Duration, now time.Time) string {
+	expiresIn = min(max(expiresIn, time.Second), maxPresignExpiry)
+	now = now.UTC()
+
+	u := s.objectURL(key)
+	query := url.Values{
+		"X-Amz-Algorithm":     {sigV4Algorithm},
+		"X-Amz-Credential":    {s.accessKeyID + "/" + s.credentialScope(now)},
+		"X-Amz-Date":          {now.Format(amzDateLayout)},
+		"X-Amz-Expires":       {strconv.Itoa(int(expiresIn.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(now, canonicalRequest))
+	u.RawQuery = canonicalQuery(query)
+	return u.String()
+}
+
+// objectURL returns the URL of an object, with the bucket in the host name unless path-style
+// addressing is configured.
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.usePathStyle {
+		u.Path += "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path += "/" + key
+	}
+	// Send the path exactly as it is signed.
+	u.RawPath = uriEncode(u.Path, false)
+	return &u
+}
+
+// signRequest adds the Signature Version 4 authorization header to a request. Every header
+// the request carries at this point is signed.
+func (s *S3Store) signRequest(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(amzDateLayout))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm,
+		s.accessKeyID,
+		s.credentialScope(now),
+		signedHeaders,
+		s.signature(now, canonicalRequest),
+	))
+}
+
+func (s *S3Store) credentialScope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request with a key derived from the secret for the day.
+func (s *S3Store) signature(now time.Time, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		now.Format(amzDateLayout),
+		s.credentialScope(now),
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// do sends a signed request and fails on any non-2xx response.
+func (s *S3Store) do(req *http.Request) error {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call s3: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 %s returned status %d: %s", req.Method, resp.StatusCode, body)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// canonicalQuery encodes query parameters sorted by name, as Signature Version 4 requires.
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes every byte except the unreserved characters of RFC 3986, and
+// slashes unless encodeSlash is set.
+func uriEncode(value string, encodeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			sb.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,298 @@
+// Package s3test provides a local stand-in for an S3-compatible server, such as MinIO, for use
+// in tests and local development with the "s3" blob store driver and path-style addressing.
+package s3test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake S3 API that keeps objects in memory. It checks the signatures of requests
+// and presigned URLs like S3 does, but not the region they are signed for.
+type Server struct {
+	*httptest.Server
+
+	accessKeyID     string
+	secretAccessKey string
+
+	mu      sync.Mutex
+	objects map[string]Object // Keyed by "bucket/key"
+}
+
+// Object is a stored object.
+type Object struct {
+	Data        []byte
+	ContentType string
+}
+
+// NewServer starts a new fake S3 API that accepts requests signed with the given access key.
+// The caller must call Close when done.
+func NewServer(accessKeyID, secretAccessKey string) *Server {
+	s := &Server{
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		objects:         make(map[string]Object),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Object returns the object stored under the bucket and key, if any.
+func (s *Server) Object(bucket, key string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[bucket+"/"+key]
+	return obj, ok
+}
+
+// Len returns the number of stored objects.
+func (s *Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.objects)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if !strings.Contains(name, "/") {
+		writeError(w, http.StatusBadRequest, "InvalidRequest", "Only objects are supported.")
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusForbidden, "AccessDenied", "Access Denied")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		s.mu.Lock()
+		s.objects[name] = Object{Data: data, ContentType: r.Header.Get("Content-Type")}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodGet:
+		s.mu.Lock()
+		obj, ok := s.objects[name]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Header().Set("Content-Type", obj.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.Data)))
+		_, _ = w.Write(obj.Data)
+
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, name)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Method not allowed.")
+	}
+}
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	amzDateLayout   = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// maxClockSkew is how far the signing time of a request may be from the server's clock.
+	maxClockSkew = 15 * time.Minute
+)
+
+// authorized checks the Signature Version 4 signature of a request signed in the Authorization
+// header, or of a presigned URL that has not expired. The body of a signed request is read to
+// check its hash, and replaced so that the caller can read it again.
+func (s *Server) authorized(r *http.Request) bool {
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		return s.authorizedByHeader(r, authorization)
+	}
+	return s.authorizedByQuery(r)
+}
+
+func (s *Server) authorizedByHeader(r *http.Request, authorization string) bool {
+	fields, ok := strings.CutPrefix(authorization, sigV4Algorithm+" ")
+	if !ok {
+		return false
+	}
+	params := make(map[string]string)
+	for _, field := range strings.Split(fields, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		params[name] = value
+	}
+
+	signedAt, err := time.Parse(amzDateLayout, r.Header.Get("X-Amz-Date"))
+	if err != nil || time.Since(signedAt).Abs() > maxClockSkew {
+		return false
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != unsignedPayload {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return false
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if sha256Hex(body) != payloadHash {
+			return false
+		}
+	}
+
+	signedHeaders := strings.Split(params["SignedHeaders"], ";")
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		canonicalQuery(r.URL.Query()),
+		canonicalHeaders(r, signedHeaders),
+		params["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	return s.signatureMatches(params["Credential"], signedAt, canonicalRequest, params["Signature"])
+}
+
+func (s *Server) authorizedByQuery(r *http.Request) bool {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") != sigV4Algorithm {
+		return false
+	}
+	signedAt, err := time.Parse(amzDateLayout, query.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || !time.Now().Before(signedAt.Add(time.Duration(expires)*time.Second)) {
+		return false
+	}
+
+	signature := query.Get("X-Amz-Signature")
+	query.Del("X-Amz-Signature")
+	signedHeaders := strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		canonicalQuery(query),
+		canonicalHeaders(r, signedHeaders),
+		query.Get("X-Amz-SignedHeaders"),
+		unsignedPayload,
+	}, "\n")
+	return s.signatureMatches(query.Get("X-Amz-Credential"), signedAt, canonicalRequest, signature)
+}
+
+// signatureMatches checks a signature made with the server's key for the credential scope,
+// which has the form <access key>/<date>/<region>/s3/aws4_request.
+func (s *Server) signatureMatches(
+	credential string,
+	signedAt time.Time,
+	canonicalRequest string,
+	signature string,
+) bool {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[0] != s.accessKeyID || parts[1] != signedAt.Format("20060102") ||
+		parts[3] != "s3" || parts[4] != "aws4_request" {
+		return false
+	}
+	scope := strings.Join(parts[1:], "/")
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		signedAt.Format(amzDateLayout),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), parts[1])
+	key = hmacSHA256(key, parts[2])
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// canonicalHeaders lists the signed headers by their lowercase names, each on its own line.
+func canonicalHeaders(r *http.Request, signedHeaders []string) string {
+	var sb strings.Builder
+	for _, name := range signedHeaders {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		sb.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	return sb.String()
+}
+
+// canonicalQuery encodes query parameters sorted by name and then by value.
+func canonicalQuery(query url.Values) string {
+	var pairs [][2]string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, [2]string{uriEncode(name), uriEncode(value)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+
+	parts := make([]string, len(pairs))
+	for i, pair := range pairs {
+		parts[i] = pair[0] + "=" + pair[1]
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes every byte except the unreserved characters of RFC 3986.
+func uriEncode(value string) string {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{Code: code, Message: message})
+}
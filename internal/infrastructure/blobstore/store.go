@@ -0,0 +1,42 @@
+package blobstore
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/moriverse/45-server/internal/domain/storage"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// Server is a blob store that serves its own files, under MountPath.
+type Server interface {
+	http.Handler
+	MountPath() string
+}
+
+// NewBlobStore creates the storage.BlobStore selected in the configuration.
+func NewBlobStore(cfg config.BlobStoreConfig) (storage.BlobStore, error) {
+	switch cfg.Driver {
+	case "local", "":
+		return NewLocalStore(cfg.Local)
+	case "s3":
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown blob store driver: %q", cfg.Driver)
+	}
+}
+
+// validateKey rejects keys that could escape the store's root directory or bucket prefix.
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key {
+		return storage.ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "." || segment == ".." {
+			return storage.ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+
 	"github.com/spf13/viper"
 )
 
@@ -10,11 +12,29 @@ type Config struct {
 	JWT      JWTConfig
 	Redis    RedisConfig
 	Log      LogConfig
+	SMS      SMSConfig
+	Email    EmailConfig
+	Wechat   WechatConfig
+	Google   OIDCProviderConfig
+	Apple    OIDCProviderConfig
+	Password PasswordConfig
+	MFA      MFAConfig
+	// LoginThrottle limits failed logins; named login_throttle in the config file.
+	LoginThrottle LoginThrottleConfig `mapstructure:"login_throttle"`
+	Guest         GuestConfig
+	OAuth         OAuthConfig
+	GeoIP         GeoIPConfig      `mapstructure:"geoip"`
+	LoginAlert    LoginAlertConfig `mapstructure:"login_alert"`
+	BlobStore     BlobStoreConfig  `mapstructure:"blob_store"`
+	Avatar        AvatarConfig
 }
 
 type ServerConfig struct {
 	Port string
 	Mode string
+	// TrustedProxies lists the addresses or CIDR ranges of the proxies whose X-Forwarded-For
+	// header is believed. Without any, the client IP is the address of the connection.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type DatabaseConfig struct {
@@ -22,8 +42,20 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	SecretKey      string `mapstructure:"secret_key"`
-	ExpiresInHours int    `mapstructure:"expires_in_hours"`
+	SecretKey                   string         `mapstructure:"secret_key"`
+	SigningKeyID                string         `mapstructure:"signing_key_id"`
+	Keys                        []JWTKeyConfig `mapstructure:"keys"`
+	AccessTokenExpiresInMinutes int            `mapstructure:"access_token_expires_in_minutes"`
+	RefreshTokenExpiresInDays   int            `mapstructure:"refresh_token_expires_in_days"`
+	// How long after signing in users may change how they sign in without re-authenticating
+	ReauthMaxAgeMinutes int `mapstructure:"reauth_max_age_minutes"`
+}
+
+type JWTKeyConfig struct {
+	ID             string
+	Algorithm      string // RS256 or EdDSA
+	PrivateKeyFile string `mapstructure:"private_key_file"` // Only needed for the signing key
+	PublicKeyFile  string `mapstructure:"public_key_file"`
 }
 
 type RedisConfig struct {
@@ -37,6 +69,172 @@ type LogConfig struct {
 	Format string
 }
 
+type SMSConfig struct {
+	Driver                string // console, file, http or memory
+	FilePath              string `mapstructure:"file_path"`
+	HTTP                  SMSHTTPConfig
+	ResendIntervalSeconds int               `mapstructure:"resend_interval_seconds"`
+	DailyLimit            int               `mapstructure:"daily_limit"`
+	Templates             map[string]string // Keyed by message type
+}
+
+type SMSHTTPConfig struct {
+	URL            string
+	APIKey         string `mapstructure:"api_key"`
+	Sender         string
+	TimeoutSeconds int `mapstructure:"timeout_seconds"`
+}
+
+type EmailConfig struct {
+	Driver    string // console, file, smtp or memory
+	From      string
+	FilePath  string `mapstructure:"file_path"`
+	SMTP      SMTPConfig
+	Templates map[string]EmailTemplateConfig // Keyed by message type
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+type EmailTemplateConfig struct {
+	Subject string
+	Body    string
+}
+
+type WechatConfig struct {
+	Driver         string          // api or mock
+	BaseURL        string          `mapstructure:"base_url"`
+	TimeoutSeconds int             `mapstructure:"timeout_seconds"`
+	MiniProgram    WechatAppConfig `mapstructure:"mini_program"`
+	MobileApp      WechatAppConfig `mapstructure:"mobile_app"`
+	Website        WechatAppConfig
+}
+
+type WechatAppConfig struct {
+	AppID     string `mapstructure:"app_id"`
+	AppSecret string `mapstructure:"app_secret"`
+}
+
+type OIDCProviderConfig struct {
+	JWKSURL   string   `mapstructure:"jwks_url"`
+	Issuers   []string // Accepted values of the iss claim
+	ClientIDs []string `mapstructure:"client_ids"` // Accepted values of the aud claim
+}
+
+type PasswordConfig struct {
+	MinLength int `mapstructure:"min_length"`
+	Argon2    Argon2Config
+}
+
+type Argon2Config struct {
+	MemoryKiB   uint32 `mapstructure:"memory_kib"`
+	Iterations  uint32
+	Parallelism uint8
+}
+
+type MFAConfig struct {
+	Issuer string // Shown as the account's name in authenticator apps
+}
+
+type LoginThrottleConfig struct {
+	// Rules are keyed by login method: a provider, or mfa for the second step of a login.
+	// The default rule applies to methods without their own.
+	Rules map[string]LoginThrottleRuleConfig
+}
+
+// Validate checks that every rule that counts failures also expires them and locks out for a
+// while, so that a mistake in the config file cannot lock a login out for good.
+func (c LoginThrottleConfig) Validate() error {
+	for method, rule := range c.Rules {
+		if rule.MaxAttempts <= 0 && rule.IPMaxAttempts <= 0 {
+			continue
+		}
+		if rule.WindowMinutes <= 0 || rule.LockoutSeconds <= 0 {
+			return fmt.Errorf(
+				"login_throttle rule %q needs a positive window_minutes and lockout_seconds",
+				method,
+			)
+		}
+		if rule.MaxLockoutMinutes < 0 {
+			return fmt.Errorf("login_throttle rule %q has a negative max_lockout_minutes", method)
+		}
+	}
+	return nil
+}
+
+type LoginThrottleRuleConfig struct {
+	MaxAttempts       int `mapstructure:"max_attempts"`    // Per account and per device
+	IPMaxAttempts     int `mapstructure:"ip_max_attempts"` // Per IP address, shared by many users
+	WindowMinutes     int `mapstructure:"window_minutes"`  // How long failures are counted
+	LockoutSeconds    int `mapstructure:"lockout_seconds"` // Doubled for each further failure
+	MaxLockoutMinutes int `mapstructure:"max_lockout_minutes"`
+}
+
+type GuestConfig struct {
+	MaxInactiveDays      int `mapstructure:"max_inactive_days"` // Before a guest is purged
+	PurgeIntervalMinutes int `mapstructure:"purge_interval_minutes"`
+	// Guests created per IP address and per device within the window. 0 disables a limit.
+	MaxCreationsPerIP     int `mapstructure:"max_creations_per_ip"`
+	MaxCreationsPerDevice int `mapstructure:"max_creations_per_device"`
+	CreationWindowMinutes int `mapstructure:"creation_window_minutes"`
+}
+
+type OAuthConfig struct {
+	AccessTokenExpiresInMinutes int `mapstructure:"access_token_expires_in_minutes"`
+	RefreshTokenExpiresInDays   int `mapstructure:"refresh_token_expires_in_days"`
+}
+
+type GeoIPConfig struct {
+	// FilePath is a CSV of "start,end,country" IP ranges. Leave empty to skip geolocation.
+	FilePath string `mapstructure:"file_path"`
+}
+
+type LoginAlertConfig struct {
+	Driver  string // log or webhook
+	Webhook LoginAlertWebhookConfig
+}
+
+type LoginAlertWebhookConfig struct {
+	URL            string
+	Secret         string // Sent as a bearer token, if set
+	TimeoutSeconds int    `mapstructure:"timeout_seconds"`
+}
+
+type BlobStoreConfig struct {
+	Driver string // local or s3
+	Local  LocalBlobStoreConfig
+	S3     S3BlobStoreConfig `mapstructure:"s3"`
+}
+
+type LocalBlobStoreConfig struct {
+	Root string // Directory the blobs are written to
+	// BaseURL is where this server serves the blobs, under a path such as /blobs.
+	BaseURL    string `mapstructure:"base_url"`
+	SigningKey string `mapstructure:"signing_key"` // Signs download URLs
+}
+
+type S3BlobStoreConfig struct {
+	Endpoint        string // Such as https://s3.us-east-1.amazonaws.com or a MinIO server
+	Region          string
+	Bucket          string
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	// UsePathStyle puts the bucket in the path rather than the host name, as MinIO expects.
+	UsePathStyle   bool `mapstructure:"use_path_style"`
+	TimeoutSeconds int  `mapstructure:"timeout_seconds"`
+}
+
+type AvatarConfig struct {
+	BaseURL             string `mapstructure:"base_url"` // Public URL of this server
+	MaxUploadBytes      int64  `mapstructure:"max_upload_bytes"`
+	Sizes               []int  // Square thumbnail sizes in pixels
+	URLExpiresInMinutes int    `mapstructure:"url_expires_in_minutes"` // Of signed URLs
+}
+
 func LoadConfig(path string) (config Config, err error) {
 	viper.AddConfigPath(path)
 	viper.SetConfigName("config")
@@ -48,6 +246,9 @@ func LoadConfig(path string) (config Config, err error) {
 		return
 	}
 
-	err = viper.Unmarshal(&config)
+	if err = viper.Unmarshal(&config); err != nil {
+		return
+	}
+	err = config.LoginThrottle.Validate()
 	return
 }
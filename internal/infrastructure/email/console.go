@@ -0,0 +1,29 @@
+package email
+
+import (
+	"context"
+	"log/slog"
+)
+
+// ConsoleDriver writes messages to the application log instead of delivering them. It is meant
+// for local development.
+type ConsoleDriver struct {
+	logger *slog.Logger
+}
+
+// NewConsoleDriver creates a new ConsoleDriver.
+func NewConsoleDriver(logger *slog.Logger) *ConsoleDriver {
+	return &ConsoleDriver{logger: logger}
+}
+
+// Deliver logs the message.
+func (d *ConsoleDriver) Deliver(ctx context.Context, msg Message) error {
+	d.logger.Info(
+		"Email delivered to console",
+		"to", msg.To,
+		"type", msg.Type,
+		"subject", msg.Subject,
+		"body", msg.Body,
+	)
+	return nil
+}
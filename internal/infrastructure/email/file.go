@@ -0,0 +1,53 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileDriver appends messages as JSON lines to a local file. It is meant for local development
+// and end-to-end tests that need to read the delivered links.
+type FileDriver struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileDriver creates a new FileDriver that writes to the given path.
+func NewFileDriver(path string) *FileDriver {
+	return &FileDriver{path: path}
+}
+
+// Deliver appends the message to the file.
+func (d *FileDriver) Deliver(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(map[string]interface{}{
+		"from":    msg.From,
+		"to":      msg.To,
+		"type":    msg.Type,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+		"params":  msg.Params,
+		"sent_at": time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(d.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
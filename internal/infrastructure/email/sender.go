@@ -0,0 +1,109 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"text/template"
+
+	"github.com/moriverse/45-server/internal/domain/notification"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// Message is a rendered email that is ready to be handed to a driver.
+type Message struct {
+	From    string
+	To      string
+	Type    notification.EmailType
+	Subject string
+	Body    string
+	Params  map[string]string
+}
+
+// Driver delivers rendered messages through a specific transport.
+type Driver interface {
+	Deliver(ctx context.Context, msg Message) error
+}
+
+type emailTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// Sender is the notification.EmailSender implementation. It renders messages from the
+// configured templates and hands the result to a driver.
+type Sender struct {
+	driver    Driver
+	from      string
+	templates map[notification.EmailType]emailTemplate
+}
+
+// NewSender creates a Sender with the driver selected in the configuration.
+func NewSender(cfg config.EmailConfig, logger *slog.Logger) (*Sender, error) {
+	var driver Driver
+	switch cfg.Driver {
+	case "console", "":
+		driver = NewConsoleDriver(logger)
+	case "file":
+		driver = NewFileDriver(cfg.FilePath)
+	case "smtp":
+		driver = NewSMTPDriver(cfg.SMTP)
+	case "memory":
+		driver = NewMemoryDriver()
+	default:
+		return nil, fmt.Errorf("unknown email driver: %q", cfg.Driver)
+	}
+	return NewSenderWithDriver(driver, cfg)
+}
+
+// NewSenderWithDriver creates a Sender that delivers messages through the given driver.
+func NewSenderWithDriver(driver Driver, cfg config.EmailConfig) (*Sender, error) {
+	templates := make(map[notification.EmailType]emailTemplate, len(cfg.Templates))
+	for name, tc := range cfg.Templates {
+		subject, err := template.New(name).Option("missingkey=error").Parse(tc.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email subject template %q: %w", name, err)
+		}
+		body, err := template.New(name).Option("missingkey=error").Parse(tc.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email body template %q: %w", name, err)
+		}
+		templates[notification.EmailType(name)] = emailTemplate{subject: subject, body: body}
+	}
+
+	return &Sender{driver: driver, from: cfg.From, templates: templates}, nil
+}
+
+// SendEmail renders and delivers a message.
+func (s *Sender) SendEmail(ctx context.Context, email notification.Email) error {
+	tmpl, ok := s.templates[email.Type]
+	if !ok {
+		return fmt.Errorf("no email template configured for type %q", email.Type)
+	}
+	subject, err := render(tmpl.subject, email.Params)
+	if err != nil {
+		return fmt.Errorf("failed to render email subject %q: %w", email.Type, err)
+	}
+	body, err := render(tmpl.body, email.Params)
+	if err != nil {
+		return fmt.Errorf("failed to render email body %q: %w", email.Type, err)
+	}
+
+	return s.driver.Deliver(ctx, Message{
+		From:    s.from,
+		To:      email.To,
+		Type:    email.Type,
+		Subject: subject,
+		Body:    body,
+		Params:  email.Params,
+	})
+}
+
+func render(tmpl *template.Template, params map[string]string) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
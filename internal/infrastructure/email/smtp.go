@@ -0,0 +1,51 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// SMTPDriver delivers messages through an SMTP server. Pointed at a local catcher such as
+// Mailpit, it lets the whole flow be exercised offline.
+type SMTPDriver struct {
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTPDriver creates a new SMTPDriver. Authentication is only used when a username is set.
+func NewSMTPDriver(cfg config.SMTPConfig) *SMTPDriver {
+	d := &SMTPDriver{addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))}
+	if cfg.Username != "" {
+		d.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return d
+}
+
+// Deliver sends the message as a plain text email.
+func (d *SMTPDriver) Deliver(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(msg.Body)
+
+	return smtp.SendMail(d.addr, d.auth, from.Address, []string{msg.To}, buf.Bytes())
+}
@@ -0,0 +1,112 @@
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// ipRange maps a range of addresses, inclusive at both ends, to a country.
+type ipRange struct {
+	start   netip.Addr
+	end     netip.Addr
+	country string
+}
+
+// Database is a loginevent.Locator backed by a local file of IP ranges, so that lookups need
+// no network call. The file is a CSV with one "start,end,country" range per line, such as the
+// free country database of DB-IP, and is loaded into memory at startup.
+type Database struct {
+	ranges []ipRange
+}
+
+// NewDatabase loads the database configured in cfg. Without a file, every address is in an
+// unknown country.
+func NewDatabase(cfg config.GeoIPConfig) (*Database, error) {
+	if cfg.FilePath == "" {
+		return &Database{}, nil
+	}
+
+	f, err := os.Open(cfg.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geoip database: %w", err)
+	}
+	defer f.Close()
+
+	ranges, err := parseRanges(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load geoip database %s: %w", cfg.FilePath, err)
+	}
+	return &Database{ranges: ranges}, nil
+}
+
+func parseRanges(r io.Reader) ([]ipRange, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	var ranges []ipRange
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: expected start,end,country", line)
+		}
+
+		start, startErr := netip.ParseAddr(strings.TrimSpace(record[0]))
+		end, endErr := netip.ParseAddr(strings.TrimSpace(record[1]))
+		if startErr != nil || endErr != nil {
+			// Skip a header line
+			if len(ranges) == 0 {
+				continue
+			}
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: invalid ip address", line)
+		}
+		ranges = append(ranges, ipRange{
+			start:   start.Unmap(),
+			end:     end.Unmap(),
+			country: strings.ToUpper(strings.TrimSpace(record[2])),
+		})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start.Less(ranges[j].start)
+	})
+	return ranges, nil
+}
+
+// Country returns the country code of the range the address is in. Private and malformed
+// addresses are in no country.
+func (d *Database) Country(ipAddress string) string {
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	// Find the last range that starts at or before the address.
+	i := sort.Search(len(d.ranges), func(i int) bool {
+		return addr.Less(d.ranges[i].start)
+	}) - 1
+	if i < 0 || d.ranges[i].end.Less(addr) {
+		return ""
+	}
+	// "ZZ" is used by some databases for unassigned and reserved ranges.
+	if d.ranges[i].country == "ZZ" {
+		return ""
+	}
+	return d.ranges[i].country
+}
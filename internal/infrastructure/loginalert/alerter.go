@@ -0,0 +1,53 @@
+package loginalert
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/moriverse/45-server/internal/domain/notification"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// NewAlerter creates the notification.LoginAlerter selected in the configuration.
+func NewAlerter(
+	cfg config.LoginAlertConfig,
+	logger *slog.Logger,
+) (notification.LoginAlerter, error) {
+	switch cfg.Driver {
+	case "log", "":
+		return NewLogAlerter(logger), nil
+	case "webhook":
+		return NewWebhookAlerter(cfg.Webhook), nil
+	default:
+		return nil, fmt.Errorf("unknown login alert driver: %q", cfg.Driver)
+	}
+}
+
+// LogAlerter writes suspicious logins to the application log, for development or for
+// deployments that alert from their logs.
+type LogAlerter struct {
+	logger *slog.Logger
+}
+
+// NewLogAlerter creates a new LogAlerter.
+func NewLogAlerter(logger *slog.Logger) *LogAlerter {
+	return &LogAlerter{logger: logger}
+}
+
+// AlertSuspiciousLogin logs the login.
+func (a *LogAlerter) AlertSuspiciousLogin(
+	ctx context.Context,
+	login notification.SuspiciousLogin,
+) error {
+	a.logger.Warn(
+		"Suspicious login",
+		"userID", login.UserID,
+		"provider", login.Provider,
+		"ipAddress", login.IPAddress,
+		"country", login.Country,
+		"newDevice", login.NewDevice,
+		"newCountry", login.NewCountry,
+	)
+	return nil
+}
@@ -0,0 +1,89 @@
+package loginalert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/moriverse/45-server/internal/domain/notification"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// WebhookAlerter posts suspicious logins to a URL, such as that of the service that sends
+// push notifications, which decides how to tell the user.
+type WebhookAlerter struct {
+	url        string
+	secret     string
+	httpClient *http.Client
+}
+
+// NewWebhookAlerter creates a new WebhookAlerter.
+func NewWebhookAlerter(cfg config.LoginAlertWebhookConfig) *WebhookAlerter {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	return &WebhookAlerter{
+		url:        cfg.URL,
+		secret:     cfg.Secret,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type webhookRequest struct {
+	Event      string    `json:"event"`
+	UserID     string    `json:"user_id"`
+	Provider   string    `json:"provider"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	Country    string    `json:"country,omitempty"`
+	NewDevice  bool      `json:"new_device"`
+	NewCountry bool      `json:"new_country"`
+	At         time.Time `json:"at"`
+}
+
+// AlertSuspiciousLogin posts the login and fails on any non-2xx response.
+func (a *WebhookAlerter) AlertSuspiciousLogin(
+	ctx context.Context,
+	login notification.SuspiciousLogin,
+) error {
+	body, err := json.Marshal(webhookRequest{
+		Event:      "suspicious_login",
+		UserID:     login.UserID,
+		Provider:   login.Provider,
+		IPAddress:  login.IPAddress,
+		UserAgent:  login.UserAgent,
+		Country:    login.Country,
+		NewDevice:  login.NewDevice,
+		NewCountry: login.NewCountry,
+		At:         login.At,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.secret != "" {
+		req.Header.Set("Authorization", "Bearer "+a.secret)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call login alert webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("login alert webhook returned status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
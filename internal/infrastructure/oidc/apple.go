@@ -0,0 +1,61 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AppleEventType is the type of a Sign in with Apple server-to-server notification.
+type AppleEventType string
+
+const (
+	AppleEmailDisabled  AppleEventType = "email-disabled"  // Private relay forwarding turned off
+	AppleEmailEnabled   AppleEventType = "email-enabled"   // Private relay forwarding turned on
+	AppleConsentRevoked AppleEventType = "consent-revoked" // User stopped using Apple ID with us
+	AppleAccountDelete  AppleEventType = "account-delete"  // User deleted their Apple account
+)
+
+// AppleEvent is the event carried by a Sign in with Apple server-to-server notification.
+type AppleEvent struct {
+	Type           AppleEventType `json:"type"`
+	Subject        string         `json:"sub"`
+	Email          string         `json:"email"`
+	IsPrivateEmail BoolString     `json:"is_private_email"`
+	EventTime      int64          `json:"event_time"`
+}
+
+type appleNotificationClaims struct {
+	jwt.RegisteredClaims
+	// Events is a JSON-encoded AppleEvent. Older notifications send it as a string.
+	Events json.RawMessage `json:"events"`
+}
+
+// VerifyAppleNotification verifies the payload of a Sign in with Apple server-to-server
+// notification and returns its event. The verifier must be configured for Apple.
+func (v *Verifier) VerifyAppleNotification(
+	ctx context.Context,
+	payload string,
+) (*AppleEvent, error) {
+	claims := &appleNotificationClaims{}
+	if err := v.verify(ctx, payload, claims); err != nil {
+		return nil, err
+	}
+
+	events := []byte(claims.Events)
+	var encoded string
+	if err := json.Unmarshal(events, &encoded); err == nil {
+		events = []byte(encoded)
+	}
+
+	var event AppleEvent
+	if err := json.Unmarshal(events, &event); err != nil {
+		return nil, fmt.Errorf("%w: invalid events claim: %v", ErrInvalidToken, err)
+	}
+	if event.Subject == "" {
+		return nil, fmt.Errorf("%w: missing event subject", ErrInvalidToken)
+	}
+	return &event, nil
+}
@@ -0,0 +1,248 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+const (
+	defaultKeysCacheTTL = time.Hour
+	// minRefreshInterval limits how often an unknown kid can trigger a JWKS fetch.
+	minRefreshInterval = time.Minute
+)
+
+var (
+	// ErrInvalidToken is returned for ID tokens that are malformed, expired, not signed by the
+	// provider or not issued for us.
+	ErrInvalidToken = errors.New("invalid id token")
+	// ErrKeysUnavailable is returned when the provider's signing keys cannot be fetched, so that
+	// the token could not be checked at all.
+	ErrKeysUnavailable = errors.New("provider signing keys are unavailable")
+)
+
+// BoolString is a boolean claim that some providers, such as Apple, encode as a string.
+type BoolString bool
+
+func (b *BoolString) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		v, err := strconv.ParseBool(s)
+		*b = BoolString(v)
+		return err
+	}
+	var v bool
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*b = BoolString(v)
+	return nil
+}
+
+// Claims are the ID token claims we use from OpenID Connect providers.
+type Claims struct {
+	jwt.RegisteredClaims
+	Email          string     `json:"email"`
+	EmailVerified  BoolString `json:"email_verified"`
+	IsPrivateEmail BoolString `json:"is_private_email"` // Apple's private relay addresses
+	Name           string     `json:"name"`
+	Picture        string     `json:"picture"`
+	Nonce          string     `json:"nonce"`
+}
+
+// Verifier verifies ID tokens issued by an OpenID Connect provider against the provider's
+// published keys, which are cached and refreshed on rotation. While the provider cannot be
+// reached, the last keys fetched keep being used.
+type Verifier struct {
+	jwksURL    string
+	issuers    []string
+	audiences  []string
+	httpClient *http.Client
+
+	// refreshMu serializes JWKS fetches; mu guards the cached keys and is never held while
+	// fetching.
+	refreshMu   sync.Mutex
+	mu          sync.Mutex
+	keys        map[string]interface{}
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetchErr    error // The error of the last fetch, if it failed
+}
+
+// NewVerifier creates a new Verifier for the provider described by the configuration.
+func NewVerifier(cfg config.OIDCProviderConfig) *Verifier {
+	return &Verifier{
+		jwksURL:    cfg.JWKSURL,
+		issuers:    cfg.Issuers,
+		audiences:  cfg.ClientIDs,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Verify checks the ID token's signature, issuer, audience and expiry, and returns its claims.
+func (v *Verifier) Verify(ctx context.Context, rawToken string) (*Claims, error) {
+	claims := &Claims{}
+	if err := v.verify(ctx, rawToken, claims, jwt.WithExpirationRequired()); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// verify checks the signature, issuer and audience of a token signed by the provider, and
+// decodes it into claims.
+func (v *Verifier) verify(
+	ctx context.Context,
+	rawToken string,
+	claims jwt.Claims,
+	opts ...jwt.ParserOption,
+) error {
+	opts = append(opts, jwt.WithValidMethods([]string{"RS256"}), jwt.WithIssuedAt())
+	_, err := jwt.ParseWithClaims(
+		rawToken,
+		claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return v.key(ctx, kid)
+		},
+		opts...,
+	)
+	if errors.Is(err, ErrKeysUnavailable) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	issuer, _ := claims.GetIssuer()
+	if !contains(v.issuers, issuer) {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, issuer)
+	}
+	audience, _ := claims.GetAudience()
+	if !containsAny(v.audiences, audience) {
+		return fmt.Errorf("%w: unexpected audience %v", ErrInvalidToken, audience)
+	}
+	return nil
+}
+
+// key returns the provider's public key with the given ID, refreshing the cached key set when
+// it is stale or does not contain the key. Refreshes are attempted at most once per
+// minRefreshInterval, and when one fails the cached keys are used for as long as it lasts.
+func (v *Verifier) key(ctx context.Context, kid string) (interface{}, error) {
+	key, ok, refresh := v.cachedKey(kid)
+	if refresh {
+		v.refresh(ctx)
+		key, ok, _ = v.cachedKey(kid)
+	}
+	if ok {
+		return key, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.fetchErr != nil {
+		// The key may well be one we failed to fetch, so the token cannot be judged invalid.
+		return nil, fmt.Errorf("%w: %v", ErrKeysUnavailable, v.fetchErr)
+	}
+	return nil, fmt.Errorf("unknown signing key: %q", kid)
+}
+
+// cachedKey looks up a key in the cached key set, and reports whether the set should be
+// refreshed first.
+func (v *Verifier) cachedKey(kid string) (key interface{}, ok bool, refresh bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, ok = v.keys[kid]
+	stale := time.Since(v.fetchedAt) > defaultKeysCacheTTL
+	return key, ok, (stale || !ok) && time.Since(v.attemptedAt) > minRefreshInterval
+}
+
+// refresh fetches the provider's JWKS and replaces the cached keys. Concurrent callers wait for
+// a single fetch, and a failed fetch leaves the cached keys in place.
+func (v *Verifier) refresh(ctx context.Context) {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	v.mu.Lock()
+	if time.Since(v.attemptedAt) <= minRefreshInterval {
+		// Another caller has just fetched the keys.
+		v.mu.Unlock()
+		return
+	}
+	v.attemptedAt = time.Now()
+	v.mu.Unlock()
+
+	keys, err := v.fetch(ctx)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.fetchErr = err
+	if err == nil {
+		v.keys = keys
+		v.fetchedAt = time.Now()
+	}
+}
+
+// fetch downloads and parses the provider's JWKS.
+func (v *Verifier) fetch(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks endpoint returned status %d", resp.StatusCode)
+	}
+
+	var set utils.JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// Skip keys of types we don't support rather than failing the whole set.
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values []string, candidates []string) bool {
+	for _, c := range candidates {
+		if contains(values, c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,285 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+const (
+	testIssuer   = "https://accounts.example.com"
+	testClientID = "client-1"
+)
+
+// keyServer publishes a JWKS like an OpenID Connect provider, and can be made to fail.
+type keyServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	failing bool
+	fetches int
+}
+
+func newKeyServer(t *testing.T) *keyServer {
+	t.Helper()
+	ks := &keyServer{keys: make(map[string]*rsa.PrivateKey)}
+	ks.Server = httptest.NewServer(http.HandlerFunc(ks.serveJWKS))
+	t.Cleanup(ks.Close)
+	return ks
+}
+
+func (ks *keyServer) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.fetches++
+	if ks.failing {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	set := utils.JWKS{Keys: []utils.JWK{}}
+	for kid, key := range ks.keys {
+		set.Keys = append(set.Keys, utils.JWK{
+			Kty: "RSA",
+			Kid: kid,
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+// addKey publishes a new key and returns its private half.
+func (ks *keyServer) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[kid] = key
+	return key
+}
+
+func (ks *keyServer) setFailing(failing bool) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.failing = failing
+}
+
+func (ks *keyServer) fetchCount() int {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.fetches
+}
+
+func (ks *keyServer) verifier() *Verifier {
+	return NewVerifier(config.OIDCProviderConfig{
+		JWKSURL:   ks.URL,
+		Issuers:   []string{testIssuer},
+		ClientIDs: []string{testClientID},
+	})
+}
+
+// expireCache makes the verifier treat its cached keys as stale and allows it to refresh them.
+func expireCache(v *Verifier) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.fetchedAt = v.fetchedAt.Add(-2 * defaultKeysCacheTTL)
+	v.attemptedAt = v.attemptedAt.Add(-2 * defaultKeysCacheTTL)
+}
+
+func signIDToken(
+	t *testing.T,
+	method jwt.SigningMethod,
+	kid string,
+	key interface{},
+	modify func(*Claims),
+) string {
+	t.Helper()
+	now := time.Now()
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Subject:   "subject-1",
+			Audience:  jwt.ClaimStrings{testClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		Email: "someone@example.com",
+	}
+	if modify != nil {
+		modify(claims)
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerify(t *testing.T) {
+	server := newKeyServer(t)
+	key := server.addKey(t, "key-1")
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := server.verifier()
+	signed := func(kid string, key interface{}) string {
+		return signIDToken(t, jwt.SigningMethodRS256, kid, key, nil)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"valid", signed("key-1", key), true},
+		{
+			"another audience",
+			signIDToken(t, jwt.SigningMethodRS256, "key-1", key, func(c *Claims) {
+				c.Audience = jwt.ClaimStrings{"client-2"}
+			}),
+			false,
+		},
+		{
+			"another issuer",
+			signIDToken(t, jwt.SigningMethodRS256, "key-1", key, func(c *Claims) {
+				c.Issuer = "https://evil.example.com"
+			}),
+			false,
+		},
+		{
+			"expired",
+			signIDToken(t, jwt.SigningMethodRS256, "key-1", key, func(c *Claims) {
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			}),
+			false,
+		},
+		{
+			"no expiry",
+			signIDToken(t, jwt.SigningMethodRS256, "key-1", key, func(c *Claims) {
+				c.ExpiresAt = nil
+			}),
+			false,
+		},
+		{
+			"no subject",
+			signIDToken(t, jwt.SigningMethodRS256, "key-1", key, func(c *Claims) {
+				c.Subject = ""
+			}),
+			false,
+		},
+		{"signed by another key", signed("key-1", other), false},
+		{"unknown kid", signed("key-2", key), false},
+		{"HMAC", signIDToken(t, jwt.SigningMethodHS256, "key-1", []byte("secret"), nil), false},
+		{"malformed", "not.a.token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), tt.token)
+			if tt.valid {
+				if err != nil || claims.Subject != "subject-1" || claims.Email == "" {
+					t.Fatalf("Verify = %+v, %v", claims, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Verify error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	// The unknown kid did not trigger another fetch so soon after the first.
+	if fetches := server.fetchCount(); fetches != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", fetches)
+	}
+}
+
+func TestVerifyKeyRotation(t *testing.T) {
+	server := newKeyServer(t)
+	key := server.addKey(t, "key-1")
+	v := server.verifier()
+	ctx := context.Background()
+
+	token := signIDToken(t, jwt.SigningMethodRS256, "key-1", key, nil)
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// A key published after the last fetch is picked up once refreshing is allowed again.
+	rotated := server.addKey(t, "key-2")
+	v.mu.Lock()
+	v.attemptedAt = v.attemptedAt.Add(-2 * minRefreshInterval)
+	v.mu.Unlock()
+	token = signIDToken(t, jwt.SigningMethodRS256, "key-2", rotated, nil)
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Fatalf("Verify with a rotated key: %v", err)
+	}
+}
+
+func TestVerifyWhenKeysUnavailable(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("never fetched", func(t *testing.T) {
+		server := newKeyServer(t)
+		key := server.addKey(t, "key-1")
+		server.setFailing(true)
+		v := server.verifier()
+
+		token := signIDToken(t, jwt.SigningMethodRS256, "key-1", key, nil)
+		for i := 0; i < 2; i++ {
+			_, err := v.Verify(ctx, token)
+			if !errors.Is(err, ErrKeysUnavailable) || errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Verify error = %v, want only ErrKeysUnavailable", err)
+			}
+		}
+		if fetches := server.fetchCount(); fetches != 1 {
+			t.Errorf("JWKS fetched %d times, want 1", fetches)
+		}
+	})
+
+	t.Run("stale keys", func(t *testing.T) {
+		server := newKeyServer(t)
+		key := server.addKey(t, "key-1")
+		v := server.verifier()
+		token := signIDToken(t, jwt.SigningMethodRS256, "key-1", key, nil)
+		if _, err := v.Verify(ctx, token); err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+
+		server.setFailing(true)
+		expireCache(v)
+		if _, err := v.Verify(ctx, token); err != nil {
+			t.Fatalf("Verify with stale keys: %v", err)
+		}
+		if fetches := server.fetchCount(); fetches != 2 {
+			t.Errorf("JWKS fetched %d times, want 2", fetches)
+		}
+
+		// A kid we have never seen cannot be judged while the provider is down.
+		unknown := signIDToken(t, jwt.SigningMethodRS256, "key-2", key, nil)
+		if _, err := v.Verify(ctx, unknown); !errors.Is(err, ErrKeysUnavailable) {
+			t.Fatalf("Verify with an unknown kid: error = %v, want ErrKeysUnavailable", err)
+		}
+	})
+}
@@ -8,5 +8,8 @@ import (
 )
 
 func NewDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
-	return gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{})
+	return gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{
+		// Translate driver errors into GORM errors such as gorm.ErrDuplicatedKey
+		TranslateError: true,
+	})
 }
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// AccountMerge is the persistence model for the account_merges table.
+type AccountMerge struct {
+	ID           string    `gorm:"primaryKey;type:uuid"`
+	SourceUserID string    `gorm:"column:source_user_id;type:uuid"`
+	TargetUserID string    `gorm:"column:target_user_id;type:uuid"`
+	InitiatedBy  string    `gorm:"column:initiated_by;type:uuid"`
+	Reason       string    `gorm:"column:reason"`
+	CreatedAt    time.Time `gorm:"column:created_at"`
+}
+
+func (AccountMerge) TableName() string {
+	return "account_merges"
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey is the persistence model for the api_keys table.
+type APIKey struct {
+	ID         string     `gorm:"primaryKey;type:uuid"`
+	Name       string     `gorm:"column:name"`
+	Prefix     string     `gorm:"column:prefix;unique"`
+	KeyHash    string     `gorm:"column:key_hash"`
+	CreatedBy  *string    `gorm:"column:created_by;type:uuid"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// APIKeyScope is the persistence model for the api_key_scopes table.
+type APIKeyScope struct {
+	APIKeyID string `gorm:"primaryKey;type:uuid"`
+	Scope    string `gorm:"primaryKey"`
+}
+
+func (APIKeyScope) TableName() string {
+	return "api_key_scopes"
+}
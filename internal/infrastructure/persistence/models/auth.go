@@ -6,13 +6,18 @@ import (
 
 // Auth is the persistence model for the auths table.
 type Auth struct {
-	ID           string    `gorm:"primaryKey;type:uuid"`
-	UserID       string    `gorm:"column:user_id;type:uuid"`
-	Provider     string    `gorm:"column:provider"`
-	ProviderID   string    `gorm:"column:provider_id"`
-	PasswordHash string    `gorm:"column:password_hash"`
-	CreatedAt    time.Time `gorm:"column:created_at"`
-	UpdatedAt    time.Time `gorm:"column:updated_at"`
+	ID              string     `gorm:"primaryKey;type:uuid"`
+	UserID          string     `gorm:"column:user_id;type:uuid"`
+	Provider        string     `gorm:"column:provider"`
+	ProviderID      string     `gorm:"column:provider_id"`
+	UnionID         *string    `gorm:"column:union_id"`
+	Email           *string    `gorm:"column:email"`
+	EmailIsPrivate  bool       `gorm:"column:email_is_private"`
+	EmailVerifiedAt *time.Time `gorm:"column:email_verified_at"`
+	PasswordHash    string     `gorm:"column:password_hash"`
+	DeviceID        *string    `gorm:"column:device_id"`
+	CreatedAt       time.Time  `gorm:"column:created_at"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at"`
 }
 
 func (Auth) TableName() string {
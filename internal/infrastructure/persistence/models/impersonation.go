@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// Impersonation is the persistence model for the impersonations table.
+type Impersonation struct {
+	ID           string    `gorm:"primaryKey;type:uuid"`
+	ActorID      string    `gorm:"column:actor_id;type:uuid"`
+	TargetUserID string    `gorm:"column:target_user_id;type:uuid"`
+	Reason       string    `gorm:"column:reason"`
+	IPAddress    string    `gorm:"column:ip_address"`
+	UserAgent    string    `gorm:"column:user_agent"`
+	ExpiresAt    time.Time `gorm:"column:expires_at"`
+	CreatedAt    time.Time `gorm:"column:created_at"`
+}
+
+func (Impersonation) TableName() string {
+	return "impersonations"
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginEvent is the persistence model for the login_events table.
+type LoginEvent struct {
+	ID            string    `gorm:"primaryKey;type:uuid"`
+	UserID        *string   `gorm:"column:user_id;type:uuid"`
+	Provider      string    `gorm:"column:provider"`
+	Success       bool      `gorm:"column:success"`
+	FailureReason string    `gorm:"column:failure_reason"`
+	IPAddress     string    `gorm:"column:ip_address"`
+	UserAgent     string    `gorm:"column:user_agent"`
+	DeviceID      string    `gorm:"column:device_id"`
+	Source        string    `gorm:"column:source"`
+	Country       string    `gorm:"column:country"`
+	NewDevice     bool      `gorm:"column:new_device"`
+	NewCountry    bool      `gorm:"column:new_country"`
+	CreatedAt     time.Time `gorm:"column:created_at"`
+}
+
+func (LoginEvent) TableName() string {
+	return "login_events"
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// MFATOTP is the persistence model for the mfa_totp table.
+type MFATOTP struct {
+	UserID       string     `gorm:"primaryKey;type:uuid"`
+	Secret       string     `gorm:"column:secret"`
+	ConfirmedAt  *time.Time `gorm:"column:confirmed_at"`
+	LastUsedStep int64      `gorm:"column:last_used_step"`
+	CreatedAt    time.Time  `gorm:"column:created_at"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at"`
+}
+
+func (MFATOTP) TableName() string {
+	return "mfa_totp"
+}
+
+// MFARecoveryCode is the persistence model for the mfa_recovery_codes table.
+type MFARecoveryCode struct {
+	ID        string     `gorm:"primaryKey;type:uuid"`
+	UserID    string     `gorm:"column:user_id;type:uuid"`
+	CodeHash  string     `gorm:"column:code_hash"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+func (MFARecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+)
+
+// OAuthClient is the persistence model for the oauth_clients table.
+type OAuthClient struct {
+	ID         string     `gorm:"primaryKey;type:uuid"`
+	Name       string     `gorm:"column:name"`
+	SecretHash *string    `gorm:"column:secret_hash"`
+	CreatedBy  *string    `gorm:"column:created_by;type:uuid"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// OAuthClientRedirectURI is the persistence model for the oauth_client_redirect_uris table.
+type OAuthClientRedirectURI struct {
+	ClientID    string `gorm:"primaryKey;type:uuid"`
+	RedirectURI string `gorm:"primaryKey"`
+}
+
+func (OAuthClientRedirectURI) TableName() string {
+	return "oauth_client_redirect_uris"
+}
+
+// OAuthClientScope is the persistence model for the oauth_client_scopes table.
+type OAuthClientScope struct {
+	ClientID string `gorm:"primaryKey;type:uuid"`
+	Scope    string `gorm:"primaryKey"`
+}
+
+func (OAuthClientScope) TableName() string {
+	return "oauth_client_scopes"
+}
+
+// OAuthConsent is the persistence model for the oauth_consents table.
+type OAuthConsent struct {
+	UserID    string    `gorm:"primaryKey;type:uuid"`
+	ClientID  string    `gorm:"primaryKey;type:uuid"`
+	Scope     string    `gorm:"column:scope"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func (OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
+
+// OAuthGrant is the persistence model for the oauth_grants table.
+type OAuthGrant struct {
+	ID               string     `gorm:"primaryKey;type:uuid"`
+	ClientID         string     `gorm:"column:client_id;type:uuid"`
+	UserID           string     `gorm:"column:user_id;type:uuid"`
+	Scope            string     `gorm:"column:scope"`
+	RefreshTokenHash string     `gorm:"column:refresh_token_hash;unique"`
+	ExpiresAt        time.Time  `gorm:"column:expires_at"`
+	RevokedAt        *time.Time `gorm:"column:revoked_at"`
+	CreatedAt        time.Time  `gorm:"column:created_at"`
+	UpdatedAt        time.Time  `gorm:"column:updated_at"`
+}
+
+func (OAuthGrant) TableName() string {
+	return "oauth_grants"
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Role is the persistence model for the roles table.
+type Role struct {
+	ID          string    `gorm:"primaryKey;type:uuid"`
+	Name        string    `gorm:"column:name;unique"`
+	Description string    `gorm:"column:description"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
+
+// RolePermission is the persistence model for the role_permissions table.
+type RolePermission struct {
+	RoleID     string `gorm:"primaryKey;type:uuid"`
+	Permission string `gorm:"primaryKey"`
+}
+
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// UserRole is the persistence model for the user_roles table.
+type UserRole struct {
+	UserID    string    `gorm:"primaryKey;type:uuid"`
+	RoleID    string    `gorm:"primaryKey;type:uuid"`
+	GrantedBy *string   `gorm:"column:granted_by;type:uuid"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (UserRole) TableName() string {
+	return "user_roles"
+}
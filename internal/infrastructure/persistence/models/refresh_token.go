@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is the persistence model for the refresh_tokens table.
+type RefreshToken struct {
+	ID        string     `gorm:"primaryKey;type:uuid"`
+	UserID    string     `gorm:"column:user_id;type:uuid"`
+	FamilyID  string     `gorm:"column:family_id;type:uuid"`
+	TokenHash string     `gorm:"column:token_hash;unique"`
+	ExpiresAt time.Time  `gorm:"column:expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is the persistence model for the sessions table.
+type Session struct {
+	ID         string     `gorm:"primaryKey;type:uuid"`
+	UserID     string     `gorm:"column:user_id;type:uuid"`
+	DeviceID   string     `gorm:"column:device_id"`
+	UserAgent  string     `gorm:"column:user_agent"`
+	IPAddress  string     `gorm:"column:ip_address"`
+	AuthTime   time.Time  `gorm:"column:auth_time"`
+	AMR        string     `gorm:"column:amr"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+	LastSeenAt time.Time  `gorm:"column:last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
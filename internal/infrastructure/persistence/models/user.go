@@ -7,9 +7,15 @@ import (
 // User is the persistence model for the users table.
 type User struct {
 	ID           string     `gorm:"primaryKey;type:uuid"`
-	PhoneNumber  string     `gorm:"column:phone_number;unique"`
+	PhoneNumber  *string    `gorm:"column:phone_number;unique"`
+	Nickname     string     `gorm:"column:nickname"`
 	AvatarURL    string     `gorm:"column:avatar_url"`
-	Source       string     `gorm:"type:user_source"`
+	Bio          string     `gorm:"column:bio"`
+	Gender       string     `gorm:"column:gender"`
+	Birthday     *time.Time `gorm:"column:birthday;type:date"`
+	Locale       string     `gorm:"column:locale"`
+	Source       *string    `gorm:"type:user_source"`
+	IsGuest      bool       `gorm:"column:is_guest"`
 	OnboardedAt  *time.Time `gorm:"column:onboarded_at"`
 	CreatedAt    time.Time  `gorm:"column:created_at"`
 	UpdatedAt    time.Time  `gorm:"column:updated_at"`
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// VerificationToken is the persistence model for the verification_tokens table.
+type VerificationToken struct {
+	ID        string     `gorm:"primaryKey;type:uuid"`
+	UserID    string     `gorm:"column:user_id;type:uuid"`
+	AuthID    string     `gorm:"column:auth_id;type:uuid"`
+	Purpose   string     `gorm:"column:purpose"`
+	TokenHash string     `gorm:"column:token_hash;unique"`
+	Email     string     `gorm:"column:email"`
+	ExpiresAt time.Time  `gorm:"column:expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+func (VerificationToken) TableName() string {
+	return "verification_tokens"
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/accountmerge"
+	"github.com/moriverse/45-server/internal/infrastructure/persistence/models"
+)
+
+// AccountMergeRepository is a GORM implementation of the accountmerge.Repository interface.
+type AccountMergeRepository struct {
+	db *gorm.DB
+}
+
+// NewAccountMergeRepository creates a new instance of AccountMergeRepository.
+func NewAccountMergeRepository(db *gorm.DB) *AccountMergeRepository {
+	return &AccountMergeRepository{db: db}
+}
+
+// WithTx returns a new instance of the repository with the database connection set to the
+// given transaction.
+func (r *AccountMergeRepository) WithTx(tx *gorm.DB) accountmerge.Repository {
+	return &AccountMergeRepository{db: tx}
+}
+
+// Create records an account merge in the database.
+func (r *AccountMergeRepository) Create(ctx context.Context, m *accountmerge.AccountMerge) error {
+	model := &models.AccountMerge{
+		ID:           string(m.ID),
+		SourceUserID: string(m.SourceUserID),
+		TargetUserID: string(m.TargetUserID),
+		InitiatedBy:  string(m.InitiatedBy),
+		Reason:       string(m.Reason),
+		CreatedAt:    m.CreatedAt,
+	}
+	return r.db.WithContext(ctx).Create(model).Error
+}
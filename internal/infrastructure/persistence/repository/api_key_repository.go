@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/apikey"
+	"github.com/moriverse/45-server/internal/domain/rbac"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/persistence/models"
+)
+
+// APIKeyRepository is a GORM implementation of the apikey.Repository interface.
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new instance of APIKeyRepository.
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// WithTx returns a new instance of the repository with the database connection set to the
+// given transaction.
+func (r *APIKeyRepository) WithTx(tx *gorm.DB) apikey.Repository {
+	return &APIKeyRepository{db: tx}
+}
+
+// Create creates a new API key and its scopes in the database.
+func (r *APIKeyRepository) Create(ctx context.Context, k *apikey.APIKey) error {
+	model := &models.APIKey{
+		ID:         string(k.ID),
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		KeyHash:    k.KeyHash,
+		CreatedBy:  nullableString(string(k.CreatedBy)),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+	scopes := make([]models.APIKeyScope, 0, len(k.Scopes))
+	for _, scope := range k.Scopes {
+		scopes = append(scopes, models.APIKeyScope{APIKeyID: model.ID, Scope: string(scope)})
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(model).Error; err != nil {
+			return err
+		}
+		if len(scopes) == 0 {
+			return nil
+		}
+		return tx.Create(&scopes).Error
+	})
+}
+
+// FindByID finds an API key by its ID.
+func (r *APIKeyRepository) FindByID(
+	ctx context.Context,
+	id apikey.APIKeyID,
+) (*apikey.APIKey, error) {
+	return r.findOne(ctx, "id = ?", string(id))
+}
+
+// FindByPrefix finds an API key by the public part of the key.
+func (r *APIKeyRepository) FindByPrefix(
+	ctx context.Context,
+	prefix string,
+) (*apikey.APIKey, error) {
+	return r.findOne(ctx, "prefix = ?", prefix)
+}
+
+func (r *APIKeyRepository) findOne(
+	ctx context.Context,
+	query string,
+	args ...interface{},
+) (*apikey.APIKey, error) {
+	var model models.APIKey
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&model).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	keys, err := r.withScopes(ctx, []models.APIKey{model})
+	if err != nil {
+		return nil, err
+	}
+	return keys[0], nil
+}
+
+// List lists every API key, newest first.
+func (r *APIKeyRepository) List(ctx context.Context) ([]*apikey.APIKey, error) {
+	var rows []models.APIKey
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return r.withScopes(ctx, rows)
+}
+
+// Revoke revokes an active key, reporting whether there was one.
+func (r *APIKeyRepository) Revoke(
+	ctx context.Context,
+	id apikey.APIKeyID,
+	t time.Time,
+) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", string(id)).
+		Update("revoked_at", t)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateLastUsedAt records when an API key was last used.
+func (r *APIKeyRepository) UpdateLastUsedAt(
+	ctx context.Context,
+	id apikey.APIKeyID,
+	t time.Time,
+) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ?", string(id)).
+		Update("last_used_at", t).Error
+}
+
+// ReassignCreator records another user as the creator of every key one user created.
+func (r *APIKeyRepository) ReassignCreator(ctx context.Context, from, to user.UserID) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("created_by = ?", string(from)).
+		Update("created_by", string(to)).Error
+}
+
+// withScopes converts API key models to domain API keys, loading their scopes.
+func (r *APIKeyRepository) withScopes(
+	ctx context.Context,
+	rows []models.APIKey,
+) ([]*apikey.APIKey, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	var grants []models.APIKeyScope
+	if err := r.db.WithContext(ctx).
+		Where("api_key_id IN ?", ids).
+		Order("scope ASC").
+		Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	scopes := make(map[string][]rbac.Permission, len(rows))
+	for _, grant := range grants {
+		scopes[grant.APIKeyID] = append(scopes[grant.APIKeyID], rbac.Permission(grant.Scope))
+	}
+
+	keys := make([]*apikey.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, &apikey.APIKey{
+			ID:         apikey.APIKeyID(row.ID),
+			Name:       row.Name,
+			Prefix:     row.Prefix,
+			KeyHash:    row.KeyHash,
+			Scopes:     scopes[row.ID],
+			CreatedBy:  user.UserID(stringValue(row.CreatedBy)),
+			ExpiresAt:  row.ExpiresAt,
+			LastUsedAt: row.LastUsedAt,
+			RevokedAt:  row.RevokedAt,
+			CreatedAt:  row.CreatedAt,
+		})
+	}
+	return keys, nil
+}
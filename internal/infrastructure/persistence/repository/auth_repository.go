@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -29,7 +31,19 @@ func (r *AuthRepository) WithTx(tx *gorm.DB) auth.Repository {
 // Create creates a new auth record in the database.
 func (r *AuthRepository) Create(ctx context.Context, a *auth.Auth) error {
 	model := toAuthModel(a)
-	return r.db.WithContext(ctx).Create(model).Error
+	return translateAuthError(r.db.WithContext(ctx).Create(model).Error)
+}
+
+// FindByID finds an auth record by its ID.
+func (r *AuthRepository) FindByID(ctx context.Context, id auth.AuthID) (*auth.Auth, error) {
+	var model models.Auth
+	if err := r.db.WithContext(ctx).First(&model, "id = ?", string(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toAuthDomain(&model), nil
 }
 
 // FindByProvider finds an auth record by provider and provider user ID.
@@ -53,28 +67,148 @@ func (r *AuthRepository) FindByProvider(
 	return toAuthDomain(&model), nil
 }
 
+// LockUnionID takes a transaction-level advisory lock on the provider and union ID. It must be
+// called within a transaction.
+func (r *AuthRepository) LockUnionID(
+	ctx context.Context,
+	provider auth.Provider,
+	unionID string,
+) error {
+	return r.db.WithContext(ctx).
+		Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", string(provider)+":"+unionID).
+		Error
+}
+
+// FindByUnionID finds the oldest auth record of a provider with the given union ID.
+func (r *AuthRepository) FindByUnionID(
+	ctx context.Context,
+	provider auth.Provider,
+	unionID string,
+) (*auth.Auth, error) {
+	var model models.Auth
+	if err := r.db.WithContext(ctx).
+		Where("provider = ? AND union_id = ?", provider, unionID).
+		Order("created_at ASC").
+		First(&model).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toAuthDomain(&model), nil
+}
+
+// UpdateUnionID sets the union ID of an auth record.
+func (r *AuthRepository) UpdateUnionID(ctx context.Context, id auth.AuthID, unionID string) error {
+	return r.db.WithContext(ctx).Model(&models.Auth{}).
+		Where("id = ?", string(id)).
+		Updates(map[string]interface{}{
+			"union_id":   unionID,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// UpdatePasswordHash replaces the password hash of an auth record.
+func (r *AuthRepository) UpdatePasswordHash(
+	ctx context.Context,
+	id auth.AuthID,
+	passwordHash string,
+) error {
+	return r.db.WithContext(ctx).Model(&models.Auth{}).
+		Where("id = ?", string(id)).
+		Updates(map[string]interface{}{
+			"password_hash": passwordHash,
+			"updated_at":    time.Now(),
+		}).Error
+}
+
+// MarkEmailVerified records that the user proved they control the email address of an auth
+// record.
+func (r *AuthRepository) MarkEmailVerified(ctx context.Context, id auth.AuthID, t time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Auth{}).
+		Where("id = ?", string(id)).
+		Updates(map[string]interface{}{
+			"email_verified_at": t,
+			"updated_at":        t,
+		}).Error
+}
+
+// ListByUserID lists all auth records of a user, oldest first.
+func (r *AuthRepository) ListByUserID(
+	ctx context.Context,
+	userID user.UserID,
+) ([]*auth.Auth, error) {
+	var rows []models.Auth
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", string(userID)).
+		Order("created_at ASC").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	auths := make([]*auth.Auth, 0, len(rows))
+	for i := range rows {
+		auths = append(auths, toAuthDomain(&rows[i]))
+	}
+	return auths, nil
+}
+
+// Delete deletes an auth record from the database.
+func (r *AuthRepository) Delete(ctx context.Context, id auth.AuthID) error {
+	return r.db.WithContext(ctx).Delete(&models.Auth{}, "id = ?", string(id)).Error
+}
+
+// ReassignUser moves every auth record of one user to another.
+func (r *AuthRepository) ReassignUser(ctx context.Context, from, to user.UserID) error {
+	return r.db.WithContext(ctx).Model(&models.Auth{}).
+		Where("user_id = ?", string(from)).
+		Updates(map[string]interface{}{
+			"user_id":    string(to),
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// translateAuthError maps constraint violations on the auths table to domain errors. The
+// provider identity is the only unique key besides the primary key.
+func translateAuthError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return auth.ErrIdentityTaken
+	}
+	return err
+}
+
 // toAuthModel converts a domain auth to a GORM auth model.
 func toAuthModel(a *auth.Auth) *models.Auth {
 	return &models.Auth{
-		ID:           string(a.ID),
-		UserID:       string(a.UserID),
-		Provider:     string(a.Provider),
-		ProviderID:   a.ProviderID,
-		PasswordHash: a.PasswordHash,
-		CreatedAt:    a.CreatedAt,
-		UpdatedAt:    a.UpdatedAt,
+		ID:              string(a.ID),
+		UserID:          string(a.UserID),
+		Provider:        string(a.Provider),
+		ProviderID:      a.ProviderID,
+		UnionID:         nullableString(a.UnionID),
+		Email:           nullableString(a.Email),
+		EmailIsPrivate:  a.EmailIsPrivate,
+		EmailVerifiedAt: a.EmailVerifiedAt,
+		PasswordHash:    a.PasswordHash,
+		DeviceID:        nullableString(a.DeviceID),
+		CreatedAt:       a.CreatedAt,
+		UpdatedAt:       a.UpdatedAt,
 	}
 }
 
 // toAuthDomain converts a GORM auth model to a domain auth.
 func toAuthDomain(m *models.Auth) *auth.Auth {
 	return &auth.Auth{
-		ID:           auth.AuthID(m.ID),
-		UserID:       user.UserID(m.UserID),
-		Provider:     auth.Provider(m.Provider),
-		ProviderID:   m.ProviderID,
-		PasswordHash: m.PasswordHash,
-		CreatedAt:    m.CreatedAt,
-		UpdatedAt:    m.UpdatedAt,
+		ID:              auth.AuthID(m.ID),
+		UserID:          user.UserID(m.UserID),
+		Provider:        auth.Provider(m.Provider),
+		ProviderID:      m.ProviderID,
+		UnionID:         stringValue(m.UnionID),
+		Email:           stringValue(m.Email),
+		EmailIsPrivate:  m.EmailIsPrivate,
+		EmailVerifiedAt: m.EmailVerifiedAt,
+		PasswordHash:    m.PasswordHash,
+		DeviceID:        stringValue(m.DeviceID),
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
 	}
 }
@@ -0,0 +1,17 @@
+package repository
+
+// nullableString maps an empty string to NULL.
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// stringValue maps NULL to an empty string.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
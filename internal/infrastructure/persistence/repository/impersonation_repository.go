@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/impersonation"
+	"github.com/moriverse/45-server/internal/infrastructure/persistence/models"
+)
+
+// ImpersonationRepository is a GORM implementation of the impersonation.Repository interface.
+type ImpersonationRepository struct {
+	db *gorm.DB
+}
+
+// NewImpersonationRepository creates a new instance of ImpersonationRepository.
+func NewImpersonationRepository(db *gorm.DB) *ImpersonationRepository {
+	return &ImpersonationRepository{db: db}
+}
+
+// WithTx returns a new instance of the repository with the database connection set to the
+// given transaction.
+func (r *ImpersonationRepository) WithTx(tx *gorm.DB) impersonation.Repository {
+	return &ImpersonationRepository{db: tx}
+}
+
+// Create records an impersonation in the database.
+func (r *ImpersonationRepository) Create(
+	ctx context.Context,
+	i *impersonation.Impersonation,
+) error {
+	model := &models.Impersonation{
+		ID:           string(i.ID),
+		ActorID:      string(i.ActorID),
+		TargetUserID: string(i.TargetUserID),
+		Reason:       i.Reason,
+		IPAddress:    i.IPAddress,
+		UserAgent:    i.UserAgent,
+		ExpiresAt:    i.ExpiresAt,
+		CreatedAt:    i.CreatedAt,
+	}
+	return r.db.WithContext(ctx).Create(model).Error
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/loginevent"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/persistence/models"
+)
+
+// LoginEventRepository is a GORM implementation of the loginevent.Repository interface.
+type LoginEventRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginEventRepository creates a new instance of LoginEventRepository.
+func NewLoginEventRepository(db *gorm.DB) *LoginEventRepository {
+	return &LoginEventRepository{db: db}
+}
+
+// WithTx returns a new instance of the repository with the database connection set to the
+// given transaction.
+func (r *LoginEventRepository) WithTx(tx *gorm.DB) loginevent.Repository {
+	return &LoginEventRepository{db: tx}
+}
+
+// Create records a login event in the database.
+func (r *LoginEventRepository) Create(ctx context.Context, e *loginevent.LoginEvent) error {
+	model := &models.LoginEvent{
+		ID:            string(e.ID),
+		UserID:        nullableString(string(e.UserID)),
+		Provider:      e.Provider,
+		Success:       e.Success,
+		FailureReason: e.FailureReason,
+		IPAddress:     e.IPAddress,
+		UserAgent:     e.UserAgent,
+		DeviceID:      e.DeviceID,
+		Source:        string(e.Source),
+		Country:       e.Country,
+		NewDevice:     e.NewDevice,
+		NewCountry:    e.NewCountry,
+		CreatedAt:     e.CreatedAt,
+	}
+	return r.db.WithContext(ctx).Create(model).Error
+}
+
+// ListByUserID lists the user's most recent logins, newest first.
+func (r *LoginEventRepository) ListByUserID(
+	ctx context.Context,
+	userID user.UserID,
+	limit int,
+) ([]*loginevent.LoginEvent, error) {
+	var rows []models.LoginEvent
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", string(userID)).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	events := make([]*loginevent.LoginEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, &loginevent.LoginEvent{
+			ID:            loginevent.LoginEventID(row.ID),
+			UserID:        user.UserID(stringValue(row.UserID)),
+			Provider:      row.Provider,
+			Success:       row.Success,
+			FailureReason: row.FailureReason,
+			IPAddress:     row.IPAddress,
+			UserAgent:     row.UserAgent,
+			DeviceID:      row.DeviceID,
+			Source:        user.Source(row.Source),
+			Country:       row.Country,
+			NewDevice:     row.NewDevice,
+			NewCountry:    row.NewCountry,
+			CreatedAt:     row.CreatedAt,
+		})
+	}
+	return events, nil
+}
+
+// HasSucceeded reports whether the user has logged in successfully before.
+func (r *LoginEventRepository) HasSucceeded(
+	ctx context.Context,
+	userID user.UserID,
+) (bool, error) {
+	return r.exists(ctx, "user_id = ? AND success", string(userID))
+}
+
+// HasSucceededFromDevice reports whether the user has logged in successfully from the device
+// before.
+func (r *LoginEventRepository) HasSucceededFromDevice(
+	ctx context.Context,
+	userID user.UserID,
+	deviceID string,
+) (bool, error) {
+	return r.exists(ctx, "user_id = ? AND success AND device_id = ?", string(userID), deviceID)
+}
+
+// HasSucceededFromCountry reports whether the user has logged in successfully from the
+// country before.
+func (r *LoginEventRepository) HasSucceededFromCountry(
+	ctx context.Context,
+	userID user.UserID,
+	country string,
+) (bool, error) {
+	return r.exists(ctx, "user_id = ? AND success AND country = ?", string(userID), country)
+}
+
+// ReassignUser moves every login event of one user to another.
+func (r *LoginEventRepository) ReassignUser(ctx context.Context, from, to user.UserID) error {
+	return r.db.WithContext(ctx).Model(&models.LoginEvent{}).
+		Where("user_id = ?", string(from)).
+		Update("user_id", string(to)).Error
+}
+
+func (r *LoginEventRepository) exists(
+	ctx context.Context,
+	query string,
+	args ...interface{},
+) (bool, error) {
+	var found []string
+	err := r.db.WithContext(ctx).Model(&models.LoginEvent{}).
+		Where(query, args...).
+		Limit(1).
+		Pluck("id", &found).Error
+	return len(found) > 0, err
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/moriverse/45-server/internal/domain/mfa"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/persistence/models"
+)
+
+// MFARepository is a GORM implementation of the mfa.Repository interface.
+type MFARepository struct {
+	db *gorm.DB
+}
+
+// NewMFARepository creates a new instance of MFARepository.
+func NewMFARepository(db *gorm.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// WithTx returns a new instance of the repository with the database connection set to the
+// given transaction.
+func (r *MFARepository) WithTx(tx *gorm.DB) mfa.Repository {
+	return &MFARepository{db: tx}
+}
+
+// FindTOTPByUserID finds a user's TOTP enrollment, locking the row for update.
+func (r *MFARepository) FindTOTPByUserID(
+	ctx context.Context,
+	userID user.UserID,
+) (*mfa.TOTP, error) {
+	var model models.MFATOTP
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&model, "user_id = ?", string(userID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &mfa.TOTP{
+		UserID:       user.UserID(model.UserID),
+		Secret:       model.Secret,
+		ConfirmedAt:  model.ConfirmedAt,
+		LastUsedStep: model.LastUsedStep,
+		CreatedAt:    model.CreatedAt,
+		UpdatedAt:    model.UpdatedAt,
+	}, nil
+}
+
+// SaveTOTP creates or replaces a user's TOTP enrollment.
+func (r *MFARepository) SaveTOTP(ctx context.Context, t *mfa.TOTP) error {
+	model := &models.MFATOTP{
+		UserID:       string(t.UserID),
+		Secret:       t.Secret,
+		ConfirmedAt:  t.ConfirmedAt,
+		LastUsedStep: t.LastUsedStep,
+		CreatedAt:    t.CreatedAt,
+		UpdatedAt:    t.UpdatedAt,
+	}
+	return r.db.WithContext(ctx).Save(model).Error
+}
+
+// DeleteTOTP deletes a user's TOTP enrollment.
+func (r *MFARepository) DeleteTOTP(ctx context.Context, userID user.UserID) error {
+	return r.db.WithContext(ctx).Delete(&models.MFATOTP{}, "user_id = ?", string(userID)).Error
+}
+
+// ReplaceRecoveryCodes deletes a user's recovery codes and stores the given ones.
+func (r *MFARepository) ReplaceRecoveryCodes(
+	ctx context.Context,
+	userID user.UserID,
+	codes []*mfa.RecoveryCode,
+) error {
+	if err := r.db.WithContext(ctx).
+		Delete(&models.MFARecoveryCode{}, "user_id = ?", string(userID)).Error; err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+
+	rows := make([]models.MFARecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		rows = append(rows, models.MFARecoveryCode{
+			ID:        string(code.ID),
+			UserID:    string(code.UserID),
+			CodeHash:  code.CodeHash,
+			UsedAt:    code.UsedAt,
+			CreatedAt: code.CreatedAt,
+		})
+	}
+	return r.db.WithContext(ctx).Create(&rows).Error
+}
+
+// UseRecoveryCode marks an unused recovery code as used, reporting whether there was one.
+func (r *MFARepository) UseRecoveryCode(
+	ctx context.Context,
+	userID user.UserID,
+	codeHash string,
+	t time.Time,
+) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", string(userID), codeHash).
+		Update("used_at", t)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReassignUser moves the TOTP enrollment and recovery codes of one user to another, replacing
+// the other user's.
+func (r *MFARepository) ReassignUser(ctx context.Context, from, to user.UserID) error {
+	if err := r.DeleteTOTP(ctx, to); err != nil {
+		return err
+	}
+	if err := r.ReplaceRecoveryCodes(ctx, to, nil); err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Model(&models.MFATOTP{}).
+		Where("user_id = ?", string(from)).
+		Updates(map[string]interface{}{"user_id": string(to), "updated_at": time.Now()}).
+		Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ?", string(from)).
+		Update("user_id", string(to)).Error
+}
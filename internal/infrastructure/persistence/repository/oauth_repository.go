@@ -0,0 +1,379 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/moriverse/45-server/internal/domain/oauth"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/persistence/models"
+)
+
+// OAuthRepository is a GORM implementation of the oauth.Repository interface.
+type OAuthRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthRepository creates a new instance of OAuthRepository.
+func NewOAuthRepository(db *gorm.DB) *OAuthRepository {
+	return &OAuthRepository{db: db}
+}
+
+// WithTx returns a new instance of the repository with the database connection set to the
+// given transaction.
+func (r *OAuthRepository) WithTx(tx *gorm.DB) oauth.Repository {
+	return &OAuthRepository{db: tx}
+}
+
+// CreateClient creates a new client with its redirect URIs and scopes in the database.
+func (r *OAuthRepository) CreateClient(ctx context.Context, c *oauth.Client) error {
+	model := &models.OAuthClient{
+		ID:         string(c.ID),
+		Name:       c.Name,
+		SecretHash: nullableString(c.SecretHash),
+		CreatedBy:  nullableString(string(c.CreatedBy)),
+		RevokedAt:  c.RevokedAt,
+		CreatedAt:  c.CreatedAt,
+	}
+	uris := make([]models.OAuthClientRedirectURI, 0, len(c.RedirectURIs))
+	for _, uri := range c.RedirectURIs {
+		uris = append(uris, models.OAuthClientRedirectURI{ClientID: model.ID, RedirectURI: uri})
+	}
+	scopes := make([]models.OAuthClientScope, 0, len(c.Scopes))
+	for _, scope := range c.Scopes {
+		scopes = append(scopes, models.OAuthClientScope{ClientID: model.ID, Scope: string(scope)})
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(model).Error; err != nil {
+			return err
+		}
+		if len(uris) > 0 {
+			if err := tx.Create(&uris).Error; err != nil {
+				return err
+			}
+		}
+		if len(scopes) > 0 {
+			if err := tx.Create(&scopes).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FindClientByID finds a client by its ID.
+func (r *OAuthRepository) FindClientByID(
+	ctx context.Context,
+	id oauth.ClientID,
+) (*oauth.Client, error) {
+	var model models.OAuthClient
+	if err := r.db.WithContext(ctx).Where("id = ?", string(id)).First(&model).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	clients, err := r.withDetails(ctx, []models.OAuthClient{model})
+	if err != nil {
+		return nil, err
+	}
+	return clients[0], nil
+}
+
+// ListClients lists every client, newest first.
+func (r *OAuthRepository) ListClients(ctx context.Context) ([]*oauth.Client, error) {
+	var rows []models.OAuthClient
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return r.withDetails(ctx, rows)
+}
+
+// RevokeClient revokes an active client, reporting whether there was one.
+func (r *OAuthRepository) RevokeClient(
+	ctx context.Context,
+	id oauth.ClientID,
+	t time.Time,
+) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.OAuthClient{}).
+		Where("id = ? AND revoked_at IS NULL", string(id)).
+		Update("revoked_at", t)
+	return result.RowsAffected > 0, result.Error
+}
+
+// withDetails converts client models to domain clients, loading their redirect URIs and
+// scopes.
+func (r *OAuthRepository) withDetails(
+	ctx context.Context,
+	rows []models.OAuthClient,
+) ([]*oauth.Client, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	var uriRows []models.OAuthClientRedirectURI
+	if err := r.db.WithContext(ctx).
+		Where("client_id IN ?", ids).
+		Order("redirect_uri ASC").
+		Find(&uriRows).Error; err != nil {
+		return nil, err
+	}
+	var scopeRows []models.OAuthClientScope
+	if err := r.db.WithContext(ctx).
+		Where("client_id IN ?", ids).
+		Order("scope ASC").
+		Find(&scopeRows).Error; err != nil {
+		return nil, err
+	}
+	uris := make(map[string][]string, len(rows))
+	for _, row := range uriRows {
+		uris[row.ClientID] = append(uris[row.ClientID], row.RedirectURI)
+	}
+	scopes := make(map[string][]oauth.Scope, len(rows))
+	for _, row := range scopeRows {
+		scopes[row.ClientID] = append(scopes[row.ClientID], oauth.Scope(row.Scope))
+	}
+
+	clients := make([]*oauth.Client, 0, len(rows))
+	for _, row := range rows {
+		clients = append(clients, &oauth.Client{
+			ID:           oauth.ClientID(row.ID),
+			Name:         row.Name,
+			SecretHash:   stringValue(row.SecretHash),
+			RedirectURIs: uris[row.ID],
+			Scopes:       scopes[row.ID],
+			CreatedBy:    user.UserID(stringValue(row.CreatedBy)),
+			RevokedAt:    row.RevokedAt,
+			CreatedAt:    row.CreatedAt,
+		})
+	}
+	return clients, nil
+}
+
+// FindConsent finds the consent a user gave a client.
+func (r *OAuthRepository) FindConsent(
+	ctx context.Context,
+	userID user.UserID,
+	clientID oauth.ClientID,
+) (*oauth.Consent, error) {
+	var model models.OAuthConsent
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND client_id = ?", string(userID), string(clientID)).
+		First(&model).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toDomainConsent(&model), nil
+}
+
+// SaveConsent creates or replaces the consent a user gave a client.
+func (r *OAuthRepository) SaveConsent(ctx context.Context, c *oauth.Consent) error {
+	model := &models.OAuthConsent{
+		UserID:    string(c.UserID),
+		ClientID:  string(c.ClientID),
+		Scope:     oauth.FormatScopes(c.Scopes),
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at"}),
+	}).Create(model).Error
+}
+
+// ListConsentsByUserID lists the clients a user has allowed, most recently updated first.
+func (r *OAuthRepository) ListConsentsByUserID(
+	ctx context.Context,
+	userID user.UserID,
+) ([]*oauth.Consent, error) {
+	var rows []models.OAuthConsent
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", string(userID)).
+		Order("updated_at DESC").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	consents := make([]*oauth.Consent, 0, len(rows))
+	for i := range rows {
+		consents = append(consents, toDomainConsent(&rows[i]))
+	}
+	return consents, nil
+}
+
+// DeleteConsent deletes the consent a user gave a client, reporting whether there was one.
+func (r *OAuthRepository) DeleteConsent(
+	ctx context.Context,
+	userID user.UserID,
+	clientID oauth.ClientID,
+) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND client_id = ?", string(userID), string(clientID)).
+		Delete(&models.OAuthConsent{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateGrant creates a new grant in the database.
+func (r *OAuthRepository) CreateGrant(ctx context.Context, g *oauth.Grant) error {
+	model := &models.OAuthGrant{
+		ID:               string(g.ID),
+		ClientID:         string(g.ClientID),
+		UserID:           string(g.UserID),
+		Scope:            oauth.FormatScopes(g.Scopes),
+		RefreshTokenHash: g.RefreshTokenHash,
+		ExpiresAt:        g.ExpiresAt,
+		RevokedAt:        g.RevokedAt,
+		CreatedAt:        g.CreatedAt,
+		UpdatedAt:        g.UpdatedAt,
+	}
+	return r.db.WithContext(ctx).Create(model).Error
+}
+
+// FindGrantByID finds a grant by its ID.
+func (r *OAuthRepository) FindGrantByID(
+	ctx context.Context,
+	id oauth.GrantID,
+) (*oauth.Grant, error) {
+	return r.findGrant(ctx, "id = ?", string(id))
+}
+
+// FindGrantByRefreshTokenHash finds a grant by the hash of its current refresh token.
+func (r *OAuthRepository) FindGrantByRefreshTokenHash(
+	ctx context.Context,
+	tokenHash string,
+) (*oauth.Grant, error) {
+	return r.findGrant(ctx, "refresh_token_hash = ?", tokenHash)
+}
+
+func (r *OAuthRepository) findGrant(
+	ctx context.Context,
+	query string,
+	args ...interface{},
+) (*oauth.Grant, error) {
+	var model models.OAuthGrant
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&model).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &oauth.Grant{
+		ID:               oauth.GrantID(model.ID),
+		ClientID:         oauth.ClientID(model.ClientID),
+		UserID:           user.UserID(model.UserID),
+		Scopes:           oauth.ParseScopes(model.Scope),
+		RefreshTokenHash: model.RefreshTokenHash,
+		ExpiresAt:        model.ExpiresAt,
+		RevokedAt:        model.RevokedAt,
+		CreatedAt:        model.CreatedAt,
+		UpdatedAt:        model.UpdatedAt,
+	}, nil
+}
+
+// RotateRefreshToken replaces the refresh token of an active grant, but only if it is still
+// oldHash.
+func (r *OAuthRepository) RotateRefreshToken(
+	ctx context.Context,
+	id oauth.GrantID,
+	oldHash string,
+	newHash string,
+	expiresAt time.Time,
+) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.OAuthGrant{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", string(id), oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": newHash,
+			"expires_at":         expiresAt,
+			"updated_at":         time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RevokeGrant revokes a grant.
+func (r *OAuthRepository) RevokeGrant(ctx context.Context, id oauth.GrantID, t time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OAuthGrant{}).
+		Where("id = ? AND revoked_at IS NULL", string(id)).
+		Updates(map[string]interface{}{"revoked_at": t, "updated_at": t}).Error
+}
+
+// RevokeGrantsByClientID revokes every active grant of a client, returning their IDs.
+func (r *OAuthRepository) RevokeGrantsByClientID(
+	ctx context.Context,
+	clientID oauth.ClientID,
+	t time.Time,
+) ([]oauth.GrantID, error) {
+	return r.revokeGrants(ctx, t, "client_id = ?", string(clientID))
+}
+
+// RevokeGrantsByUserAndClient revokes the active grants a user gave a client, returning their
+// IDs.
+func (r *OAuthRepository) RevokeGrantsByUserAndClient(
+	ctx context.Context,
+	userID user.UserID,
+	clientID oauth.ClientID,
+	t time.Time,
+) ([]oauth.GrantID, error) {
+	return r.revokeGrants(ctx, t, "user_id = ? AND client_id = ?", string(userID), string(clientID))
+}
+
+// revokeGrants revokes the active grants matching the query, returning their IDs.
+func (r *OAuthRepository) revokeGrants(
+	ctx context.Context,
+	t time.Time,
+	query string,
+	args ...interface{},
+) ([]oauth.GrantID, error) {
+	var rows []models.OAuthGrant
+	err := r.db.WithContext(ctx).Model(&rows).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Updates(map[string]interface{}{"revoked_at": t, "updated_at": t}).Error
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]oauth.GrantID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, oauth.GrantID(row.ID))
+	}
+	return ids, nil
+}
+
+// ReassignUser moves every grant and consent of one user to another. Consents to clients the
+// other user has already allowed are dropped.
+func (r *OAuthRepository) ReassignUser(ctx context.Context, from, to user.UserID) error {
+	reassigned := map[string]interface{}{"user_id": string(to), "updated_at": time.Now()}
+	if err := r.db.WithContext(ctx).Model(&models.OAuthGrant{}).
+		Where("user_id = ?", string(from)).
+		Updates(reassigned).Error; err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Model(&models.OAuthConsent{}).
+		Where("user_id = ?", string(from)).
+		Where("client_id NOT IN (?)", r.db.Model(&models.OAuthConsent{}).
+			Select("client_id").
+			Where("user_id = ?", string(to))).
+		Updates(reassigned).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Delete(&models.OAuthConsent{}, "user_id = ?", string(from)).Error
+}
+
+func toDomainConsent(model *models.OAuthConsent) *oauth.Consent {
+	return &oauth.Consent{
+		UserID:    user.UserID(model.UserID),
+		ClientID:  oauth.ClientID(model.ClientID),
+		Scopes:    oauth.ParseScopes(model.Scope),
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/rbac"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/persistence/models"
+)
+
+// RBACRepository is a GORM implementation of the rbac.Repository interface.
+type RBACRepository struct {
+	db *gorm.DB
+}
+
+// NewRBACRepository creates a new instance of RBACRepository.
+func NewRBACRepository(db *gorm.DB) *RBACRepository {
+	return &RBACRepository{db: db}
+}
+
+// WithTx returns a new instance of the repository with the database connection set to the
+// given transaction.
+func (r *RBACRepository) WithTx(tx *gorm.DB) rbac.Repository {
+	return &RBACRepository{db: tx}
+}
+
+// FindRoleByName finds a role and its permissions by the role's name.
+func (r *RBACRepository) FindRoleByName(ctx context.Context, name string) (*rbac.Role, error) {
+	var model models.Role
+	if err := r.db.WithContext(ctx).First(&model, "name = ?", name).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	roles, err := r.withPermissions(ctx, []models.Role{model})
+	if err != nil {
+		return nil, err
+	}
+	return roles[0], nil
+}
+
+// ListRoles lists every role and its permissions, by name.
+func (r *RBACRepository) ListRoles(ctx context.Context) ([]*rbac.Role, error) {
+	var rows []models.Role
+	if err := r.db.WithContext(ctx).Order("name ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return r.withPermissions(ctx, rows)
+}
+
+// ListRolesByUserID lists the roles of a user and their permissions, by name.
+func (r *RBACRepository) ListRolesByUserID(
+	ctx context.Context,
+	userID user.UserID,
+) ([]*rbac.Role, error) {
+	var rows []models.Role
+	if err := r.db.WithContext(ctx).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", string(userID)).
+		Order("roles.name ASC").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return r.withPermissions(ctx, rows)
+}
+
+// ListPermissionsByUserID lists the permissions a user has through any of their roles.
+func (r *RBACRepository) ListPermissionsByUserID(
+	ctx context.Context,
+	userID user.UserID,
+) ([]rbac.Permission, error) {
+	var permissions []rbac.Permission
+	err := r.db.WithContext(ctx).Model(&models.RolePermission{}).
+		Distinct("role_permissions.permission").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", string(userID)).
+		Pluck("role_permissions.permission", &permissions).Error
+	return permissions, err
+}
+
+// AssignRole grants a role to a user.
+func (r *RBACRepository) AssignRole(ctx context.Context, ur *rbac.UserRole) error {
+	model := &models.UserRole{
+		UserID:    string(ur.UserID),
+		RoleID:    string(ur.RoleID),
+		GrantedBy: nullableString(string(ur.GrantedBy)),
+		CreatedAt: ur.CreatedAt,
+	}
+	err := r.db.WithContext(ctx).Create(model).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return rbac.ErrRoleAlreadyAssigned
+	}
+	return err
+}
+
+// RevokeRole takes a role away from a user, reporting whether they had it.
+func (r *RBACRepository) RevokeRole(
+	ctx context.Context,
+	userID user.UserID,
+	roleID rbac.RoleID,
+) (bool, error) {
+	result := r.db.WithContext(ctx).Delete(
+		&models.UserRole{},
+		"user_id = ? AND role_id = ?",
+		string(userID),
+		string(roleID),
+	)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReassignUser moves every role of one user to another, dropping those the other user already
+// has.
+func (r *RBACRepository) ReassignUser(ctx context.Context, from, to user.UserID) error {
+	if err := r.db.WithContext(ctx).Model(&models.UserRole{}).
+		Where("user_id = ?", string(from)).
+		Where("role_id NOT IN (?)", r.db.Model(&models.UserRole{}).
+			Select("role_id").
+			Where("user_id = ?", string(to))).
+		Update("user_id", string(to)).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Delete(&models.UserRole{}, "user_id = ?", string(from)).Error
+}
+
+// withPermissions converts role models to domain roles, loading their permissions.
+func (r *RBACRepository) withPermissions(
+	ctx context.Context,
+	rows []models.Role,
+) ([]*rbac.Role, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	roleIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		roleIDs = append(roleIDs, row.ID)
+	}
+	var grants []models.RolePermission
+	if err := r.db.WithContext(ctx).
+		Where("role_id IN ?", roleIDs).
+		Order("permission ASC").
+		Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	permissions := make(map[string][]rbac.Permission, len(rows))
+	for _, grant := range grants {
+		permissions[grant.RoleID] = append(
+			permissions[grant.RoleID],
+			rbac.Permission(grant.Permission),
+		)
+	}
+
+	roles := make([]*rbac.Role, 0, len(rows))
+	for _, row := range rows {
+		roles = append(roles, &rbac.Role{
+			ID:          rbac.RoleID(row.ID),
+			Name:        row.Name,
+			Description: row.Description,
+			Permissions: permissions[row.ID],
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+	return roles, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/moriverse/45-server/internal/domain/refreshtoken"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/persistence/models"
+)
+
+// RefreshTokenRepository is a GORM implementation of the refreshtoken.Repository interface.
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository creates a new instance of RefreshTokenRepository.
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// WithTx returns a new instance of the repository with the database connection set to the
+// given transaction.
+func (r *RefreshTokenRepository) WithTx(tx *gorm.DB) refreshtoken.Repository {
+	return &RefreshTokenRepository{db: tx}
+}
+
+// Create creates a new refresh token in the database.
+func (r *RefreshTokenRepository) Create(ctx context.Context, t *refreshtoken.RefreshToken) error {
+	model := toRefreshTokenModel(t)
+	return r.db.WithContext(ctx).Create(model).Error
+}
+
+// FindByTokenHash finds a refresh token by its hash, locking the row for update.
+func (r *RefreshTokenRepository) FindByTokenHash(
+	ctx context.Context,
+	tokenHash string,
+) (*refreshtoken.RefreshToken, error) {
+	var model models.RefreshToken
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&model, "token_hash = ?", tokenHash).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toRefreshTokenDomain(&model), nil
+}
+
+// MarkUsed records that a refresh token has been exchanged.
+func (r *RefreshTokenRepository) MarkUsed(
+	ctx context.Context,
+	id refreshtoken.RefreshTokenID,
+	t time.Time,
+) error {
+	return r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ?", string(id)).
+		Update("used_at", t).Error
+}
+
+// RevokeFamily revokes every token in a family that has not been revoked yet.
+func (r *RefreshTokenRepository) RevokeFamily(
+	ctx context.Context,
+	familyID refreshtoken.FamilyID,
+	t time.Time,
+) error {
+	return r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", string(familyID)).
+		Update("revoked_at", t).Error
+}
+
+// toRefreshTokenModel converts a domain refresh token to a GORM refresh token model.
+func toRefreshTokenModel(t *refreshtoken.RefreshToken) *models.RefreshToken {
+	return &models.RefreshToken{
+		ID:        string(t.ID),
+		UserID:    string(t.UserID),
+		FamilyID:  string(t.FamilyID),
+		TokenHash: t.TokenHash,
+		ExpiresAt: t.ExpiresAt,
+		UsedAt:    t.UsedAt,
+		RevokedAt: t.RevokedAt,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+// toRefreshTokenDomain converts a GORM refresh token model to a domain refresh token.
+func toRefreshTokenDomain(m *models.RefreshToken) *refreshtoken.RefreshToken {
+	return &refreshtoken.RefreshToken{
+		ID:        refreshtoken.RefreshTokenID(m.ID),
+		UserID:    user.UserID(m.UserID),
+		FamilyID:  refreshtoken.FamilyID(m.FamilyID),
+		TokenHash: m.TokenHash,
+		ExpiresAt: m.ExpiresAt,
+		UsedAt:    m.UsedAt,
+		RevokedAt: m.RevokedAt,
+		CreatedAt: m.CreatedAt,
+	}
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/moriverse/45-server/internal/domain/session"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/persistence/models"
+)
+
+// SessionRepository is a GORM implementation of the session.Repository interface.
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new instance of SessionRepository.
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// WithTx returns a new instance of the repository with the database connection set to the
+// given transaction.
+func (r *SessionRepository) WithTx(tx *gorm.DB) session.Repository {
+	return &SessionRepository{db: tx}
+}
+
+// Create creates a new session in the database.
+func (r *SessionRepository) Create(ctx context.Context, s *session.Session) error {
+	model := toSessionModel(s)
+	return r.db.WithContext(ctx).Create(model).Error
+}
+
+// FindByID finds a session by its ID.
+func (r *SessionRepository) FindByID(
+	ctx context.Context,
+	id session.SessionID,
+) (*session.Session, error) {
+	var model models.Session
+	if err := r.db.WithContext(ctx).First(&model, "id = ?", string(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toSessionDomain(&model), nil
+}
+
+// ListActiveByUserID lists a user's sessions that are neither revoked nor expired, most
+// recently seen first.
+func (r *SessionRepository) ListActiveByUserID(
+	ctx context.Context,
+	userID user.UserID,
+) ([]*session.Session, error) {
+	var rows []models.Session
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", string(userID), time.Now()).
+		Order("last_seen_at DESC").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*session.Session, 0, len(rows))
+	for i := range rows {
+		sessions = append(sessions, toSessionDomain(&rows[i]))
+	}
+	return sessions, nil
+}
+
+// UpdateLastSeen records activity on a session and extends its expiry.
+func (r *SessionRepository) UpdateLastSeen(
+	ctx context.Context,
+	id session.SessionID,
+	ipAddress string,
+	lastSeenAt time.Time,
+	expiresAt time.Time,
+) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ?", string(id)).
+		Updates(map[string]interface{}{
+			"ip_address":   ipAddress,
+			"last_seen_at": lastSeenAt,
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// UpdateAuthentication records that the user has just proved their identity on a session again.
+func (r *SessionRepository) UpdateAuthentication(
+	ctx context.Context,
+	id session.SessionID,
+	authTime time.Time,
+	amr []string,
+) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", string(id)).
+		Updates(map[string]interface{}{
+			"auth_time": authTime,
+			"amr":       strings.Join(amr, " "),
+		}).Error
+}
+
+// Revoke marks a session as revoked.
+func (r *SessionRepository) Revoke(ctx context.Context, id session.SessionID, t time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", string(id)).
+		Update("revoked_at", t).Error
+}
+
+// toSessionModel converts a domain session to a GORM session model.
+func toSessionModel(s *session.Session) *models.Session {
+	return &models.Session{
+		ID:         string(s.ID),
+		UserID:     string(s.UserID),
+		DeviceID:   s.DeviceID,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		AuthTime:   s.AuthTime,
+		AMR:        strings.Join(s.AMR, " "),
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		RevokedAt:  s.RevokedAt,
+	}
+}
+
+// toSessionDomain converts a GORM session model to a domain session.
+func toSessionDomain(m *models.Session) *session.Session {
+	return &session.Session{
+		ID:         session.SessionID(m.ID),
+		UserID:     user.UserID(m.UserID),
+		DeviceID:   m.DeviceID,
+		UserAgent:  m.UserAgent,
+		IPAddress:  m.IPAddress,
+		AuthTime:   m.AuthTime,
+		AMR:        strings.Fields(m.AMR),
+		CreatedAt:  m.CreatedAt,
+		LastSeenAt: m.LastSeenAt,
+		ExpiresAt:  m.ExpiresAt,
+		RevokedAt:  m.RevokedAt,
+	}
+}
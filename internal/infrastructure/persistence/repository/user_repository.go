@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/moriverse/45-server/internal/domain/user"
 	"github.com/moriverse/45-server/internal/infrastructure/persistence/models"
@@ -29,7 +31,7 @@ func (r *UserRepository) WithTx(tx *gorm.DB) user.Repository {
 // Create creates a new user in the database.
 func (r *UserRepository) Create(ctx context.Context, u *user.User) error {
 	model := toUserModel(u)
-	return r.db.WithContext(ctx).Create(model).Error
+	return translateUserError(r.db.WithContext(ctx).Create(model).Error)
 }
 
 func (r *UserRepository) FindByID(ctx context.Context, id user.UserID) (*user.User, error) {
@@ -63,14 +65,15 @@ func (r *UserRepository) FindByPhoneNumber(
 // Update updates an existing user in the database.
 func (r *UserRepository) Update(ctx context.Context, u *user.User) error {
 	model := toUserModel(u)
-	return r.db.WithContext(ctx).Save(model).Error
+	return translateUserError(r.db.WithContext(ctx).Save(model).Error)
 }
 
-// Delete marks a user as deleted in the database.
+// Delete marks a user as deleted in the database, and releases their phone number so that it
+// can sign up again.
 func (r *UserRepository) Delete(ctx context.Context, id user.UserID) error {
 	return r.db.WithContext(ctx).Model(&models.User{}).
 		Where("id = ?", string(id)).
-		Update("deleted_at", time.Now()).Error
+		Updates(map[string]interface{}{"deleted_at": time.Now(), "phone_number": nil}).Error
 }
 
 // UpdateLastActiveAt updates the last_active_at timestamp for a user.
@@ -80,13 +83,62 @@ func (r *UserRepository) UpdateLastActiveAt(ctx context.Context, id user.UserID,
 		Update("last_active_at", t).Error
 }
 
+// DeleteStaleGuests permanently deletes guest users who have been inactive since the given time.
+// Their identities, sessions and tokens are deleted with them. Guests involved in an account
+// merge or an impersonation are kept, as the audit records refer to them.
+func (r *UserRepository) DeleteStaleGuests(
+	ctx context.Context,
+	inactiveSince time.Time,
+	limit int,
+) ([]*user.User, error) {
+	stale := r.db.WithContext(ctx).Model(&models.User{}).
+		Select("id").
+		Where("is_guest AND COALESCE(last_active_at, created_at) < ?", inactiveSince).
+		Where("NOT EXISTS (?)", r.db.Model(&models.AccountMerge{}).
+			Select("1").
+			Where("users.id IN (source_user_id, target_user_id, initiated_by)")).
+		Where("NOT EXISTS (?)", r.db.Model(&models.Impersonation{}).
+			Select("1").
+			Where("impersonations.target_user_id = users.id")).
+		Limit(limit)
+
+	var deleted []models.User
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("id IN (?)", stale).
+		Delete(&deleted).Error
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*user.User, len(deleted))
+	for i := range deleted {
+		users[i] = toUserDomain(&deleted[i])
+	}
+	return users, nil
+}
+
+// translateUserError maps constraint violations on the users table to domain errors. The phone
+// number is the only unique column besides the primary key.
+func translateUserError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return user.ErrPhoneNumberTaken
+	}
+	return err
+}
+
 // toUserModel converts a domain user to a GORM user model.
 func toUserModel(u *user.User) *models.User {
 	return &models.User{
 		ID:           string(u.ID),
-		PhoneNumber:  u.PhoneNumber,
+		PhoneNumber:  nullableString(u.PhoneNumber),
+		Nickname:     u.Nickname,
 		AvatarURL:    u.AvatarURL,
-		Source:       string(u.Source),
+		Bio:          u.Bio,
+		Gender:       string(u.Gender),
+		Birthday:     u.Birthday,
+		Locale:       u.Locale,
+		Source:       nullableString(string(u.Source)),
+		IsGuest:      u.IsGuest,
 		OnboardedAt:  u.OnboardedAt,
 		CreatedAt:    u.CreatedAt,
 		UpdatedAt:    u.UpdatedAt,
@@ -99,9 +151,15 @@ func toUserModel(u *user.User) *models.User {
 func toUserDomain(m *models.User) *user.User {
 	return &user.User{
 		ID:           user.UserID(m.ID),
-		PhoneNumber:  m.PhoneNumber,
+		PhoneNumber:  stringValue(m.PhoneNumber),
+		Nickname:     m.Nickname,
 		AvatarURL:    m.AvatarURL,
-		Source:       user.Source(m.Source),
+		Bio:          m.Bio,
+		Gender:       user.Gender(m.Gender),
+		Birthday:     m.Birthday,
+		Locale:       m.Locale,
+		Source:       user.Source(stringValue(m.Source)),
+		IsGuest:      m.IsGuest,
 		OnboardedAt:  m.OnboardedAt,
 		CreatedAt:    m.CreatedAt,
 		UpdatedAt:    m.UpdatedAt,
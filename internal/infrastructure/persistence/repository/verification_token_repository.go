@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/domain/verificationtoken"
+	"github.com/moriverse/45-server/internal/infrastructure/persistence/models"
+)
+
+// VerificationTokenRepository is a GORM implementation of the verificationtoken.Repository
+// interface.
+type VerificationTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewVerificationTokenRepository creates a new instance of VerificationTokenRepository.
+func NewVerificationTokenRepository(db *gorm.DB) *VerificationTokenRepository {
+	return &VerificationTokenRepository{db: db}
+}
+
+// WithTx returns a new instance of the repository with the database connection set to the
+// given transaction.
+func (r *VerificationTokenRepository) WithTx(tx *gorm.DB) verificationtoken.Repository {
+	return &VerificationTokenRepository{db: tx}
+}
+
+// Create creates a new verification token in the database.
+func (r *VerificationTokenRepository) Create(
+	ctx context.Context,
+	t *verificationtoken.VerificationToken,
+) error {
+	model := toVerificationTokenModel(t)
+	return r.db.WithContext(ctx).Create(model).Error
+}
+
+// FindByTokenHash finds a verification token by its hash, locking the row for update.
+func (r *VerificationTokenRepository) FindByTokenHash(
+	ctx context.Context,
+	tokenHash string,
+) (*verificationtoken.VerificationToken, error) {
+	var model models.VerificationToken
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&model, "token_hash = ?", tokenHash).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return toVerificationTokenDomain(&model), nil
+}
+
+// MarkUsed records that a verification token has been redeemed.
+func (r *VerificationTokenRepository) MarkUsed(
+	ctx context.Context,
+	id verificationtoken.VerificationTokenID,
+	t time.Time,
+) error {
+	return r.db.WithContext(ctx).Model(&models.VerificationToken{}).
+		Where("id = ?", string(id)).
+		Update("used_at", t).Error
+}
+
+// InvalidateAll marks every unused token of an identity with the given purpose as used.
+func (r *VerificationTokenRepository) InvalidateAll(
+	ctx context.Context,
+	authID auth.AuthID,
+	purpose verificationtoken.Purpose,
+	t time.Time,
+) error {
+	return r.db.WithContext(ctx).Model(&models.VerificationToken{}).
+		Where("auth_id = ? AND purpose = ? AND used_at IS NULL", string(authID), string(purpose)).
+		Update("used_at", t).Error
+}
+
+// ReassignUser moves every verification token of one user to another.
+func (r *VerificationTokenRepository) ReassignUser(
+	ctx context.Context,
+	from user.UserID,
+	to user.UserID,
+) error {
+	return r.db.WithContext(ctx).Model(&models.VerificationToken{}).
+		Where("user_id = ?", string(from)).
+		Update("user_id", string(to)).Error
+}
+
+// toVerificationTokenModel converts a domain verification token to a GORM model.
+func toVerificationTokenModel(t *verificationtoken.VerificationToken) *models.VerificationToken {
+	return &models.VerificationToken{
+		ID:        string(t.ID),
+		UserID:    string(t.UserID),
+		AuthID:    string(t.AuthID),
+		Purpose:   string(t.Purpose),
+		TokenHash: t.TokenHash,
+		Email:     t.Email,
+		ExpiresAt: t.ExpiresAt,
+		UsedAt:    t.UsedAt,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+// toVerificationTokenDomain converts a GORM model to a domain verification token.
+func toVerificationTokenDomain(m *models.VerificationToken) *verificationtoken.VerificationToken {
+	return &verificationtoken.VerificationToken{
+		ID:        verificationtoken.VerificationTokenID(m.ID),
+		UserID:    user.UserID(m.UserID),
+		AuthID:    auth.AuthID(m.AuthID),
+		Purpose:   verificationtoken.Purpose(m.Purpose),
+		TokenHash: m.TokenHash,
+		Email:     m.Email,
+		ExpiresAt: m.ExpiresAt,
+		UsedAt:    m.UsedAt,
+		CreatedAt: m.CreatedAt,
+	}
+}
@@ -5,16 +5,36 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/moriverse/45-server/internal/domain/accountmerge"
+	"github.com/moriverse/45-server/internal/domain/apikey"
 	"github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/impersonation"
+	"github.com/moriverse/45-server/internal/domain/loginevent"
+	"github.com/moriverse/45-server/internal/domain/mfa"
+	"github.com/moriverse/45-server/internal/domain/oauth"
+	"github.com/moriverse/45-server/internal/domain/rbac"
+	"github.com/moriverse/45-server/internal/domain/refreshtoken"
+	"github.com/moriverse/45-server/internal/domain/session"
 	"github.com/moriverse/45-server/internal/domain/unitofwork"
 	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/domain/verificationtoken"
 )
 
 // gormUnitOfWork is the GORM implementation of the UnitOfWork interface.
 type gormUnitOfWork struct {
-	db       *gorm.DB
-	userRepo user.Repository
-	authRepo auth.Repository
+	db                *gorm.DB
+	userRepo          user.Repository
+	authRepo          auth.Repository
+	refreshTokenRepo  refreshtoken.Repository
+	sessionRepo       session.Repository
+	verificationRepo  verificationtoken.Repository
+	mergeRepo         accountmerge.Repository
+	mfaRepo           mfa.Repository
+	impersonationRepo impersonation.Repository
+	rbacRepo          rbac.Repository
+	loginEventRepo    loginevent.Repository
+	oauthRepo         oauth.Repository
+	apiKeyRepo        apikey.Repository
 }
 
 // NewUnitOfWork creates a new GORM UnitOfWork.
@@ -22,11 +42,31 @@ func NewUnitOfWork(
 	db *gorm.DB,
 	userRepo user.Repository,
 	authRepo auth.Repository,
+	refreshTokenRepo refreshtoken.Repository,
+	sessionRepo session.Repository,
+	verificationRepo verificationtoken.Repository,
+	mergeRepo accountmerge.Repository,
+	mfaRepo mfa.Repository,
+	impersonationRepo impersonation.Repository,
+	rbacRepo rbac.Repository,
+	loginEventRepo loginevent.Repository,
+	oauthRepo oauth.Repository,
+	apiKeyRepo apikey.Repository,
 ) unitofwork.UnitOfWork {
 	return &gormUnitOfWork{
-		db:       db,
-		userRepo: userRepo,
-		authRepo: authRepo,
+		db:                db,
+		userRepo:          userRepo,
+		authRepo:          authRepo,
+		refreshTokenRepo:  refreshTokenRepo,
+		sessionRepo:       sessionRepo,
+		verificationRepo:  verificationRepo,
+		mergeRepo:         mergeRepo,
+		mfaRepo:           mfaRepo,
+		impersonationRepo: impersonationRepo,
+		rbacRepo:          rbacRepo,
+		loginEventRepo:    loginEventRepo,
+		oauthRepo:         oauthRepo,
+		apiKeyRepo:        apiKeyRepo,
 	}
 }
 
@@ -37,8 +77,18 @@ func (uow *gormUnitOfWork) Execute(
 ) error {
 	return uow.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		work := &gormUserAuthWork{
-			userRepo: uow.userRepo.WithTx(tx),
-			authRepo: uow.authRepo.WithTx(tx),
+			userRepo:          uow.userRepo.WithTx(tx),
+			authRepo:          uow.authRepo.WithTx(tx),
+			refreshTokenRepo:  uow.refreshTokenRepo.WithTx(tx),
+			sessionRepo:       uow.sessionRepo.WithTx(tx),
+			verificationRepo:  uow.verificationRepo.WithTx(tx),
+			mergeRepo:         uow.mergeRepo.WithTx(tx),
+			mfaRepo:           uow.mfaRepo.WithTx(tx),
+			impersonationRepo: uow.impersonationRepo.WithTx(tx),
+			rbacRepo:          uow.rbacRepo.WithTx(tx),
+			loginEventRepo:    uow.loginEventRepo.WithTx(tx),
+			oauthRepo:         uow.oauthRepo.WithTx(tx),
+			apiKeyRepo:        uow.apiKeyRepo.WithTx(tx),
 		}
 		return fn(work)
 	})
@@ -46,8 +96,18 @@ func (uow *gormUnitOfWork) Execute(
 
 // gormUserAuthWork is the GORM implementation of the UserAuthWork interface.
 type gormUserAuthWork struct {
-	userRepo user.Repository
-	authRepo auth.Repository
+	userRepo          user.Repository
+	authRepo          auth.Repository
+	refreshTokenRepo  refreshtoken.Repository
+	sessionRepo       session.Repository
+	verificationRepo  verificationtoken.Repository
+	mergeRepo         accountmerge.Repository
+	mfaRepo           mfa.Repository
+	impersonationRepo impersonation.Repository
+	rbacRepo          rbac.Repository
+	loginEventRepo    loginevent.Repository
+	oauthRepo         oauth.Repository
+	apiKeyRepo        apikey.Repository
 }
 
 func (w *gormUserAuthWork) Users() user.Repository {
@@ -57,3 +117,43 @@ func (w *gormUserAuthWork) Users() user.Repository {
 func (w *gormUserAuthWork) Auths() auth.Repository {
 	return w.authRepo
 }
+
+func (w *gormUserAuthWork) RefreshTokens() refreshtoken.Repository {
+	return w.refreshTokenRepo
+}
+
+func (w *gormUserAuthWork) Sessions() session.Repository {
+	return w.sessionRepo
+}
+
+func (w *gormUserAuthWork) VerificationTokens() verificationtoken.Repository {
+	return w.verificationRepo
+}
+
+func (w *gormUserAuthWork) AccountMerges() accountmerge.Repository {
+	return w.mergeRepo
+}
+
+func (w *gormUserAuthWork) MFA() mfa.Repository {
+	return w.mfaRepo
+}
+
+func (w *gormUserAuthWork) Impersonations() impersonation.Repository {
+	return w.impersonationRepo
+}
+
+func (w *gormUserAuthWork) Roles() rbac.Repository {
+	return w.rbacRepo
+}
+
+func (w *gormUserAuthWork) LoginEvents() loginevent.Repository {
+	return w.loginEventRepo
+}
+
+func (w *gormUserAuthWork) OAuth() oauth.Repository {
+	return w.oauthRepo
+}
+
+func (w *gormUserAuthWork) APIKeys() apikey.Repository {
+	return w.apiKeyRepo
+}
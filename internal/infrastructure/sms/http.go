@@ -0,0 +1,78 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// HTTPDriver delivers messages by posting them to an SMS vendor's HTTP API.
+type HTTPDriver struct {
+	url        string
+	apiKey     string
+	sender     string
+	httpClient *http.Client
+}
+
+// NewHTTPDriver creates a new HTTPDriver.
+func NewHTTPDriver(cfg config.SMSHTTPConfig) *HTTPDriver {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	return &HTTPDriver{
+		url:        cfg.URL,
+		apiKey:     cfg.APIKey,
+		sender:     cfg.Sender,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type httpDeliverRequest struct {
+	To       string            `json:"to"`
+	From     string            `json:"from,omitempty"`
+	Template string            `json:"template"`
+	Content  string            `json:"content"`
+	Params   map[string]string `json:"params,omitempty"`
+}
+
+// Deliver posts the message to the vendor and fails on any non-2xx response.
+func (d *HTTPDriver) Deliver(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(httpDeliverRequest{
+		To:       msg.PhoneNumber,
+		From:     d.sender,
+		Template: string(msg.Type),
+		Content:  msg.Content,
+		Params:   msg.Params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+d.apiKey)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call sms gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("sms gateway returned status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package sms
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryDriver records messages in memory so tests can inspect what would have been sent.
+type MemoryDriver struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryDriver creates a new MemoryDriver.
+func NewMemoryDriver() *MemoryDriver {
+	return &MemoryDriver{}
+}
+
+// Deliver records the message.
+func (d *MemoryDriver) Deliver(ctx context.Context, msg Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.messages = append(d.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of all recorded messages, oldest first.
+func (d *MemoryDriver) Messages() []Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Message(nil), d.messages...)
+}
+
+// Last returns the most recent message sent to the phone number.
+func (d *MemoryDriver) Last(phoneNumber string) (Message, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := len(d.messages) - 1; i >= 0; i-- {
+		if d.messages[i].PhoneNumber == phoneNumber {
+			return d.messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// Reset discards all recorded messages.
+func (d *MemoryDriver) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.messages = nil
+}
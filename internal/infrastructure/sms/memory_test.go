@@ -0,0 +1,130 @@
+package sms
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/moriverse/45-server/internal/domain/notification"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+var testSMSConfig = config.SMSConfig{
+	Templates: map[string]string{
+		string(notification.SMSVerificationCode): "Your code is {{.code}}.",
+	},
+}
+
+func newMemorySender(t *testing.T) (*Sender, *MemoryDriver) {
+	t.Helper()
+	driver := NewMemoryDriver()
+	sender, err := NewSenderWithDriver(driver, testSMSConfig, nil)
+	if err != nil {
+		t.Fatalf("NewSenderWithDriver: %v", err)
+	}
+	return sender, driver
+}
+
+func TestMemoryDriverRecordsRenderedMessages(t *testing.T) {
+	sender, driver := newMemorySender(t)
+	ctx := context.Background()
+
+	for _, sms := range []notification.SMS{
+		{PhoneNumber: "+8613800138000", Type: notification.SMSVerificationCode,
+			Params: map[string]string{"code": "111111"}},
+		{PhoneNumber: "+14155550123", Type: notification.SMSVerificationCode,
+			Params: map[string]string{"code": "222222"}},
+		{PhoneNumber: "+8613800138000", Type: notification.SMSVerificationCode,
+			Params: map[string]string{"code": "333333"}},
+	} {
+		if err := sender.SendSMS(ctx, sms); err != nil {
+			t.Fatalf("SendSMS: %v", err)
+		}
+	}
+
+	tests := []struct {
+		phoneNumber string
+		want        string
+		found       bool
+	}{
+		{"+8613800138000", "Your code is 333333.", true},
+		{"+14155550123", "Your code is 222222.", true},
+		{"+8613900139000", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			msg, ok := driver.Last(tt.phoneNumber)
+			if ok != tt.found || msg.Content != tt.want {
+				t.Fatalf("Last = %+v, %v; want %q, %v", msg, ok, tt.want, tt.found)
+			}
+			if ok && (msg.Type != notification.SMSVerificationCode || msg.Params["code"] == "") {
+				t.Fatalf("Last = %+v, want the type and params of the request", msg)
+			}
+		})
+	}
+
+	messages := driver.Messages()
+	if len(messages) != 3 || messages[0].Content != "Your code is 111111." {
+		t.Fatalf("Messages = %+v, want all three, oldest first", messages)
+	}
+	messages[0].Content = "changed"
+	if driver.Messages()[0].Content == "changed" {
+		t.Fatal("Messages returned the recorded messages rather than a copy")
+	}
+
+	driver.Reset()
+	if messages := driver.Messages(); len(messages) != 0 {
+		t.Fatalf("Messages after Reset = %+v", messages)
+	}
+}
+
+func TestSendSMSRenderErrors(t *testing.T) {
+	sender, driver := newMemorySender(t)
+	tests := []struct {
+		name string
+		sms  notification.SMS
+	}{
+		{"no template", notification.SMS{PhoneNumber: "+8613800138000", Type: "promotion"}},
+		{
+			"missing parameter",
+			notification.SMS{PhoneNumber: "+8613800138000", Type: notification.SMSVerificationCode},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := sender.SendSMS(context.Background(), tt.sms); err == nil {
+				t.Fatal("SendSMS succeeded")
+			}
+			if messages := driver.Messages(); len(messages) != 0 {
+				t.Fatalf("a message was delivered: %+v", messages)
+			}
+		})
+	}
+}
+
+func TestNewSender(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name    string
+		cfg     config.SMSConfig
+		wantErr bool
+	}{
+		{"memory driver", config.SMSConfig{Driver: "memory"}, false},
+		{"console by default", config.SMSConfig{}, false},
+		{"unknown driver", config.SMSConfig{Driver: "pigeon"}, true},
+		{
+			"invalid template",
+			config.SMSConfig{Driver: "memory", Templates: map[string]string{"code": "{{.code"}},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSender(tt.cfg, nil, logger)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSender error = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"text/template"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/moriverse/45-server/internal/domain/notification"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// Message is a rendered text message that is ready to be handed to a driver.
+type Message struct {
+	PhoneNumber string
+	Type        notification.SMSType
+	Content     string
+	Params      map[string]string
+}
+
+// Driver delivers rendered messages through a specific gateway.
+type Driver interface {
+	Deliver(ctx context.Context, msg Message) error
+}
+
+// Sender is the notification.SMSSender implementation. It renders messages from the configured
+// templates, applies per-phone throttling and hands the result to a driver.
+type Sender struct {
+	driver    Driver
+	templates map[notification.SMSType]*template.Template
+	throttle  *throttle
+}
+
+// NewSender creates a Sender with the driver selected in the configuration.
+func NewSender(
+	cfg config.SMSConfig,
+	redisClient *redis.Client,
+	logger *slog.Logger,
+) (*Sender, error) {
+	var driver Driver
+	switch cfg.Driver {
+	case "console", "":
+		driver = NewConsoleDriver(logger)
+	case "file":
+		driver = NewFileDriver(cfg.FilePath)
+	case "http":
+		driver = NewHTTPDriver(cfg.HTTP)
+	case "memory":
+		driver = NewMemoryDriver()
+	default:
+		return nil, fmt.Errorf("unknown sms driver: %q", cfg.Driver)
+	}
+	return NewSenderWithDriver(driver, cfg, redisClient)
+}
+
+// NewSenderWithDriver creates a Sender that delivers messages through the given driver. Throttling
+// is disabled when redisClient is nil.
+func NewSenderWithDriver(
+	driver Driver,
+	cfg config.SMSConfig,
+	redisClient *redis.Client,
+) (*Sender, error) {
+	templates := make(map[notification.SMSType]*template.Template, len(cfg.Templates))
+	for name, text := range cfg.Templates {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sms template %q: %w", name, err)
+		}
+		templates[notification.SMSType(name)] = tmpl
+	}
+
+	var t *throttle
+	if redisClient != nil {
+		t = newThrottle(redisClient, cfg)
+	}
+
+	return &Sender{driver: driver, templates: templates, throttle: t}, nil
+}
+
+// SendSMS renders and delivers a message, unless the phone number has been throttled. A failed
+// delivery does not count towards the throttle.
+func (s *Sender) SendSMS(ctx context.Context, sms notification.SMS) error {
+	content, err := s.render(sms)
+	if err != nil {
+		return err
+	}
+
+	if s.throttle != nil {
+		if err := s.throttle.allow(ctx, sms.PhoneNumber); err != nil {
+			return err
+		}
+	}
+
+	err = s.driver.Deliver(ctx, Message{
+		PhoneNumber: sms.PhoneNumber,
+		Type:        sms.Type,
+		Content:     content,
+		Params:      sms.Params,
+	})
+	if err != nil && s.throttle != nil {
+		// The request may already be cancelled when the delivery fails.
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if releaseErr := s.throttle.release(releaseCtx, sms.PhoneNumber); releaseErr != nil {
+			return errors.Join(err, releaseErr)
+		}
+	}
+	return err
+}
+
+func (s *Sender) render(sms notification.SMS) (string, error) {
+	tmpl, ok := s.templates[sms.Type]
+	if !ok {
+		return "", fmt.Errorf("no sms template configured for type %q", sms.Type)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, sms.Params); err != nil {
+		return "", fmt.Errorf("failed to render sms template %q: %w", sms.Type, err)
+	}
+	return buf.String(), nil
+}
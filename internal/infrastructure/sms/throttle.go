@@ -0,0 +1,94 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/moriverse/45-server/internal/domain/notification"
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+const (
+	resendCacheKeyPrefix = "sms-resend"
+	dailyCacheKeyPrefix  = "sms-daily"
+)
+
+// throttle limits how often messages can be sent to a single phone number.
+type throttle struct {
+	redisClient    *redis.Client
+	resendInterval time.Duration
+	dailyLimit     int64
+}
+
+func newThrottle(redisClient *redis.Client, cfg config.SMSConfig) *throttle {
+	return &throttle{
+		redisClient:    redisClient,
+		resendInterval: time.Duration(cfg.ResendIntervalSeconds) * time.Second,
+		dailyLimit:     int64(cfg.DailyLimit),
+	}
+}
+
+func resendKey(phoneNumber string) string {
+	return fmt.Sprintf("%s:%s", resendCacheKeyPrefix, phoneNumber)
+}
+
+func dailyKey(phoneNumber string) string {
+	return fmt.Sprintf(
+		"%s:%s:%s",
+		dailyCacheKeyPrefix,
+		phoneNumber,
+		time.Now().UTC().Format("20060102"),
+	)
+}
+
+// allow records a send to the phone number, or returns notification.ErrSMSThrottled if the
+// phone number is within its resend interval or has reached its daily limit.
+func (t *throttle) allow(ctx context.Context, phoneNumber string) error {
+	if t.resendInterval > 0 {
+		wasSet, err := t.redisClient.SetNX(
+			ctx,
+			resendKey(phoneNumber),
+			"sent",
+			t.resendInterval,
+		).Result()
+		if err != nil {
+			return err
+		}
+		if !wasSet {
+			return notification.ErrSMSThrottled
+		}
+	}
+
+	if t.dailyLimit > 0 {
+		key := dailyKey(phoneNumber)
+		count, err := t.redisClient.Incr(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if count == 1 {
+			t.redisClient.Expire(ctx, key, 24*time.Hour)
+		}
+		if count > t.dailyLimit {
+			return notification.ErrSMSThrottled
+		}
+	}
+
+	return nil
+}
+
+// release takes back a send recorded by allow that was never delivered, so that a failure at
+// the provider does not lock the phone number out until the resend interval passes.
+func (t *throttle) release(ctx context.Context, phoneNumber string) error {
+	pipe := t.redisClient.TxPipeline()
+	if t.resendInterval > 0 {
+		pipe.Del(ctx, resendKey(phoneNumber))
+	}
+	if t.dailyLimit > 0 {
+		pipe.Decr(ctx, dailyKey(phoneNumber))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	apiKeyService "github.com/moriverse/45-server/internal/app/apikey"
+	"github.com/moriverse/45-server/internal/domain/apikey"
+	"github.com/moriverse/45-server/internal/domain/rbac"
+	"github.com/moriverse/45-server/internal/infrastructure/web/middleware"
+	"github.com/moriverse/45-server/internal/infrastructure/web/response"
+)
+
+// APIKeyHandler handles admin HTTP requests for managing API keys.
+type APIKeyHandler struct {
+	apiKeyService *apiKeyService.Service
+}
+
+// NewAPIKeyHandler creates a new instance of APIKeyHandler.
+func NewAPIKeyHandler(apiKeyService *apiKeyService.Service) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+// APIKeyResponse is the public representation of an API key. The key itself is never shown
+// after it is created.
+type APIKeyResponse struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Prefix     string            `json:"prefix"`
+	Scopes     []rbac.Permission `json:"scopes"`
+	CreatedBy  string            `json:"created_by,omitempty"`
+	ExpiresAt  *time.Time        `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time        `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time        `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+func toAPIKeyResponse(k *apikey.APIKey) APIKeyResponse {
+	scopes := k.Scopes
+	if scopes == nil {
+		scopes = []rbac.Permission{}
+	}
+	return APIKeyResponse{
+		ID:         string(k.ID),
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     scopes,
+		CreatedBy:  string(k.CreatedBy),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+// List handles the HTTP request for listing every API key.
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.apiKeyService.List(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	items := make([]APIKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, toAPIKeyResponse(k))
+	}
+	response.Data(c, http.StatusOK, gin.H{"api_keys": items})
+}
+
+// CreateAPIKeyRequest defines the request body for issuing an API key.
+type CreateAPIKeyRequest struct {
+	Name      string            `json:"name" binding:"required"`
+	Scopes    []rbac.Permission `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time        `json:"expires_at"` // Omit for a key that never expires
+}
+
+// Create handles the HTTP request for issuing an API key. The response is the only time the
+// key is shown.
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.apiKeyService.Create(c.Request.Context(), apiKeyService.CreateParams{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: currentUserID(c),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Data(c, http.StatusCreated, gin.H{
+		"api_key": toAPIKeyResponse(result.APIKey),
+		"key":     result.Key,
+	})
+}
+
+// Revoke handles the HTTP request for revoking an API key.
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id := apikey.APIKeyID(c.Param("id"))
+	if err := h.apiKeyService.Revoke(c.Request.Context(), id, currentUserID(c)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *APIKeyHandler) handleError(c *gin.Context, err error) {
+	logger, _ := c.Get(middleware.LoggerKey)
+	requestLogger, ok := logger.(*slog.Logger)
+	if !ok {
+		requestLogger = slog.Default()
+	}
+
+	switch err {
+	case apiKeyService.ErrAPIKeyNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "API_KEY_NOT_FOUND",
+			Message: "The API key does not exist or is already revoked.",
+		})
+	case apiKeyService.ErrNameRequired:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "NAME_REQUIRED",
+			Message: "Name the service or partner the key is for.",
+		})
+	case apiKeyService.ErrScopesRequired:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "SCOPES_REQUIRED",
+			Message: "An API key needs at least one scope.",
+		})
+	case apiKeyService.ErrInvalidScope:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_SCOPE",
+			Message: "One of the scopes is not supported.",
+		})
+	case apiKeyService.ErrScopeNotGranted:
+		response.Error(c, http.StatusForbidden, response.APIError{
+			Code:    "SCOPE_NOT_GRANTED",
+			Message: "You can only give an API key permissions you have yourself.",
+		})
+	case apiKeyService.ErrExpiryInThePast:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_EXPIRY",
+			Message: "The expiry must be in the future.",
+		})
+	default:
+		requestLogger.Error("Unhandled API error", "error", err)
+		response.Error(c, http.StatusInternalServerError, response.APIError{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: "An unexpected error occurred on our end.",
+		})
+	}
+}
@@ -1,12 +1,16 @@
 package handler
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	authService "github.com/moriverse/45-server/internal/app/auth"
 	authDomain "github.com/moriverse/45-server/internal/domain/auth"
+	"github.com/moriverse/45-server/internal/domain/user"
 	"github.com/moriverse/45-server/internal/infrastructure/web/middleware"
 	"github.com/moriverse/45-server/internal/infrastructure/web/response"
 )
@@ -25,6 +29,9 @@ func NewAuthHandler(authService *authService.Service) *AuthHandler {
 type LoginRequest struct {
 	Provider    string                 `json:"provider" binding:"required"`
 	Credentials map[string]interface{} `json:"credentials" binding:"required"`
+	// Source is the client the user signs in from. Clients released before it was added leave
+	// it out.
+	Source string `json:"source"`
 }
 
 // Login handles the HTTP request for user login or seamless registration.
@@ -38,34 +45,621 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	source := user.Source(req.Source)
+	if source != "" && !source.IsValid() {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_SOURCE",
+			Message: "The specified source is not supported.",
+		})
+		return
+	}
+
 	provider := authDomain.Provider(req.Provider)
 
-	var result *authService.RegisterResult // Login and Register return the same result
-	var err error
+	var result *authService.RegisterResult // Login and Register return the same result
+	var err error
+
+	switch provider {
+	case authDomain.Wechat:
+		code, ok := stringCredential(c, req.Credentials, "code", "Code")
+		if !ok {
+			return
+		}
+		params := authService.LoginOrRegisterWithWechatParams{
+			Code:   code,
+			Source: source,
+			Client: clientInfo(c),
+		}
+		result, err = h.authService.LoginOrRegisterWithWechat(c.Request.Context(), params)
+
+	case authDomain.Phone:
+		phoneNumber, ok := stringCredential(c, req.Credentials, "phone_number", "Phone number")
+		if !ok {
+			return
+		}
+		code, ok := stringCredential(c, req.Credentials, "code", "Code")
+		if !ok {
+			return
+		}
+		params := authService.LoginOrRegisterWithPhoneParams{
+			PhoneNumber: phoneNumber,
+			Code:        code,
+			Source:      source,
+			Client:      clientInfo(c),
+		}
+		result, err = h.authService.LoginOrRegisterWithPhone(c.Request.Context(), params)
+
+	case authDomain.Google:
+		idToken, ok := stringCredential(c, req.Credentials, "id_token", "ID token")
+		if !ok {
+			return
+		}
+		params := authService.LoginOrRegisterWithGoogleParams{
+			IDToken: idToken,
+			Source:  source,
+			Client:  clientInfo(c),
+		}
+		result, err = h.authService.LoginOrRegisterWithGoogle(c.Request.Context(), params)
+
+	case authDomain.Apple:
+		idToken, ok := stringCredential(c, req.Credentials, "id_token", "ID token")
+		if !ok {
+			return
+		}
+		nonce, ok := stringCredential(c, req.Credentials, "nonce", "Nonce")
+		if !ok {
+			return
+		}
+		// The name is optional; Apple only provides it on the first sign-in.
+		givenName, _ := req.Credentials["given_name"].(string)
+		familyName, _ := req.Credentials["family_name"].(string)
+		params := authService.LoginOrRegisterWithAppleParams{
+			IDToken:    idToken,
+			Nonce:      nonce,
+			GivenName:  givenName,
+			FamilyName: familyName,
+			Source:     source,
+			Client:     clientInfo(c),
+		}
+		result, err = h.authService.LoginOrRegisterWithApple(c.Request.Context(), params)
+
+	case authDomain.Password:
+		identifier, ok := stringCredential(c, req.Credentials, "identifier", "Identifier")
+		if !ok {
+			return
+		}
+		password, ok := stringCredential(c, req.Credentials, "password", "Password")
+		if !ok {
+			return
+		}
+		params := authService.LoginWithPasswordParams{
+			Identifier: identifier,
+			Password:   password,
+			Client:     clientInfo(c),
+		}
+		result, err = h.authService.LoginWithPassword(c.Request.Context(), params)
+
+	case authDomain.Guest:
+		// Without a guest token, a new guest is created and their token returned.
+		guestToken, _ := req.Credentials["guest_token"].(string)
+		params := authService.LoginAsGuestParams{
+			GuestToken: guestToken,
+			Source:     source,
+			Client:     clientInfo(c),
+		}
+		result, err = h.authService.LoginAsGuest(c.Request.Context(), params)
+
+	default:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_PROVIDER",
+			Message: "The specified provider is not supported.",
+		})
+		return
+	}
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	loginResponse(c, http.StatusOK, result)
+}
+
+// loginResponse writes the tokens of a completed login, or the MFA challenge when the user must
+// still enter a second factor.
+func loginResponse(c *gin.Context, status int, result *authService.RegisterResult) {
+	if result.MFA != nil {
+		response.Data(c, http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    result.MFA.Token,
+			"expires_in":   int(result.MFA.ExpiresIn.Seconds()),
+		})
+		return
+	}
+
+	body := gin.H{
+		"user":          toUserResponse(result.User),
+		"token":         result.AccessToken,
+		"refresh_token": result.RefreshToken,
+		"expires_in":    int(result.ExpiresIn.Seconds()),
+	}
+	if result.GuestToken != "" {
+		body["guest_token"] = result.GuestToken
+	}
+	response.Data(c, status, body)
+}
+
+// stringCredential reads a required string credential, responding with INVALID_CREDENTIALS if
+// it is missing.
+func stringCredential(
+	c *gin.Context,
+	credentials map[string]interface{},
+	key string,
+	name string,
+) (string, bool) {
+	value, ok := credentials[key].(string)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_CREDENTIALS",
+			Message: name + " is required and must be a string.",
+		})
+	}
+	return value, ok
+}
+
+// RegisterRequest defines the request body for registering with a password.
+type RegisterRequest struct {
+	Identifier string `json:"identifier" binding:"required"` // A username or email address
+	Password   string `json:"password" binding:"required"`
+	Source     string `json:"source" binding:"required"`
+}
+
+// Register handles the HTTP request for registering a user with a username or email address and
+// a password. Registering an email address answers 202 Accepted whether or not it is taken, and
+// the user signs in separately.
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	source := user.Source(req.Source)
+	if !source.IsValid() {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_SOURCE",
+			Message: "The specified source is not supported.",
+		})
+		return
+	}
+
+	result, err := h.authService.RegisterWithPassword(
+		c.Request.Context(),
+		authService.RegisterWithPasswordParams{
+			Identifier: req.Identifier,
+			Password:   req.Password,
+			Source:     source,
+			Client:     clientInfo(c),
+		},
+	)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	if result.EmailSent {
+		response.Data(c, http.StatusAccepted, gin.H{"email_sent": true})
+		return
+	}
+
+	loginResponse(c, http.StatusCreated, result)
+}
+
+// RefreshRequest defines the request body for exchanging a refresh token.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh handles the HTTP request for rotating a refresh token into a new token pair.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	tokens, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken, clientInfo(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Data(c, http.StatusOK, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    int(tokens.ExpiresIn.Seconds()),
+	})
+}
+
+// AppleNotificationRequest defines the body of a Sign in with Apple server-to-server
+// notification.
+type AppleNotificationRequest struct {
+	Payload string `json:"payload" binding:"required"`
+}
+
+// AppleNotification handles server-to-server notifications from Sign in with Apple.
+func (h *AuthHandler) AppleNotification(c *gin.Context) {
+	var req AppleNotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.HandleAppleNotification(c.Request.Context(), req.Payload); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// SendSMSCodeRequest defines the request body for sending a phone verification code.
+type SendSMSCodeRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+}
+
+// SendSMSCode handles the HTTP request for sending a login verification code by SMS.
+func (h *AuthHandler) SendSMSCode(c *gin.Context) {
+	var req SendSMSCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.authService.SendPhoneVerificationCode(c.Request.Context(), req.PhoneNumber)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Data(c, http.StatusOK, gin.H{
+		"expires_in": int(result.ExpiresIn.Seconds()),
+	})
+}
+
+// ForgotPasswordRequest defines the request body for requesting a password reset email.
+type ForgotPasswordRequest struct {
+	Identifier string `json:"identifier" binding:"required"` // A username or email address
+}
+
+// ForgotPassword handles the HTTP request for a password reset email. The response is the same
+// whether or not the account exists.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(c.Request.Context(), req.Identifier); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPasswordRequest defines the request body for choosing a new password.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ResetPassword handles the HTTP request for choosing a new password with a reset token.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	err := h.authService.ResetPassword(c.Request.Context(), authService.ResetPasswordParams{
+		Token:    req.Token,
+		Password: req.Password,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// VerifyEmailRequest defines the request body for verifying an email address.
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail handles the HTTP request for verifying an email address with the token from a
+// verification email.
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SendEmailVerification handles the HTTP request for emailing a verification link to the
+// current user's unverified email address.
+func (h *AuthHandler) SendEmailVerification(c *gin.Context) {
+	result, err := h.authService.SendEmailVerification(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Data(c, http.StatusAccepted, gin.H{
+		"email":      result.Email,
+		"expires_in": int(result.ExpiresIn.Seconds()),
+	})
+}
+
+// IdentityResponse describes one of the current user's login methods.
+type IdentityResponse struct {
+	Provider string `json:"provider"`
+	// Identifier is the phone number, username or email address the user signs in with. It is
+	// omitted for providers whose IDs mean nothing to the user.
+	Identifier     string    `json:"identifier,omitempty"`
+	Email          string    `json:"email,omitempty"`
+	EmailIsPrivate bool      `json:"email_is_private"`
+	EmailVerified  bool      `json:"email_verified"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func toIdentityResponse(a *authDomain.Auth) IdentityResponse {
+	resp := IdentityResponse{
+		Provider:       string(a.Provider),
+		Email:          a.Email,
+		EmailIsPrivate: a.EmailIsPrivate,
+		EmailVerified:  a.EmailVerifiedAt != nil,
+		CreatedAt:      a.CreatedAt,
+	}
+	if a.Provider == authDomain.Phone || a.Provider == authDomain.Password {
+		resp.Identifier = a.ProviderID
+	}
+	return resp
+}
+
+// ListIdentities handles the HTTP request for listing the current user's login methods.
+func (h *AuthHandler) ListIdentities(c *gin.Context) {
+	auths, err := h.authService.ListIdentities(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	items := make([]IdentityResponse, 0, len(auths))
+	for _, a := range auths {
+		items = append(items, toIdentityResponse(a))
+	}
+	response.Data(c, http.StatusOK, gin.H{"identities": items})
+}
+
+// LinkIdentityRequest defines the request body for adding a login method. The credentials prove
+// that the user owns the identity, and take the same form as for login.
+type LinkIdentityRequest struct {
+	Provider    string                 `json:"provider" binding:"required"`
+	Credentials map[string]interface{} `json:"credentials" binding:"required"`
+	Source      string                 `json:"source"` // Required for WeChat
+}
+
+// LinkIdentity handles the HTTP request for adding a login method to the current user. A guest
+// who links one becomes a full user, and should refresh their tokens to lose the guest claim.
+func (h *AuthHandler) LinkIdentity(c *gin.Context) {
+	var req LinkIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := currentUserID(c)
+
+	var linked *authDomain.Auth
+	var err error
+
+	switch authDomain.Provider(req.Provider) {
+	case authDomain.Phone:
+		phoneNumber, ok := stringCredential(c, req.Credentials, "phone_number", "Phone number")
+		if !ok {
+			return
+		}
+		code, ok := stringCredential(c, req.Credentials, "code", "Code")
+		if !ok {
+			return
+		}
+		linked, err = h.authService.LinkPhone(ctx, authService.LinkPhoneParams{
+			UserID:      userID,
+			PhoneNumber: phoneNumber,
+			Code:        code,
+		})
+
+	case authDomain.Wechat:
+		source := user.Source(req.Source)
+		if !source.IsValid() {
+			response.Error(c, http.StatusBadRequest, response.APIError{
+				Code:    "INVALID_SOURCE",
+				Message: "The specified source is not supported.",
+			})
+			return
+		}
+		code, ok := stringCredential(c, req.Credentials, "code", "Code")
+		if !ok {
+			return
+		}
+		linked, err = h.authService.LinkWechat(ctx, authService.LinkWechatParams{
+			UserID: userID,
+			Code:   code,
+			Source: source,
+		})
+
+	case authDomain.Google:
+		idToken, ok := stringCredential(c, req.Credentials, "id_token", "ID token")
+		if !ok {
+			return
+		}
+		linked, err = h.authService.LinkGoogle(ctx, userID, idToken)
+
+	case authDomain.Apple:
+		idToken, ok := stringCredential(c, req.Credentials, "id_token", "ID token")
+		if !ok {
+			return
+		}
+		nonce, ok := stringCredential(c, req.Credentials, "nonce", "Nonce")
+		if !ok {
+			return
+		}
+		linked, err = h.authService.LinkApple(ctx, userID, idToken, nonce)
+
+	case authDomain.Password:
+		identifier, ok := stringCredential(c, req.Credentials, "identifier", "Identifier")
+		if !ok {
+			return
+		}
+		password, ok := stringCredential(c, req.Credentials, "password", "Password")
+		if !ok {
+			return
+		}
+		linked, err = h.authService.LinkPassword(ctx, authService.LinkPasswordParams{
+			UserID:     userID,
+			Identifier: identifier,
+			Password:   password,
+		})
+
+	default:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_PROVIDER",
+			Message: "The specified provider is not supported.",
+		})
+		return
+	}
+
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Data(c, http.StatusCreated, gin.H{"identity": toIdentityResponse(linked)})
+}
+
+// UnlinkIdentity handles the HTTP request for removing a login method from the current user.
+func (h *AuthHandler) UnlinkIdentity(c *gin.Context) {
+	provider := authDomain.Provider(c.Param("provider"))
+	err := h.authService.UnlinkIdentity(c.Request.Context(), currentUserID(c), provider)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteAccount handles the HTTP request for deleting the current user's account.
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	if err := h.authService.DeleteAccount(c.Request.Context(), currentUserID(c)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ReauthenticateRequest defines the request body for proving who the current user is again.
+// The credentials take the same form as for login, except that a phone number or username is
+// not needed, since the user's own is used.
+type ReauthenticateRequest struct {
+	Provider    string                 `json:"provider" binding:"required"`
+	Credentials map[string]interface{} `json:"credentials" binding:"required"`
+	Source      string                 `json:"source"`   // Required for WeChat
+	MFACode     string                 `json:"mfa_code"` // Required with two-factor authentication
+}
+
+// Reauthenticate handles the HTTP request for re-authenticating the current user before a
+// sensitive action. The response carries an access token with a new authentication time.
+func (h *AuthHandler) Reauthenticate(c *gin.Context) {
+	var req ReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	params := authService.ReauthenticateParams{
+		UserID:    currentUserID(c),
+		SessionID: currentSessionID(c),
+		Provider:  authDomain.Provider(req.Provider),
+		MFACode:   req.MFACode,
+		Client:    clientInfo(c),
+	}
+
+	var ok bool
+	switch params.Provider {
+	case authDomain.Phone:
+		params.Code, ok = stringCredential(c, req.Credentials, "code", "Code")
 
-	switch provider {
 	case authDomain.Wechat:
-		code, ok := req.Credentials["code"].(string)
-		if !ok {
+		params.Source = user.Source(req.Source)
+		if !params.Source.IsValid() {
 			response.Error(c, http.StatusBadRequest, response.APIError{
-				Code:    "INVALID_CREDENTIALS",
-				Message: "Code is required and must be a string.",
+				Code:    "INVALID_SOURCE",
+				Message: "The specified source is not supported.",
 			})
 			return
 		}
-		params := authService.LoginOrRegisterWithWechatParams{
-			Code: code,
-			// Source can be set here
+		params.Code, ok = stringCredential(c, req.Credentials, "code", "Code")
+
+	case authDomain.Google:
+		params.IDToken, ok = stringCredential(c, req.Credentials, "id_token", "ID token")
+
+	case authDomain.Apple:
+		params.IDToken, ok = stringCredential(c, req.Credentials, "id_token", "ID token")
+		if ok {
+			params.Nonce, ok = stringCredential(c, req.Credentials, "nonce", "Nonce")
 		}
-		result, err = h.authService.LoginOrRegisterWithWechat(c.Request.Context(), params)
 
-	// TODO: Add case for phone login
-	case authDomain.Phone:
-		response.Error(c, http.StatusNotImplemented, response.APIError{
-			Code:    "NOT_IMPLEMENTED",
-			Message: "This login provider is not yet implemented.",
-		})
-		return
+	case authDomain.Password:
+		params.Password, ok = stringCredential(c, req.Credentials, "password", "Password")
 
 	default:
 		response.Error(c, http.StatusBadRequest, response.APIError{
@@ -74,15 +668,252 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		})
 		return
 	}
+	if !ok {
+		return
+	}
 
+	tokens, err := h.authService.Reauthenticate(c.Request.Context(), params)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
 	response.Data(c, http.StatusOK, gin.H{
-		"user":  result.User,
-		"token": result.Token,
+		"token":      tokens.AccessToken,
+		"expires_in": int(tokens.ExpiresIn.Seconds()),
+	})
+}
+
+// ConfirmMergeRequest defines the request body for confirming an account merge.
+type ConfirmMergeRequest struct {
+	MergeToken string `json:"merge_token" binding:"required"`
+	// Code is a TOTP code or a recovery code of the other account, required when it has
+	// two-factor authentication enabled.
+	Code string `json:"code"`
+}
+
+// ConfirmMerge handles the HTTP request for merging another account, whose identity the current
+// user tried to link, into the current user's account.
+func (h *AuthHandler) ConfirmMerge(c *gin.Context) {
+	var req ConfirmMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	err := h.authService.ConfirmMerge(
+		c.Request.Context(),
+		currentUserID(c),
+		req.MergeToken,
+		req.Code,
+	)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	auths, err := h.authService.ListIdentities(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	items := make([]IdentityResponse, 0, len(auths))
+	for _, a := range auths {
+		items = append(items, toIdentityResponse(a))
+	}
+	response.Data(c, http.StatusOK, gin.H{"identities": items})
+}
+
+// BindWechatPhoneNumberRequest defines the request body for binding a phone number shared
+// through the mini program. Either code, or encrypted_data and iv, must be provided.
+type BindWechatPhoneNumberRequest struct {
+	Code          string `json:"code"`
+	EncryptedData string `json:"encrypted_data" binding:"required_without=Code"`
+	IV            string `json:"iv" binding:"required_with=EncryptedData"`
+}
+
+// BindWechatPhoneNumber handles the HTTP request for binding the phone number a user shared
+// through the mini program's getPhoneNumber button to their account.
+func (h *AuthHandler) BindWechatPhoneNumber(c *gin.Context) {
+	var req BindWechatPhoneNumberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	u, err := h.authService.BindWechatPhoneNumber(
+		c.Request.Context(),
+		authService.BindWechatPhoneNumberParams{
+			UserID:        currentUserID(c),
+			Code:          req.Code,
+			EncryptedData: req.EncryptedData,
+			IV:            req.IV,
+		},
+	)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Data(c, http.StatusOK, gin.H{"user": toUserResponse(u)})
+}
+
+// VerifyMFARequest defines the request body for completing a login with a second factor.
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // A TOTP code or a recovery code
+}
+
+// VerifyMFA handles the HTTP request for completing a login that requires a second factor.
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req VerifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.authService.VerifyMFA(c.Request.Context(), authService.VerifyMFAParams{
+		MFAToken: req.MFAToken,
+		Code:     req.Code,
+		Client:   clientInfo(c),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	loginResponse(c, http.StatusOK, result)
+}
+
+// BeginTOTPEnrollment handles the HTTP request for starting to set up an authenticator app.
+func (h *AuthHandler) BeginTOTPEnrollment(c *gin.Context) {
+	enrollment, err := h.authService.BeginTOTPEnrollment(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Data(c, http.StatusCreated, gin.H{
+		"secret":      enrollment.Secret,
+		"otpauth_uri": enrollment.URI,
+	})
+}
+
+// MFACodeRequest defines a request body that carries a second factor.
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ConfirmTOTPEnrollment handles the HTTP request for enabling two-factor authentication with a
+// code from the newly set up authenticator app.
+func (h *AuthHandler) ConfirmTOTPEnrollment(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.authService.ConfirmTOTPEnrollment(
+		c.Request.Context(),
+		currentUserID(c),
+		req.Code,
+	)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Data(c, http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes handles the HTTP request for replacing the current user's recovery
+// codes.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(
+		c.Request.Context(),
+		currentUserID(c),
+		req.Code,
+	)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Data(c, http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableTOTP handles the HTTP request for turning off two-factor authentication.
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	err := h.authService.DisableTOTP(c.Request.Context(), currentUserID(c), req.Code)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ImpersonateRequest defines the request body for acting as a user.
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// Impersonate handles the admin HTTP request for a short-lived access token that lets a staff
+// member see the app as a user.
+func (h *AuthHandler) Impersonate(c *gin.Context) {
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.authService.Impersonate(c.Request.Context(), authService.ImpersonateParams{
+		ActorID:      realUserID(c),
+		TargetUserID: user.UserID(c.Param("id")),
+		Reason:       req.Reason,
+		Client:       clientInfo(c),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Data(c, http.StatusCreated, gin.H{
+		"user":       toUserResponse(result.User),
+		"token":      result.AccessToken,
+		"expires_in": int(result.ExpiresIn.Seconds()),
 	})
 }
 
@@ -94,12 +925,252 @@ func (h *AuthHandler) handleError(c *gin.Context, err error) {
 		requestLogger = slog.Default()
 	}
 
+	var attemptsErr *authService.TooManyAttemptsError
+	if errors.As(err, &attemptsErr) {
+		retryAfter := int(attemptsErr.RetryAfter.Seconds())
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		response.Error(c, http.StatusTooManyRequests, response.APIError{
+			Code:    "TOO_MANY_ATTEMPTS",
+			Message: "Too many failed attempts. Please try again later.",
+			Details: gin.H{"retry_after": retryAfter},
+		})
+		return
+	}
+
+	// A conflicting identity can be resolved by merging the accounts, so the client gets what
+	// it needs to offer that.
+	var mergeErr *authService.MergeRequiredError
+	if errors.As(err, &mergeErr) {
+		response.Error(c, http.StatusConflict, response.APIError{
+			Code:    "IDENTITY_LINKED_TO_ANOTHER_USER",
+			Message: "Another account uses this login method. You can merge it into yours.",
+			Details: gin.H{
+				"merge_token":  mergeErr.MergeToken,
+				"expires_in":   int(mergeErr.ExpiresIn.Seconds()),
+				"providers":    mergeErr.Providers,
+				"mfa_required": mergeErr.MFARequired,
+			},
+		})
+		return
+	}
+
 	// We check for specific, known application errors first.
 	switch err {
 	case authService.ErrUserAlreadyExists:
 		response.Error(c, http.StatusConflict, response.APIError{
 			Code:    "USER_ALREADY_EXISTS",
-			Message: "A user with this identity already exists.",
+			Message: "This username is already taken.",
+		})
+	case authService.ErrInvalidPhoneNumber:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_PHONE_NUMBER",
+			Message: "The phone number is not valid.",
+		})
+	case authService.ErrInvalidVerificationCode:
+		response.Error(c, http.StatusUnauthorized, response.APIError{
+			Code:    "INVALID_VERIFICATION_CODE",
+			Message: "The verification code is invalid or has expired.",
+		})
+	case authService.ErrTooManyVerificationAttempts:
+		response.Error(c, http.StatusTooManyRequests, response.APIError{
+			Code:    "TOO_MANY_VERIFICATION_ATTEMPTS",
+			Message: "Too many failed attempts. Please request a new code.",
+		})
+	case authService.ErrInvalidRefreshToken:
+		response.Error(c, http.StatusUnauthorized, response.APIError{
+			Code:    "INVALID_REFRESH_TOKEN",
+			Message: "The refresh token is invalid or has expired.",
+		})
+	case authService.ErrRefreshTokenReused:
+		response.Error(c, http.StatusUnauthorized, response.APIError{
+			Code:    "REFRESH_TOKEN_REUSED",
+			Message: "The refresh token has already been used. Please log in again.",
+		})
+	case authService.ErrInvalidWechatCode:
+		response.Error(c, http.StatusUnauthorized, response.APIError{
+			Code:    "INVALID_WECHAT_CODE",
+			Message: "The WeChat code is invalid or has already been used.",
+		})
+	case authService.ErrWechatUnavailable:
+		response.Error(c, http.StatusServiceUnavailable, response.APIError{
+			Code:    "WECHAT_UNAVAILABLE",
+			Message: "WeChat is temporarily unavailable. Please try again later.",
+		})
+	case authService.ErrIdentityProviderUnavailable:
+		response.Error(c, http.StatusServiceUnavailable, response.APIError{
+			Code:    "IDENTITY_PROVIDER_UNAVAILABLE",
+			Message: "The sign-in provider is temporarily unavailable. Please try again later.",
+		})
+	case authService.ErrWechatSessionExpired:
+		response.Error(c, http.StatusUnauthorized, response.APIError{
+			Code:    "WECHAT_SESSION_EXPIRED",
+			Message: "The WeChat session has expired. Please log in with WeChat again.",
+		})
+	case authService.ErrPhoneNumberTaken:
+		response.Error(c, http.StatusConflict, response.APIError{
+			Code:    "PHONE_NUMBER_TAKEN",
+			Message: "This phone number is already used by another account.",
+		})
+	case authService.ErrPhoneNumberAlreadyBound:
+		response.Error(c, http.StatusConflict, response.APIError{
+			Code:    "PHONE_NUMBER_ALREADY_BOUND",
+			Message: "A phone number is already bound to this account.",
+		})
+	case authService.ErrInvalidCredentials:
+		response.Error(c, http.StatusUnauthorized, response.APIError{
+			Code:    "INVALID_CREDENTIALS",
+			Message: "The username, email address or password is incorrect.",
+		})
+	case authService.ErrInvalidIdentifier:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_IDENTIFIER",
+			Message: "Use a valid email address, or a username of 3-32 letters, digits, _ or .",
+		})
+	case authService.ErrWeakPassword:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code: "WEAK_PASSWORD",
+			Message: "Passwords must be long enough, mix at least two of lowercase, uppercase, " +
+				"digits and symbols, and not contain your username.",
+		})
+	case authService.ErrInvalidResetToken:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_RESET_TOKEN",
+			Message: "The password reset link is invalid or has expired.",
+		})
+	case authService.ErrInvalidVerificationToken:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_VERIFICATION_TOKEN",
+			Message: "The email verification link is invalid or has expired.",
+		})
+	case authService.ErrEmailAlreadyVerified:
+		response.Error(c, http.StatusConflict, response.APIError{
+			Code:    "EMAIL_ALREADY_VERIFIED",
+			Message: "Your email address is already verified.",
+		})
+	case authService.ErrNoEmailToVerify:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "NO_EMAIL_TO_VERIFY",
+			Message: "Your account has no email address to verify.",
+		})
+	case authService.ErrEmailThrottled:
+		response.Error(c, http.StatusTooManyRequests, response.APIError{
+			Code:    "EMAIL_THROTTLED",
+			Message: "An email was sent recently. Please try again later.",
+		})
+	case authService.ErrIdentityLinkedToAnotherUser:
+		response.Error(c, http.StatusConflict, response.APIError{
+			Code:    "IDENTITY_LINKED_TO_ANOTHER_USER",
+			Message: "This login method is already used by another account.",
+		})
+	case authService.ErrProviderAlreadyLinked:
+		response.Error(c, http.StatusConflict, response.APIError{
+			Code:    "PROVIDER_ALREADY_LINKED",
+			Message: "Your account already has a login method of this kind.",
+		})
+	case authService.ErrIdentityNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "IDENTITY_NOT_FOUND",
+			Message: "Your account has no login method of this kind.",
+		})
+	case authService.ErrLastIdentity:
+		response.Error(c, http.StatusConflict, response.APIError{
+			Code:    "LAST_IDENTITY",
+			Message: "You cannot remove your only way to sign in.",
+		})
+	case authService.ErrInvalidMergeToken:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_MERGE_TOKEN",
+			Message: "The merge request is invalid or has expired.",
+		})
+	case authService.ErrCannotMergeSameUser:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "CANNOT_MERGE_SAME_USER",
+			Message: "An account cannot be merged into itself.",
+		})
+	case authService.ErrMergeUserNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "MERGE_USER_NOT_FOUND",
+			Message: "The account to merge no longer exists.",
+		})
+	case authService.ErrInvalidMFAToken:
+		response.Error(c, http.StatusUnauthorized, response.APIError{
+			Code:    "INVALID_MFA_TOKEN",
+			Message: "The login has expired. Please sign in again.",
+		})
+	case authService.ErrInvalidMFACode:
+		response.Error(c, http.StatusUnauthorized, response.APIError{
+			Code:    "INVALID_MFA_CODE",
+			Message: "The verification code is incorrect.",
+		})
+	case authService.ErrMFAAlreadyEnabled:
+		response.Error(c, http.StatusConflict, response.APIError{
+			Code:    "MFA_ALREADY_ENABLED",
+			Message: "Two-factor authentication is already enabled.",
+		})
+	case authService.ErrMFANotEnabled:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "MFA_NOT_ENABLED",
+			Message: "Two-factor authentication is not enabled.",
+		})
+	case authService.ErrMFAEnrollmentNotFound:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "MFA_ENROLLMENT_NOT_FOUND",
+			Message: "Start setting up two-factor authentication first.",
+		})
+	case authService.ErrDeviceIDRequired:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "DEVICE_ID_REQUIRED",
+			Message: "The " + DeviceIDHeader + " header is required.",
+		})
+	case authService.ErrCannotImpersonateSelf:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "CANNOT_IMPERSONATE_SELF",
+			Message: "You cannot impersonate yourself.",
+		})
+	case authService.ErrImpersonationReasonRequired:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "IMPERSONATION_REASON_REQUIRED",
+			Message: "Give a reason for impersonating the user.",
+		})
+	case authService.ErrImpersonationTargetNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "USER_NOT_FOUND",
+			Message: "The user does not exist.",
+		})
+	case authService.ErrInvalidIDToken:
+		response.Error(c, http.StatusUnauthorized, response.APIError{
+			Code:    "INVALID_ID_TOKEN",
+			Message: "The ID token is invalid or has expired.",
+		})
+	case authService.ErrMFACodeRequired:
+		response.Error(c, http.StatusUnauthorized, response.APIError{
+			Code:    "MFA_CODE_REQUIRED",
+			Message: "Enter a code from your authenticator app or a recovery code.",
+		})
+	case authService.ErrReauthProviderUnsupported:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_PROVIDER",
+			Message: "This login method cannot be used to confirm it is you.",
+		})
+	case authService.ErrUserNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "USER_NOT_FOUND",
+			Message: "The user does not exist.",
+		})
+	case authService.ErrSessionNotActive:
+		response.Error(c, http.StatusUnauthorized, response.APIError{
+			Code:    "SESSION_NOT_ACTIVE",
+			Message: "Your session has ended. Please sign in again.",
+		})
+	case authService.ErrTooManyGuests:
+		response.Error(c, http.StatusTooManyRequests, response.APIError{
+			Code:    "TOO_MANY_GUESTS",
+			Message: "Too many guests were created from this device. Please sign in instead.",
+		})
+	case authService.ErrSMSThrottled:
+		response.Error(c, http.StatusTooManyRequests, response.APIError{
+			Code:    "SMS_THROTTLED",
+			Message: "Too many messages were sent to this number. Please try again later.",
 		})
 	default:
 		// For unhandled or unexpected errors, log them and return a generic 500.
@@ -109,4 +1180,4 @@ func (h *AuthHandler) handleError(c *gin.Context, err error) {
 			Message: "An unexpected error occurred on our end.",
 		})
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	avatarService "github.com/moriverse/45-server/internal/app/avatar"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/web/middleware"
+	"github.com/moriverse/45-server/internal/infrastructure/web/response"
+)
+
+// AvatarFormField is the multipart form field that carries an uploaded avatar.
+const AvatarFormField = "avatar"
+
+// multipartOverhead allows for the multipart boundaries and headers around the avatar file.
+const multipartOverhead = 64 << 10
+
+// AvatarHandler handles HTTP requests for uploading and fetching avatars.
+type AvatarHandler struct {
+	avatarService *avatarService.Service
+}
+
+// NewAvatarHandler creates a new instance of AvatarHandler.
+func NewAvatarHandler(avatarService *avatarService.Service) *AvatarHandler {
+	return &AvatarHandler{avatarService: avatarService}
+}
+
+// Upload handles the HTTP request for replacing the current user's avatar with an image sent
+// as multipart form data.
+func (h *AvatarHandler) Upload(c *gin.Context) {
+	maxBytes := h.avatarService.MaxUploadBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+multipartOverhead)
+
+	file, err := c.FormFile(AvatarFormField)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.handleError(c, avatarService.ErrFileTooLarge)
+			return
+		}
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: "Send the image as multipart form data in the " + AvatarFormField + " field.",
+		})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	defer f.Close()
+
+	u, err := h.avatarService.Upload(c.Request.Context(), currentUserID(c), f)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Data(c, http.StatusOK, gin.H{"user": toUserResponse(u)})
+}
+
+// Delete handles the HTTP request for removing the current user's avatar.
+func (h *AvatarHandler) Delete(c *gin.Context) {
+	u, err := h.avatarService.Remove(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Data(c, http.StatusOK, gin.H{"user": toUserResponse(u)})
+}
+
+// Get handles the HTTP request behind a user's avatar link, redirecting to a signed URL of
+// the thumbnail closest to the optional size query parameter. Avatar links are public, so
+// that they can be used in image tags.
+func (h *AvatarHandler) Get(c *gin.Context) {
+	size := 0
+	if raw := c.Query("size"); raw != "" {
+		var err error
+		size, err = strconv.Atoi(raw)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, response.APIError{
+				Code:    "INVALID_REQUEST_QUERY",
+				Message: "size must be a number.",
+			})
+			return
+		}
+	}
+
+	signedURL, expiresIn, err := h.avatarService.SignedURL(
+		c.Request.Context(),
+		user.UserID(c.Param("user_id")),
+		c.Param("avatar_id"),
+		size,
+	)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	// Let clients reuse the redirect for half the life of the signed URL.
+	maxAge := int(expiresIn.Seconds()) / 2
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	c.Redirect(http.StatusFound, signedURL)
+}
+
+func (h *AvatarHandler) handleError(c *gin.Context, err error) {
+	logger, _ := c.Get(middleware.LoggerKey)
+	requestLogger, ok := logger.(*slog.Logger)
+	if !ok {
+		requestLogger = slog.Default()
+	}
+
+	switch err {
+	case avatarService.ErrUserNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "USER_NOT_FOUND",
+			Message: "The user does not exist.",
+		})
+	case avatarService.ErrAvatarNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "AVATAR_NOT_FOUND",
+			Message: "The avatar does not exist.",
+		})
+	case avatarService.ErrFileTooLarge:
+		response.Error(c, http.StatusRequestEntityTooLarge, response.APIError{
+			Code:    "AVATAR_TOO_LARGE",
+			Message: "The image file is too large.",
+			Details: gin.H{"max_bytes": h.avatarService.MaxUploadBytes()},
+		})
+	case avatarService.ErrUnsupportedImageType:
+		response.Error(c, http.StatusUnsupportedMediaType, response.APIError{
+			Code:    "UNSUPPORTED_IMAGE_TYPE",
+			Message: "Upload a JPEG, PNG or GIF image.",
+		})
+	case avatarService.ErrInvalidImage:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_IMAGE",
+			Message: "The image is damaged or cannot be read.",
+		})
+	case avatarService.ErrImageTooLarge:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "IMAGE_DIMENSIONS_TOO_LARGE",
+			Message: "The image has too many pixels.",
+		})
+	default:
+		requestLogger.Error("Unhandled API error", "error", err)
+		response.Error(c, http.StatusInternalServerError, response.APIError{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: "An unexpected error occurred on our end.",
+		})
+	}
+}
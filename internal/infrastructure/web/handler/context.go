@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	authService "github.com/moriverse/45-server/internal/app/auth"
+	"github.com/moriverse/45-server/internal/domain/session"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/web/middleware"
+)
+
+// DeviceIDHeader is the header clients use to identify the device a request comes from.
+const DeviceIDHeader = "X-Device-ID"
+
+// The longest user agent and device ID kept, matching the columns they are stored in.
+const (
+	maxUserAgentLength = 512
+	maxDeviceIDLength  = 255
+)
+
+// clientInfo collects information about the device that made the request. Headers are
+// truncated, so that a client cannot make storing a session or login event fail.
+func clientInfo(c *gin.Context) authService.ClientInfo {
+	return authService.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: truncate(c.Request.UserAgent(), maxUserAgentLength),
+		DeviceID:  truncate(c.GetHeader(DeviceIDHeader), maxDeviceIDLength),
+	}
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	count := 0
+	for i := range s {
+		if count == n {
+			return s[:i]
+		}
+		count++
+	}
+	return s
+}
+
+// currentUserID returns the ID of the user authenticated by the auth middleware.
+func currentUserID(c *gin.Context) user.UserID {
+	return user.UserID(c.GetString(middleware.UserIDKey))
+}
+
+// realUserID returns the user actually making the request, who is a staff member rather than
+// the current user while impersonating.
+func realUserID(c *gin.Context) user.UserID {
+	return user.UserID(c.GetString(middleware.RealUserIDKey))
+}
+
+// currentSessionID returns the ID of the session authenticated by the auth middleware.
+func currentSessionID(c *gin.Context) session.SessionID {
+	return session.SessionID(c.GetString(middleware.SessionIDKey))
+}
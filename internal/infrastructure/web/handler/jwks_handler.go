@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/moriverse/45-server/internal/infrastructure/web/response"
+	"github.com/moriverse/45-server/internal/utils"
+)
+
+// JWKSHandler publishes the public keys that access tokens can be verified with.
+type JWKSHandler struct {
+	keys *utils.KeySet
+}
+
+// NewJWKSHandler creates a new instance of JWKSHandler.
+func NewJWKSHandler(keys *utils.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// Get handles the HTTP request for the JSON Web Key Set.
+func (h *JWKSHandler) Get(c *gin.Context) {
+	// Verifiers may cache the set, but not for so long that they miss a key rotation.
+	c.Header("Cache-Control", "public, max-age=300")
+	response.Data(c, http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	loginHistoryService "github.com/moriverse/45-server/internal/app/loginhistory"
+	"github.com/moriverse/45-server/internal/infrastructure/web/middleware"
+	"github.com/moriverse/45-server/internal/infrastructure/web/response"
+)
+
+// LoginHistoryHandler handles HTTP requests for the current user's login history.
+type LoginHistoryHandler struct {
+	loginHistoryService *loginHistoryService.Service
+}
+
+// NewLoginHistoryHandler creates a new instance of LoginHistoryHandler.
+func NewLoginHistoryHandler(loginHistoryService *loginHistoryService.Service) *LoginHistoryHandler {
+	return &LoginHistoryHandler{loginHistoryService: loginHistoryService}
+}
+
+// LoginEventResponse is the public representation of a login event.
+type LoginEventResponse struct {
+	ID            string    `json:"id"`
+	Provider      string    `json:"provider"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	IPAddress     string    `json:"ip_address"`
+	UserAgent     string    `json:"user_agent"`
+	DeviceID      string    `json:"device_id,omitempty"`
+	Source        string    `json:"source,omitempty"`
+	Country       string    `json:"country,omitempty"`
+	NewDevice     bool      `json:"new_device"`
+	NewCountry    bool      `json:"new_country"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// List handles the HTTP request for listing the current user's recent logins, including failed
+// attempts to sign in to their account.
+func (h *LoginHistoryHandler) List(c *gin.Context) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, response.APIError{
+				Code:    "INVALID_REQUEST_QUERY",
+				Message: "limit must be a number.",
+			})
+			return
+		}
+	}
+
+	events, err := h.loginHistoryService.List(c.Request.Context(), currentUserID(c), limit)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	items := make([]LoginEventResponse, 0, len(events))
+	for _, e := range events {
+		items = append(items, LoginEventResponse{
+			ID:            string(e.ID),
+			Provider:      e.Provider,
+			Success:       e.Success,
+			FailureReason: e.FailureReason,
+			IPAddress:     e.IPAddress,
+			UserAgent:     e.UserAgent,
+			DeviceID:      e.DeviceID,
+			Source:        string(e.Source),
+			Country:       e.Country,
+			NewDevice:     e.NewDevice,
+			NewCountry:    e.NewCountry,
+			CreatedAt:     e.CreatedAt,
+		})
+	}
+	response.Data(c, http.StatusOK, gin.H{"logins": items})
+}
+
+func (h *LoginHistoryHandler) handleError(c *gin.Context, err error) {
+	logger, _ := c.Get(middleware.LoggerKey)
+	requestLogger, ok := logger.(*slog.Logger)
+	if !ok {
+		requestLogger = slog.Default()
+	}
+
+	requestLogger.Error("Unhandled API error", "error", err)
+	response.Error(c, http.StatusInternalServerError, response.APIError{
+		Code:    "INTERNAL_SERVER_ERROR",
+		Message: "An unexpected error occurred on our end.",
+	})
+}
@@ -0,0 +1,484 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	oauthService "github.com/moriverse/45-server/internal/app/oauth"
+	"github.com/moriverse/45-server/internal/domain/oauth"
+	"github.com/moriverse/45-server/internal/infrastructure/web/middleware"
+	"github.com/moriverse/45-server/internal/infrastructure/web/response"
+)
+
+// OAuthHandler handles HTTP requests for the OAuth 2.0 authorization server: the endpoints
+// third-party clients call, the consent screen of the first-party app, and client management
+// for admins.
+type OAuthHandler struct {
+	oauthService *oauthService.Service
+}
+
+// NewOAuthHandler creates a new instance of OAuthHandler.
+func NewOAuthHandler(oauthService *oauthService.Service) *OAuthHandler {
+	return &OAuthHandler{oauthService: oauthService}
+}
+
+// OAuthClientResponse is the public representation of an OAuth client. The secret is never
+// shown after the client is created.
+type OAuthClientResponse struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Confidential bool          `json:"confidential"`
+	RedirectURIs []string      `json:"redirect_uris"`
+	Scopes       []oauth.Scope `json:"scopes"`
+	CreatedBy    string        `json:"created_by,omitempty"`
+	RevokedAt    *time.Time    `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+func toOAuthClientResponse(client *oauth.Client) OAuthClientResponse {
+	redirectURIs := client.RedirectURIs
+	if redirectURIs == nil {
+		redirectURIs = []string{}
+	}
+	scopes := client.Scopes
+	if scopes == nil {
+		scopes = []oauth.Scope{}
+	}
+	return OAuthClientResponse{
+		ID:           string(client.ID),
+		Name:         client.Name,
+		Confidential: client.IsConfidential(),
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+		CreatedBy:    string(client.CreatedBy),
+		RevokedAt:    client.RevokedAt,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// ListClients handles the HTTP request for listing every OAuth client.
+func (h *OAuthHandler) ListClients(c *gin.Context) {
+	clients, err := h.oauthService.ListClients(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	items := make([]OAuthClientResponse, 0, len(clients))
+	for _, client := range clients {
+		items = append(items, toOAuthClientResponse(client))
+	}
+	response.Data(c, http.StatusOK, gin.H{"clients": items})
+}
+
+// CreateOAuthClientRequest defines the request body for registering an OAuth client.
+type CreateOAuthClientRequest struct {
+	Name         string        `json:"name" binding:"required"`
+	RedirectURIs []string      `json:"redirect_uris" binding:"required"`
+	Scopes       []oauth.Scope `json:"scopes" binding:"required"`
+	Confidential bool          `json:"confidential"` // False for apps that cannot keep a secret
+}
+
+// CreateClient handles the HTTP request for registering an OAuth client. The response is the
+// only time a confidential client's secret is shown.
+func (h *OAuthHandler) CreateClient(c *gin.Context) {
+	var req CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.oauthService.CreateClient(
+		c.Request.Context(),
+		oauthService.CreateClientParams{
+			Name:         req.Name,
+			RedirectURIs: req.RedirectURIs,
+			Scopes:       req.Scopes,
+			Confidential: req.Confidential,
+			CreatedBy:    currentUserID(c),
+		},
+	)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	body := gin.H{"client": toOAuthClientResponse(result.Client)}
+	if result.Secret != "" {
+		body["client_secret"] = result.Secret
+	}
+	response.Data(c, http.StatusCreated, body)
+}
+
+// RevokeClient handles the HTTP request for revoking an OAuth client.
+func (h *OAuthHandler) RevokeClient(c *gin.Context) {
+	id := oauth.ClientID(c.Param("id"))
+	if err := h.oauthService.RevokeClient(c.Request.Context(), id, currentUserID(c)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AuthorizeRequest defines the parameters of an authorization request, which the first-party
+// app passes on from the URI the client sent the user to.
+type AuthorizeRequest struct {
+	ClientID            string `json:"client_id" form:"client_id" binding:"required"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri" binding:"required"`
+	ResponseType        string `json:"response_type" form:"response_type"`
+	Scope               string `json:"scope" form:"scope"`
+	State               string `json:"state" form:"state"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+	// Approved is the user's decision on the consent screen.
+	Approved bool `json:"approved" form:"-"`
+}
+
+func (req *AuthorizeRequest) params(c *gin.Context) oauthService.AuthorizeParams {
+	return oauthService.AuthorizeParams{
+		UserID:              currentUserID(c),
+		ClientID:            oauth.ClientID(req.ClientID),
+		RedirectURI:         req.RedirectURI,
+		ResponseType:        req.ResponseType,
+		Scope:               req.Scope,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}
+}
+
+// GetAuthorization handles the HTTP request for checking an authorization request before the
+// consent screen is shown.
+func (h *OAuthHandler) GetAuthorization(c *gin.Context) {
+	var req AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_QUERY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.oauthService.PrepareAuthorization(c.Request.Context(), req.params(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Data(c, http.StatusOK, gin.H{
+		"client": gin.H{
+			"id":   result.Client.ID,
+			"name": result.Client.Name,
+		},
+		"scopes":           result.Scopes,
+		"consent_required": result.ConsentRequired,
+	})
+}
+
+// Authorize handles the HTTP request for approving or denying an authorization request. The
+// first-party app sends the user on to redirect_to.
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var req AuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	redirectTo, err := h.oauthService.Authorize(c.Request.Context(), req.params(c), req.Approved)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Data(c, http.StatusOK, gin.H{"redirect_to": redirectTo})
+}
+
+// AuthorizedAppResponse is the public representation of a client the user has authorized.
+type AuthorizedAppResponse struct {
+	ClientID     string        `json:"client_id"`
+	Name         string        `json:"name"`
+	Scopes       []oauth.Scope `json:"scopes"`
+	AuthorizedAt time.Time     `json:"authorized_at"`
+}
+
+// ListAuthorizedApps handles the HTTP request for listing the apps the user has authorized.
+func (h *OAuthHandler) ListAuthorizedApps(c *gin.Context) {
+	apps, err := h.oauthService.ListAuthorizedApps(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	items := make([]AuthorizedAppResponse, 0, len(apps))
+	for _, app := range apps {
+		items = append(items, AuthorizedAppResponse{
+			ClientID:     string(app.Client.ID),
+			Name:         app.Client.Name,
+			Scopes:       app.Scopes,
+			AuthorizedAt: app.AuthorizedAt,
+		})
+	}
+	response.Data(c, http.StatusOK, gin.H{"apps": items})
+}
+
+// RevokeAuthorizedApp handles the HTTP request for removing an app's access to the account.
+func (h *OAuthHandler) RevokeAuthorizedApp(c *gin.Context) {
+	clientID := oauth.ClientID(c.Param("id"))
+	err := h.oauthService.RevokeAuthorizedApp(c.Request.Context(), currentUserID(c), clientID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TokenRequest defines the form body of a token request.
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+}
+
+// Token handles the OAuth token endpoint, which takes a form-encoded body and answers in the
+// format of RFC 6749, section 5.
+func (h *OAuthHandler) Token(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.oauthError(c, &oauthService.Error{
+			Code:        oauthService.CodeInvalidRequest,
+			Description: err.Error(),
+		})
+		return
+	}
+
+	tokens, err := h.oauthService.Token(c.Request.Context(), oauthService.TokenParams{
+		Client:       clientCredentials(c),
+		GrantType:    req.GrantType,
+		Code:         req.Code,
+		RedirectURI:  req.RedirectURI,
+		CodeVerifier: req.CodeVerifier,
+		RefreshToken: req.RefreshToken,
+		Scope:        req.Scope,
+	})
+	if err != nil {
+		h.oauthError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  tokens.AccessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(tokens.ExpiresIn.Seconds()),
+		"refresh_token": tokens.RefreshToken,
+		"scope":         oauth.FormatScopes(tokens.Scopes),
+	})
+}
+
+// TokenOperationRequest defines the form body of an introspection or revocation request.
+type TokenOperationRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"` // Ignored; both token types are checked
+}
+
+// Introspect handles the OAuth token introspection endpoint (RFC 7662).
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	var req TokenOperationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.oauthError(c, &oauthService.Error{
+			Code:        oauthService.CodeInvalidRequest,
+			Description: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.oauthService.Introspect(c.Request.Context(), clientCredentials(c), req.Token)
+	if err != nil {
+		h.oauthError(c, err)
+		return
+	}
+	if !result.Active {
+		c.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"active":     true,
+		"token_type": result.TokenType,
+		"client_id":  result.ClientID,
+		"sub":        result.UserID,
+		"scope":      oauth.FormatScopes(result.Scopes),
+		"exp":        result.ExpiresAt.Unix(),
+	})
+}
+
+// Revoke handles the OAuth token revocation endpoint (RFC 7009).
+func (h *OAuthHandler) Revoke(c *gin.Context) {
+	var req TokenOperationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.oauthError(c, &oauthService.Error{
+			Code:        oauthService.CodeInvalidRequest,
+			Description: err.Error(),
+		})
+		return
+	}
+
+	err := h.oauthService.Revoke(c.Request.Context(), clientCredentials(c), req.Token)
+	if err != nil {
+		h.oauthError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// UserInfo handles the HTTP request of a client for the profile of the user who authorized
+// it, limited to the scopes it was granted. First-party tokens see every field.
+func (h *OAuthHandler) UserInfo(c *gin.Context) {
+	scopes := []oauth.Scope{oauth.ScopeProfile, oauth.ScopePhone}
+	if value, ok := c.Get(middleware.OAuthScopesKey); ok {
+		scopes, _ = value.([]oauth.Scope)
+	}
+
+	info, err := h.oauthService.GetUserInfo(c.Request.Context(), currentUserID(c), scopes)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	body := gin.H{
+		"sub":      info.Subject,
+		"nickname": info.Nickname,
+		"picture":  info.AvatarURL,
+	}
+	if info.PhoneNumber != "" {
+		body["phone_number"] = info.PhoneNumber
+	}
+	response.Data(c, http.StatusOK, body)
+}
+
+// clientCredentials reads the client's credentials from HTTP Basic authentication, or from
+// the client_id and client_secret form fields (RFC 6749, section 2.3.1).
+func clientCredentials(c *gin.Context) oauthService.ClientCredentials {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		// Both parts are form-encoded before they are put in the header.
+		if decoded, err := url.QueryUnescape(id); err == nil {
+			id = decoded
+		}
+		if decoded, err := url.QueryUnescape(secret); err == nil {
+			secret = decoded
+		}
+		return oauthService.ClientCredentials{ID: oauth.ClientID(id), Secret: secret}
+	}
+	return oauthService.ClientCredentials{
+		ID:     oauth.ClientID(c.PostForm("client_id")),
+		Secret: c.PostForm("client_secret"),
+	}
+}
+
+// oauthError answers a request to an endpoint called by OAuth clients, which expect errors in
+// the format of RFC 6749, section 5.2 rather than the API's own.
+func (h *OAuthHandler) oauthError(c *gin.Context, err error) {
+	var oauthErr *oauthService.Error
+	if !errors.As(err, &oauthErr) {
+		h.handleError(c, err)
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == oauthService.CodeInvalidClient {
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+	})
+}
+
+func (h *OAuthHandler) handleError(c *gin.Context, err error) {
+	logger, _ := c.Get(middleware.LoggerKey)
+	requestLogger, ok := logger.(*slog.Logger)
+	if !ok {
+		requestLogger = slog.Default()
+	}
+
+	// The client and redirect URI were valid, so the first-party app can send the user back to
+	// the client with the error.
+	var redirectErr *oauthService.RedirectError
+	if errors.As(err, &redirectErr) {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_AUTHORIZATION_REQUEST",
+			Message: redirectErr.Err.Description,
+			Details: gin.H{
+				"error":       redirectErr.Err.Code,
+				"redirect_to": redirectErr.RedirectTo,
+			},
+		})
+		return
+	}
+
+	switch err {
+	case oauthService.ErrClientNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "OAUTH_CLIENT_NOT_FOUND",
+			Message: "The app does not exist or is no longer available.",
+		})
+	case oauthService.ErrRedirectURIMismatch:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "REDIRECT_URI_MISMATCH",
+			Message: "The redirect URI is not registered for the app.",
+		})
+	case oauthService.ErrNameRequired:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "NAME_REQUIRED",
+			Message: "Name the app the client is for.",
+		})
+	case oauthService.ErrRedirectURIRequired:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "REDIRECT_URI_REQUIRED",
+			Message: "A client needs at least one redirect URI.",
+		})
+	case oauthService.ErrInvalidRedirectURI:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REDIRECT_URI",
+			Message: "Redirect URIs must be absolute, without a fragment, and use HTTPS.",
+		})
+	case oauthService.ErrScopesRequired:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "SCOPES_REQUIRED",
+			Message: "A client needs at least one scope.",
+		})
+	case oauthService.ErrInvalidScope:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_SCOPE",
+			Message: "One of the scopes is not supported.",
+		})
+	case oauthService.ErrConsentNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "AUTHORIZED_APP_NOT_FOUND",
+			Message: "You have not authorized this app.",
+		})
+	case oauthService.ErrUserNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "USER_NOT_FOUND",
+			Message: "The user does not exist.",
+		})
+	default:
+		requestLogger.Error("Unhandled API error", "error", err)
+		response.Error(c, http.StatusInternalServerError, response.APIError{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: "An unexpected error occurred on our end.",
+		})
+	}
+}
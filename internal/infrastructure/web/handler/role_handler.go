@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	rbacService "github.com/moriverse/45-server/internal/app/rbac"
+	"github.com/moriverse/45-server/internal/domain/rbac"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/web/middleware"
+	"github.com/moriverse/45-server/internal/infrastructure/web/response"
+)
+
+// RoleHandler handles admin HTTP requests for managing the roles of users.
+type RoleHandler struct {
+	rbacService *rbacService.Service
+}
+
+// NewRoleHandler creates a new instance of RoleHandler.
+func NewRoleHandler(rbacService *rbacService.Service) *RoleHandler {
+	return &RoleHandler{rbacService: rbacService}
+}
+
+// RoleResponse is the public representation of a role.
+type RoleResponse struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Permissions []rbac.Permission `json:"permissions"`
+}
+
+func toRoleResponses(roles []*rbac.Role) []RoleResponse {
+	items := make([]RoleResponse, 0, len(roles))
+	for _, r := range roles {
+		permissions := r.Permissions
+		if permissions == nil {
+			permissions = []rbac.Permission{}
+		}
+		items = append(items, RoleResponse{
+			Name:        r.Name,
+			Description: r.Description,
+			Permissions: permissions,
+		})
+	}
+	return items
+}
+
+// List handles the HTTP request for listing every role that can be granted.
+func (h *RoleHandler) List(c *gin.Context) {
+	roles, err := h.rbacService.ListRoles(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Data(c, http.StatusOK, gin.H{"roles": toRoleResponses(roles)})
+}
+
+// ListUserRoles handles the HTTP request for listing the roles of a user.
+func (h *RoleHandler) ListUserRoles(c *gin.Context) {
+	userID := user.UserID(c.Param("id"))
+	roles, err := h.rbacService.ListUserRoles(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Data(c, http.StatusOK, gin.H{"roles": toRoleResponses(roles)})
+}
+
+// AssignRoleRequest defines the request body for granting a role to a user.
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// AssignRole handles the HTTP request for granting a role to a user.
+func (h *RoleHandler) AssignRole(c *gin.Context) {
+	var req AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	role, err := h.rbacService.AssignRole(c.Request.Context(), rbacService.AssignRoleParams{
+		UserID:    user.UserID(c.Param("id")),
+		RoleName:  req.Role,
+		GrantedBy: currentUserID(c),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Data(c, http.StatusCreated, gin.H{"role": toRoleResponses([]*rbac.Role{role})[0]})
+}
+
+// RevokeRole handles the HTTP request for taking a role away from a user.
+func (h *RoleHandler) RevokeRole(c *gin.Context) {
+	err := h.rbacService.RevokeRole(
+		c.Request.Context(),
+		user.UserID(c.Param("id")),
+		c.Param("role"),
+		currentUserID(c),
+	)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *RoleHandler) handleError(c *gin.Context, err error) {
+	logger, _ := c.Get(middleware.LoggerKey)
+	requestLogger, ok := logger.(*slog.Logger)
+	if !ok {
+		requestLogger = slog.Default()
+	}
+
+	switch err {
+	case rbacService.ErrRoleNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "ROLE_NOT_FOUND",
+			Message: "The role does not exist.",
+		})
+	case rbacService.ErrUserNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "USER_NOT_FOUND",
+			Message: "The user does not exist.",
+		})
+	case rbacService.ErrRoleAlreadyAssigned:
+		response.Error(c, http.StatusConflict, response.APIError{
+			Code:    "ROLE_ALREADY_ASSIGNED",
+			Message: "The user already has this role.",
+		})
+	case rbacService.ErrRoleNotAssigned:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "ROLE_NOT_ASSIGNED",
+			Message: "The user does not have this role.",
+		})
+	default:
+		requestLogger.Error("Unhandled API error", "error", err)
+		response.Error(c, http.StatusInternalServerError, response.APIError{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: "An unexpected error occurred on our end.",
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	sessionService "github.com/moriverse/45-server/internal/app/session"
+	"github.com/moriverse/45-server/internal/domain/session"
+	"github.com/moriverse/45-server/internal/infrastructure/web/middleware"
+	"github.com/moriverse/45-server/internal/infrastructure/web/response"
+)
+
+// SessionHandler handles HTTP requests for listing and signing out sessions.
+type SessionHandler struct {
+	sessionService *sessionService.Service
+}
+
+// NewSessionHandler creates a new instance of SessionHandler.
+func NewSessionHandler(sessionService *sessionService.Service) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+// SessionResponse is the public representation of a session.
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceID   string    `json:"device_id,omitempty"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// List handles the HTTP request for listing the current user's active sessions.
+func (h *SessionHandler) List(c *gin.Context) {
+	sessions, err := h.sessionService.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	currentID := currentSessionID(c)
+	items := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		items = append(items, SessionResponse{
+			ID:         string(s.ID),
+			DeviceID:   s.DeviceID,
+			UserAgent:  s.UserAgent,
+			IPAddress:  s.IPAddress,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			Current:    s.ID == currentID,
+		})
+	}
+
+	response.Data(c, http.StatusOK, gin.H{"sessions": items})
+}
+
+// Delete handles the HTTP request for signing out one of the current user's sessions.
+func (h *SessionHandler) Delete(c *gin.Context) {
+	sessionID := session.SessionID(c.Param("id"))
+	err := h.sessionService.Revoke(c.Request.Context(), currentUserID(c), sessionID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutRequest defines the optional request body for logging out.
+type LogoutRequest struct {
+	Everywhere bool `json:"everywhere"` // Sign out of all sessions, not just the current one
+}
+
+// Logout handles the HTTP request for signing out the current session, or all sessions.
+func (h *SessionHandler) Logout(c *gin.Context) {
+	var req LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, response.APIError{
+				Code:    "INVALID_REQUEST_BODY",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	var err error
+	if req.Everywhere {
+		err = h.sessionService.RevokeAll(c.Request.Context(), currentUserID(c))
+	} else {
+		err = h.sessionService.Revoke(c.Request.Context(), currentUserID(c), currentSessionID(c))
+	}
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SessionHandler) handleError(c *gin.Context, err error) {
+	logger, _ := c.Get(middleware.LoggerKey)
+	requestLogger, ok := logger.(*slog.Logger)
+	if !ok {
+		requestLogger = slog.Default()
+	}
+
+	switch err {
+	case sessionService.ErrSessionNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "SESSION_NOT_FOUND",
+			Message: "The session does not exist.",
+		})
+	default:
+		requestLogger.Error("Unhandled API error", "error", err)
+		response.Error(c, http.StatusInternalServerError, response.APIError{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: "An unexpected error occurred on our end.",
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	userService "github.com/moriverse/45-server/internal/app/user"
+	"github.com/moriverse/45-server/internal/domain/user"
+	"github.com/moriverse/45-server/internal/infrastructure/web/middleware"
+	"github.com/moriverse/45-server/internal/infrastructure/web/response"
+)
+
+// UserHandler handles HTTP requests for the current user's profile.
+type UserHandler struct {
+	userService *userService.Service
+}
+
+// NewUserHandler creates a new instance of UserHandler.
+func NewUserHandler(userService *userService.Service) *UserHandler {
+	return &UserHandler{userService: userService}
+}
+
+// UserResponse is the public representation of a user. Internal fields, such as the source the
+// user signed up from or when they were last active, are left out.
+type UserResponse struct {
+	ID          string    `json:"id"`
+	PhoneNumber string    `json:"phone_number,omitempty"`
+	Nickname    string    `json:"nickname"`
+	AvatarURL   string    `json:"avatar_url"`
+	Bio         string    `json:"bio"`
+	Gender      string    `json:"gender,omitempty"`
+	Birthday    string    `json:"birthday,omitempty"` // yyyy-mm-dd
+	Locale      string    `json:"locale,omitempty"`
+	IsGuest     bool      `json:"is_guest"`
+	Onboarded   bool      `json:"onboarded"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func toUserResponse(u *user.User) UserResponse {
+	resp := UserResponse{
+		ID:          string(u.ID),
+		PhoneNumber: u.PhoneNumber,
+		Nickname:    u.Nickname,
+		AvatarURL:   u.AvatarURL,
+		Bio:         u.Bio,
+		Gender:      string(u.Gender),
+		Locale:      u.Locale,
+		IsGuest:     u.IsGuest,
+		Onboarded:   u.OnboardedAt != nil,
+		CreatedAt:   u.CreatedAt,
+	}
+	if u.Birthday != nil {
+		resp.Birthday = u.Birthday.Format(time.DateOnly)
+	}
+	return resp
+}
+
+// Get handles the HTTP request for reading the current user's profile.
+func (h *UserHandler) Get(c *gin.Context) {
+	u, err := h.userService.GetProfile(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Data(c, http.StatusOK, gin.H{"user": toUserResponse(u)})
+}
+
+// UpdateProfileRequest defines the request body for changing the current user's profile.
+// Omitted fields are left as they are; empty strings clear every field but the nickname.
+type UpdateProfileRequest struct {
+	Nickname  *string `json:"nickname"`
+	AvatarURL *string `json:"avatar_url"`
+	Bio       *string `json:"bio"`
+	Gender    *string `json:"gender"`
+	Birthday  *string `json:"birthday"` // yyyy-mm-dd
+	Locale    *string `json:"locale"`   // A BCP 47 language tag, such as "zh-CN"
+}
+
+// Update handles the HTTP request for changing the current user's profile.
+func (h *UserHandler) Update(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_REQUEST_BODY",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	params := userService.UpdateProfileParams{
+		UserID:    currentUserID(c),
+		Nickname:  req.Nickname,
+		AvatarURL: req.AvatarURL,
+		Bio:       req.Bio,
+		Birthday:  req.Birthday,
+		Locale:    req.Locale,
+	}
+	if req.Gender != nil {
+		gender := user.Gender(*req.Gender)
+		params.Gender = &gender
+	}
+
+	u, err := h.userService.UpdateProfile(c.Request.Context(), params)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Data(c, http.StatusOK, gin.H{"user": toUserResponse(u)})
+}
+
+func (h *UserHandler) handleError(c *gin.Context, err error) {
+	logger, _ := c.Get(middleware.LoggerKey)
+	requestLogger, ok := logger.(*slog.Logger)
+	if !ok {
+		requestLogger = slog.Default()
+	}
+
+	switch err {
+	case userService.ErrUserNotFound:
+		response.Error(c, http.StatusNotFound, response.APIError{
+			Code:    "USER_NOT_FOUND",
+			Message: "The user does not exist.",
+		})
+	case userService.ErrInvalidNickname:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_NICKNAME",
+			Message: "Nicknames must be 1 to 32 characters long.",
+		})
+	case userService.ErrInvalidAvatarURL:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_AVATAR_URL",
+			Message: "The avatar URL must be an http or https URL.",
+		})
+	case userService.ErrInvalidBio:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_BIO",
+			Message: "The bio can be at most 160 characters long.",
+		})
+	case userService.ErrInvalidGender:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_GENDER",
+			Message: "Gender must be male, female, other or empty.",
+		})
+	case userService.ErrInvalidBirthday:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_BIRTHDAY",
+			Message: "The birthday must be a past date in the format yyyy-mm-dd.",
+		})
+	case userService.ErrInvalidLocale:
+		response.Error(c, http.StatusBadRequest, response.APIError{
+			Code:    "INVALID_LOCALE",
+			Message: "The locale must be a language tag such as zh-CN.",
+		})
+	default:
+		requestLogger.Error("Unhandled API error", "error", err)
+		response.Error(c, http.StatusInternalServerError, response.APIError{
+			Code:    "INTERNAL_SERVER_ERROR",
+			Message: "An unexpected error occurred on our end.",
+		})
+	}
+}
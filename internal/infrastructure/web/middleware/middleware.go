@@ -8,33 +8,63 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	appAPIKey "github.com/moriverse/45-server/internal/app/apikey"
+	appRBAC "github.com/moriverse/45-server/internal/app/rbac"
+	appSession "github.com/moriverse/45-server/internal/app/session"
 	appUser "github.com/moriverse/45-server/internal/app/user"
+	"github.com/moriverse/45-server/internal/domain/apikey"
+	"github.com/moriverse/45-server/internal/domain/oauth"
+	"github.com/moriverse/45-server/internal/domain/rbac"
+	"github.com/moriverse/45-server/internal/domain/session"
 	"github.com/moriverse/45-server/internal/domain/user"
-	"github.com/moriverse/45-server/internal/infrastructure/config"
+	"github.com/moriverse/45-server/internal/infrastructure/web/response"
 	"github.com/moriverse/45-server/internal/utils"
 )
 
 const (
-	LoggerKey = "logger"
+	LoggerKey    = "logger"
+	UserIDKey    = "userID"
+	SessionIDKey = "sessionID"
+	GuestKey     = "guest"
+	// RealUserIDKey holds the user who is actually making the request. It differs from
+	// UserIDKey only while a staff member impersonates a user.
+	RealUserIDKey = "realUserID"
+	// ServicePrincipalKey holds the API key of a server-to-server client.
+	ServicePrincipalKey = "servicePrincipal"
+	// OAuthClientIDKey and OAuthScopesKey are set for tokens issued to third-party OAuth
+	// clients.
+	OAuthClientIDKey = "oauthClientID"
+	OAuthScopesKey   = "oauthScopes"
+	// AuthTimeKey holds when the user last proved their identity on the session, if known.
+	AuthTimeKey = "authTime"
 )
 
 // Middleware encapsulates all middleware logic and dependencies.
 type Middleware struct {
-	userService *appUser.Service
-	jwtConfig   config.JWTConfig
-	logger      *slog.Logger
+	userService    *appUser.Service
+	sessionService *appSession.Service
+	rbacService    *appRBAC.Service
+	apiKeyService  *appAPIKey.Service
+	keys           *utils.KeySet
+	logger         *slog.Logger
 }
 
 // NewMiddleware creates a new Middleware instance.
 func NewMiddleware(
 	userService *appUser.Service,
-	jwtConfig config.JWTConfig,
+	sessionService *appSession.Service,
+	rbacService *appRBAC.Service,
+	apiKeyService *appAPIKey.Service,
+	keys *utils.KeySet,
 	logger *slog.Logger,
 ) *Middleware {
 	return &Middleware{
-		userService: userService,
-		jwtConfig:   jwtConfig,
-		logger:      logger,
+		userService:    userService,
+		sessionService: sessionService,
+		rbacService:    rbacService,
+		apiKeyService:  apiKeyService,
+		keys:           keys,
+		logger:         logger,
 	}
 }
 
@@ -77,8 +107,21 @@ func (m *Middleware) LoggingMiddleware() gin.HandlerFunc {
 	}
 }
 
-// AuthMiddleware is a Gin middleware for JWT authentication.
+// AuthMiddleware is a Gin middleware for JWT authentication. Tokens issued to third-party
+// OAuth clients are rejected; routes they may call use ScopedAuthMiddleware instead.
 func (m *Middleware) AuthMiddleware() gin.HandlerFunc {
+	return m.authenticate("")
+}
+
+// ScopedAuthMiddleware is AuthMiddleware for routes that third-party OAuth clients may also
+// call. It accepts first-party tokens, and client tokens that were granted the scope.
+func (m *Middleware) ScopedAuthMiddleware(scope oauth.Scope) gin.HandlerFunc {
+	return m.authenticate(scope)
+}
+
+// authenticate validates the bearer token. Tokens of third-party OAuth clients are only
+// accepted if they carry the scope, and never if it is empty.
+func (m *Middleware) authenticate(scope oauth.Scope) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -99,7 +142,7 @@ func (m *Middleware) AuthMiddleware() gin.HandlerFunc {
 		}
 
 		tokenString := parts[1]
-		claims, err := utils.ValidateToken(tokenString, m.jwtConfig.SecretKey)
+		claims, err := utils.ValidateToken(tokenString, m.keys)
 		if err != nil {
 			c.AbortWithStatusJSON(
 				http.StatusUnauthorized,
@@ -108,13 +151,216 @@ func (m *Middleware) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Set user ID in context for downstream handlers
-		c.Set("userID", claims.Subject)
+		var scopes []oauth.Scope
+		if claims.ClientID != "" {
+			scopes = oauth.ParseScopes(claims.Scope)
+			if scope == "" || !oauth.ContainsAll(scopes, []oauth.Scope{scope}) {
+				response.Error(c, http.StatusForbidden, response.APIError{
+					Code:    "INSUFFICIENT_SCOPE",
+					Message: "The access token does not grant access to this API.",
+				})
+				c.Abort()
+				return
+			}
+		}
 
-		// Update user's last active time
-		userID := user.UserID(claims.Subject)
-		m.userService.UpdateLastActive(c.Request.Context(), userID)
+		// Reject tokens whose session or OAuth grant has been revoked
+		sessionID := session.SessionID(claims.ID)
+		revoked, err := m.sessionService.IsRevoked(c.Request.Context(), sessionID)
+		if err != nil {
+			m.logger.Error(
+				"Failed to check session revocation",
+				"session_id", sessionID,
+				"error", err,
+			)
+			c.AbortWithStatusJSON(
+				http.StatusInternalServerError,
+				gin.H{"error": "Failed to verify session"},
+			)
+			return
+		}
+		if revoked {
+			c.AbortWithStatusJSON(
+				http.StatusUnauthorized,
+				gin.H{"error": "Session has been signed out"},
+			)
+			return
+		}
+
+		// Set user and session IDs in context for downstream handlers
+		c.Set(UserIDKey, claims.Subject)
+		c.Set(SessionIDKey, claims.ID)
+		c.Set(GuestKey, claims.Guest)
+		c.Set(RealUserIDKey, claims.Subject)
+		if claims.AuthTime != nil {
+			c.Set(AuthTimeKey, claims.AuthTime.Time)
+		}
+		if claims.ClientID != "" {
+			c.Set(OAuthClientIDKey, claims.ClientID)
+			c.Set(OAuthScopesKey, scopes)
+		}
 
+		if claims.Act != nil {
+			// Every request made while impersonating is logged with the staff member.
+			c.Set(RealUserIDKey, claims.Act.Subject)
+			logger, _ := c.Get(LoggerKey)
+			if requestLogger, ok := logger.(*slog.Logger); ok {
+				c.Set(LoggerKey, requestLogger.With(
+					"impersonation_id", claims.ID,
+					"actor_id", claims.Act.Subject,
+				))
+			}
+		} else {
+			// Update user's last active time, which impersonation must not affect
+			userID := user.UserID(claims.Subject)
+			m.userService.UpdateLastActive(c.Request.Context(), userID)
+		}
+
+		c.Next()
+	}
+}
+
+// RequireFullAccount rejects guests, who must sign in with a real login method first. It must
+// run after AuthMiddleware.
+func (m *Middleware) RequireFullAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool(GuestKey) {
+			response.Error(c, http.StatusForbidden, response.APIError{
+				Code:    "GUEST_NOT_ALLOWED",
+				Message: "Sign in to use this feature.",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission rejects users whose roles do not grant the permission, and service
+// principals whose API key lacks it as a scope. It must run after AuthMiddleware or
+// APIKeyMiddleware.
+func (m *Middleware) RequirePermission(permission rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal, ok := c.Get(ServicePrincipalKey); ok {
+			if key, ok := principal.(*apikey.APIKey); ok && key.HasScope(permission) {
+				c.Next()
+				return
+			}
+			response.Error(c, http.StatusForbidden, response.APIError{
+				Code:    "PERMISSION_DENIED",
+				Message: "The API key does not have the scope to do this.",
+			})
+			c.Abort()
+			return
+		}
+
+		userID := user.UserID(c.GetString(UserIDKey))
+		allowed, err := m.rbacService.HasPermission(c.Request.Context(), userID, permission)
+		if err != nil {
+			m.logger.Error(
+				"Failed to check permission",
+				"user_id", userID,
+				"permission", permission,
+				"error", err,
+			)
+			response.Error(c, http.StatusInternalServerError, response.APIError{
+				Code:    "INTERNAL_SERVER_ERROR",
+				Message: "An unexpected error occurred on our end.",
+			})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			response.Error(c, http.StatusForbidden, response.APIError{
+				Code:    "PERMISSION_DENIED",
+				Message: "You do not have permission to do this.",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireNoImpersonation rejects requests made while a staff member impersonates a user, for
+// actions that only the user themselves may take. It must run after AuthMiddleware.
+func (m *Middleware) RequireNoImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(RealUserIDKey) != c.GetString(UserIDKey) {
+			response.Error(c, http.StatusForbidden, response.APIError{
+				Code:    "IMPERSONATION_NOT_ALLOWED",
+				Message: "This action is not available while impersonating a user.",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRecentAuth rejects users who have not proved their identity within maxAge, for
+// sensitive actions such as changing how they sign in. Clients should re-authenticate the user
+// and retry with the new access token. Guests are let through, since they have no login method
+// to prove again; this is how they link one. It must run after AuthMiddleware.
+func (m *Middleware) RequireRecentAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool(GuestKey) {
+			c.Next()
+			return
+		}
+		authTime := c.GetTime(AuthTimeKey)
+		if authTime.IsZero() || time.Since(authTime) > maxAge {
+			response.Error(c, http.StatusUnauthorized, response.APIError{
+				Code:    "REAUTH_REQUIRED",
+				Message: "Sign in again to continue.",
+				Details: gin.H{"max_age": int(maxAge.Seconds())},
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// APIKeyMiddleware authenticates server-to-server clients by an "Authorization: ApiKey <key>"
+// header, in place of AuthMiddleware. The key is stored in the context as the service
+// principal; there is no current user.
+func (m *Middleware) APIKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, key, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !ok || scheme != "ApiKey" || key == "" {
+			response.Error(c, http.StatusUnauthorized, response.APIError{
+				Code:    "INVALID_API_KEY",
+				Message: "Authorization header format is ApiKey {key}.",
+			})
+			c.Abort()
+			return
+		}
+
+		principal, err := m.apiKeyService.Authenticate(c.Request.Context(), key)
+		if err == appAPIKey.ErrInvalidAPIKey {
+			response.Error(c, http.StatusUnauthorized, response.APIError{
+				Code:    "INVALID_API_KEY",
+				Message: "The API key is invalid, expired or revoked.",
+			})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			m.logger.Error("Failed to authenticate api key", "error", err)
+			response.Error(c, http.StatusInternalServerError, response.APIError{
+				Code:    "INTERNAL_SERVER_ERROR",
+				Message: "An unexpected error occurred on our end.",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set(ServicePrincipalKey, principal)
+		logger, _ := c.Get(LoggerKey)
+		if requestLogger, ok := logger.(*slog.Logger); ok {
+			c.Set(LoggerKey, requestLogger.With("api_key_id", principal.ID))
+		}
 		c.Next()
 	}
 }
@@ -4,8 +4,9 @@ import "github.com/gin-gonic/gin"
 
 // APIError defines the structure for a standard API error response.
 type APIError struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"` // Extra data the client needs to recover
 }
 
 // Data sends a standard success response with a JSON payload.
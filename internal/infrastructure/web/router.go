@@ -1,15 +1,39 @@
 package web
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/moriverse/45-server/internal/domain/oauth"
+	"github.com/moriverse/45-server/internal/domain/rbac"
+	"github.com/moriverse/45-server/internal/infrastructure/blobstore"
 	"github.com/moriverse/45-server/internal/infrastructure/config"
 	"github.com/moriverse/45-server/internal/infrastructure/web/handler"
 	"github.com/moriverse/45-server/internal/infrastructure/web/middleware"
 )
 
-func NewRouter(authHandler *handler.AuthHandler, mw *middleware.Middleware, cfg config.Config) *gin.Engine {
+func NewRouter(
+	authHandler *handler.AuthHandler,
+	sessionHandler *handler.SessionHandler,
+	jwksHandler *handler.JWKSHandler,
+	roleHandler *handler.RoleHandler,
+	apiKeyHandler *handler.APIKeyHandler,
+	oauthHandler *handler.OAuthHandler,
+	loginHistoryHandler *handler.LoginHistoryHandler,
+	userHandler *handler.UserHandler,
+	avatarHandler *handler.AvatarHandler,
+	blobServer blobstore.Server,
+	mw *middleware.Middleware,
+	cfg config.Config,
+) (*gin.Engine, error) {
 	router := gin.Default()
+	// Only believe X-Forwarded-For from our own proxies, since the client IP is what failed
+	// logins are counted against.
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
 	// Middlewares
 	router.Use(mw.LoggingMiddleware())
@@ -18,19 +42,124 @@ func NewRouter(authHandler *handler.AuthHandler, mw *middleware.Middleware, cfg
 	router.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "pong"})
 	})
+	router.GET("/.well-known/jwks.json", jwksHandler.Get)
+	// Avatar links redirect to a signed URL, and carry no credentials so images can embed them.
+	router.GET("/avatars/:user_id/:avatar_id", avatarHandler.Get)
+	if blobServer != nil {
+		// The local blob store serves the signed URLs it hands out.
+		router.GET(blobServer.MountPath()+"/*key", gin.WrapH(blobServer))
+	}
 
 	// Auth routes
 	authRoutes := router.Group("/auth")
 	{
 		authRoutes.POST("/login", authHandler.Login)
+		authRoutes.POST("/register", authHandler.Register)
+		authRoutes.POST("/sms/send", authHandler.SendSMSCode)
+		authRoutes.POST("/refresh", authHandler.Refresh)
+		authRoutes.POST("/apple/notifications", authHandler.AppleNotification)
+		authRoutes.POST("/password/forgot", authHandler.ForgotPassword)
+		authRoutes.POST("/password/reset", authHandler.ResetPassword)
+		authRoutes.POST("/email/verify", authHandler.VerifyEmail)
+		authRoutes.POST("/mfa/verify", authHandler.VerifyMFA)
+		authRoutes.POST(
+			"/logout",
+			mw.AuthMiddleware(),
+			mw.RequireNoImpersonation(),
+			sessionHandler.Logout,
+		)
+	}
+
+	// OAuth routes called by third-party clients, which authenticate with their own credentials
+	oauthRoutes := router.Group("/oauth")
+	{
+		oauthRoutes.POST("/token", oauthHandler.Token)
+		oauthRoutes.POST("/introspect", oauthHandler.Introspect)
+		oauthRoutes.POST("/revoke", oauthHandler.Revoke)
+		oauthRoutes.GET(
+			"/userinfo",
+			mw.ScopedAuthMiddleware(oauth.ScopeProfile),
+			oauthHandler.UserInfo,
+		)
 	}
 
 	// Private route group
 	v1 := router.Group("/api/v1")
 	v1.Use(mw.AuthMiddleware())
 	{
-		// User routes will be added here
+		v1.GET("/me", userHandler.Get)
+		v1.GET("/sessions", sessionHandler.List)
+		v1.GET("/me/identities", authHandler.ListIdentities)
+		v1.GET("/me/logins", loginHistoryHandler.List)
+	}
+
+	// Private routes that staff impersonating the user cannot use
+	own := v1.Group("", mw.RequireNoImpersonation())
+	recentAuth := mw.RequireRecentAuth(
+		time.Duration(cfg.JWT.ReauthMaxAgeMinutes) * time.Minute,
+	)
+	{
+		own.PATCH("/me", userHandler.Update)
+		own.DELETE("/me", recentAuth, authHandler.DeleteAccount)
+		own.PUT("/me/avatar", avatarHandler.Upload)
+		own.DELETE("/me/avatar", avatarHandler.Delete)
+		own.DELETE("/sessions/:id", sessionHandler.Delete)
+		own.POST("/me/reauth", authHandler.Reauthenticate)
+		// Linking a real login method is also how guests upgrade their account.
+		own.POST("/me/identities", recentAuth, authHandler.LinkIdentity)
+		own.DELETE("/me/identities/:provider", recentAuth, authHandler.UnlinkIdentity)
+		own.POST("/me/merge", recentAuth, authHandler.ConfirmMerge)
+	}
+
+	// Private routes guests cannot use until they sign in
+	account := own.Group("", mw.RequireFullAccount())
+	{
+		account.POST("/me/phone/wechat", recentAuth, authHandler.BindWechatPhoneNumber)
+		account.POST("/me/email/verification", authHandler.SendEmailVerification)
+		account.POST("/me/mfa/totp", recentAuth, authHandler.BeginTOTPEnrollment)
+		account.POST("/me/mfa/totp/confirm", authHandler.ConfirmTOTPEnrollment)
+		account.DELETE("/me/mfa/totp", recentAuth, authHandler.DisableTOTP)
+		account.POST("/me/mfa/recovery-codes", recentAuth, authHandler.RegenerateRecoveryCodes)
+		// The consent screen of "Log in with 45"
+		account.GET("/oauth/authorize", oauthHandler.GetAuthorization)
+		account.POST("/oauth/authorize", oauthHandler.Authorize)
+		account.GET("/me/authorized-apps", oauthHandler.ListAuthorizedApps)
+		account.DELETE("/me/authorized-apps/:id", oauthHandler.RevokeAuthorizedApp)
+	}
+
+	// Admin routes, each guarded by the permission it needs
+	admin := own.Group("/admin")
+	{
+		manageRoles := mw.RequirePermission(rbac.RolesManage)
+		admin.GET("/roles", manageRoles, roleHandler.List)
+		admin.GET("/users/:id/roles", manageRoles, roleHandler.ListUserRoles)
+		admin.POST("/users/:id/roles", manageRoles, roleHandler.AssignRole)
+		admin.DELETE("/users/:id/roles/:role", manageRoles, roleHandler.RevokeRole)
+		admin.POST(
+			"/users/:id/impersonate",
+			mw.RequirePermission(rbac.UsersImpersonate),
+			authHandler.Impersonate,
+		)
+		manageAPIKeys := mw.RequirePermission(rbac.APIKeysManage)
+		admin.GET("/api-keys", manageAPIKeys, apiKeyHandler.List)
+		admin.POST("/api-keys", manageAPIKeys, apiKeyHandler.Create)
+		admin.DELETE("/api-keys/:id", manageAPIKeys, apiKeyHandler.Revoke)
+		manageOAuthClients := mw.RequirePermission(rbac.OAuthClientsManage)
+		admin.GET("/oauth-clients", manageOAuthClients, oauthHandler.ListClients)
+		admin.POST("/oauth-clients", manageOAuthClients, oauthHandler.CreateClient)
+		admin.DELETE("/oauth-clients/:id", manageOAuthClients, oauthHandler.RevokeClient)
+	}
+
+	// Server-to-server routes, for internal services and partners with an API key
+	service := router.Group("/service/v1")
+	service.Use(mw.APIKeyMiddleware())
+	{
+		service.GET(
+			"/users/:id/roles",
+			mw.RequirePermission(rbac.UsersRead),
+			roleHandler.ListUserRoles,
+		)
 	}
 
-	return router
+	return router, nil
 }
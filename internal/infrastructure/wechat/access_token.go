@@ -0,0 +1,62 @@
+package wechat
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// accessTokenCache holds the mini program's client credential access token. WeChat limits how
+// often a token can be requested, so it is reused until shortly before it expires.
+type accessTokenCache struct {
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+type accessTokenResponse struct {
+	errorResponse
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// accessToken returns a valid access token for the mini program, fetching a new one if needed.
+func (c *APIClient) accessToken(ctx context.Context) (string, error) {
+	c.tokenCache.mu.Lock()
+	defer c.tokenCache.mu.Unlock()
+
+	if c.tokenCache.token != "" && time.Now().Before(c.tokenCache.expiresAt) {
+		return c.tokenCache.token, nil
+	}
+
+	app := c.apps[MiniProgram]
+	if app.AppID == "" {
+		return "", ErrPlatformNotConfigured
+	}
+
+	var resp accessTokenResponse
+	if err := c.get(ctx, "/cgi-bin/token", url.Values{
+		"grant_type": {"client_credential"},
+		"appid":      {app.AppID},
+		"secret":     {app.AppSecret},
+	}, &resp); err != nil {
+		return "", err
+	}
+	if err := resp.err(); err != nil {
+		return "", err
+	}
+
+	// Refresh a few minutes early so a token never expires while a request is in flight.
+	ttl := time.Duration(resp.ExpiresIn) * time.Second
+	c.tokenCache.token = resp.AccessToken
+	c.tokenCache.expiresAt = time.Now().Add(ttl - 5*time.Minute)
+	return c.tokenCache.token, nil
+}
+
+// invalidate discards the cached token, for example after WeChat reports it as invalid.
+func (t *accessTokenCache) invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.token = ""
+}
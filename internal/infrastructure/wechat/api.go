@@ -0,0 +1,232 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+const defaultBaseURL = "https://api.weixin.qq.com"
+
+// APIClient is a Client that talks to the WeChat API.
+type APIClient struct {
+	baseURL    string
+	apps       map[Platform]config.WechatAppConfig
+	httpClient *http.Client
+	tokenCache accessTokenCache
+}
+
+// NewAPIClient creates a new APIClient.
+func NewAPIClient(cfg config.WechatConfig) *APIClient {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	return &APIClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apps: map[Platform]config.WechatAppConfig{
+			MiniProgram: cfg.MiniProgram,
+			MobileApp:   cfg.MobileApp,
+			Website:     cfg.Website,
+		},
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// errorResponse holds the error fields that every WeChat API response may carry.
+type errorResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (r errorResponse) err() error {
+	if r.ErrCode == 0 {
+		return nil
+	}
+	return &APIError{Code: r.ErrCode, Message: r.ErrMsg}
+}
+
+type code2SessionResponse struct {
+	errorResponse
+	OpenID     string `json:"openid"`
+	UnionID    string `json:"unionid"`
+	SessionKey string `json:"session_key"`
+}
+
+type oauthAccessTokenResponse struct {
+	errorResponse
+	AccessToken string `json:"access_token"`
+	OpenID      string `json:"openid"`
+	UnionID     string `json:"unionid"`
+}
+
+// ExchangeCode exchanges a login code with WeChat. Mini program codes go through
+// jscode2session; app and website codes go through the OAuth access token endpoint.
+func (c *APIClient) ExchangeCode(
+	ctx context.Context,
+	platform Platform,
+	code string,
+) (*Identity, error) {
+	if code == "" {
+		return nil, ErrInvalidCode
+	}
+	app, ok := c.apps[platform]
+	if !ok || app.AppID == "" {
+		return nil, ErrPlatformNotConfigured
+	}
+
+	if platform == MiniProgram {
+		var resp code2SessionResponse
+		if err := c.get(ctx, "/sns/jscode2session", url.Values{
+			"appid":      {app.AppID},
+			"secret":     {app.AppSecret},
+			"js_code":    {code},
+			"grant_type": {"authorization_code"},
+		}, &resp); err != nil {
+			return nil, err
+		}
+		if err := resp.err(); err != nil {
+			return nil, err
+		}
+		if resp.OpenID == "" {
+			return nil, ErrMissingOpenID
+		}
+		return &Identity{
+			OpenID:     resp.OpenID,
+			UnionID:    resp.UnionID,
+			SessionKey: resp.SessionKey,
+		}, nil
+	}
+
+	var resp oauthAccessTokenResponse
+	if err := c.get(ctx, "/sns/oauth2/access_token", url.Values{
+		"appid":      {app.AppID},
+		"secret":     {app.AppSecret},
+		"code":       {code},
+		"grant_type": {"authorization_code"},
+	}, &resp); err != nil {
+		return nil, err
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+	if resp.OpenID == "" {
+		return nil, ErrMissingOpenID
+	}
+	return &Identity{OpenID: resp.OpenID, UnionID: resp.UnionID}, nil
+}
+
+// DecryptPhoneNumber decrypts the phone number and checks that it was encrypted for our mini
+// program.
+func (c *APIClient) DecryptPhoneNumber(sessionKey, encryptedData, iv string) (*PhoneInfo, error) {
+	info, err := decryptPhoneNumber(sessionKey, encryptedData, iv)
+	if err != nil {
+		return nil, err
+	}
+	if info.Watermark.AppID != c.apps[MiniProgram].AppID {
+		return nil, ErrInvalidEncryptedData
+	}
+	return info, nil
+}
+
+type getPhoneNumberResponse struct {
+	errorResponse
+	PhoneInfo PhoneInfo `json:"phone_info"`
+}
+
+// GetPhoneNumber exchanges a phone code through the getuserphonenumber endpoint.
+func (c *APIClient) GetPhoneNumber(ctx context.Context, code string) (*PhoneInfo, error) {
+	if code == "" {
+		return nil, ErrInvalidCode
+	}
+	accessToken, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp getPhoneNumberResponse
+	if err := c.post(
+		ctx,
+		"/wxa/business/getuserphonenumber",
+		url.Values{"access_token": {accessToken}},
+		map[string]string{"code": code},
+		&resp,
+	); err != nil {
+		return nil, err
+	}
+	if err := resp.err(); err != nil {
+		if errors.Is(err, ErrInvalidCredential) {
+			c.tokenCache.invalidate()
+		}
+		return nil, err
+	}
+	return &resp.PhoneInfo, nil
+}
+
+// get calls a WeChat API endpoint and decodes its JSON response into out.
+func (c *APIClient) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		c.baseURL+path+"?"+query.Encode(),
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	return c.do(req, out)
+}
+
+// post calls a WeChat API endpoint with a JSON body and decodes its JSON response into out.
+func (c *APIClient) post(
+	ctx context.Context,
+	path string,
+	query url.Values,
+	body interface{},
+	out interface{},
+) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		c.baseURL+path+"?"+query.Encode(),
+		bytes.NewReader(payload),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req, out)
+}
+
+func (c *APIClient) do(req *http.Request, out interface{}) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call wechat api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("wechat api returned status %d", resp.StatusCode)
+	}
+	// WeChat often responds with a text/plain content type, so the body is decoded regardless.
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode wechat api response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package wechat_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+	"github.com/moriverse/45-server/internal/infrastructure/wechat"
+	"github.com/moriverse/45-server/internal/infrastructure/wechat/wechattest"
+)
+
+const miniProgramAppID = "wx_mini_program"
+
+func newTestClient(t *testing.T) (*wechat.APIClient, *wechattest.Server) {
+	t.Helper()
+	server := wechattest.NewServer()
+	t.Cleanup(server.Close)
+	client := wechat.NewAPIClient(config.WechatConfig{
+		BaseURL:     server.URL,
+		MiniProgram: config.WechatAppConfig{AppID: miniProgramAppID, AppSecret: "secret"},
+		MobileApp:   config.WechatAppConfig{AppID: "wx_mobile_app", AppSecret: "secret"},
+	})
+	return client, server
+}
+
+func TestExchangeCode(t *testing.T) {
+	client, server := newTestClient(t)
+	server.AddCode("mini", wechat.Identity{
+		OpenID:     "openid-mini",
+		UnionID:    "unionid",
+		SessionKey: "session-key",
+	})
+	server.AddCode("app", wechat.Identity{OpenID: "openid-app", UnionID: "unionid"})
+
+	tests := []struct {
+		name     string
+		platform wechat.Platform
+		code     string
+		want     wechat.Identity
+	}{
+		{
+			name:     "mini program",
+			platform: wechat.MiniProgram,
+			code:     "mini",
+			want: wechat.Identity{
+				OpenID:     "openid-mini",
+				UnionID:    "unionid",
+				SessionKey: "session-key",
+			},
+		},
+		{
+			name:     "mobile app",
+			platform: wechat.MobileApp,
+			code:     "app",
+			want:     wechat.Identity{OpenID: "openid-app", UnionID: "unionid"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.ExchangeCode(context.Background(), tt.platform, tt.code)
+			if err != nil {
+				t.Fatalf("ExchangeCode: %v", err)
+			}
+			if *got != tt.want {
+				t.Fatalf("ExchangeCode = %+v, want %+v", *got, tt.want)
+			}
+
+			// Codes can only be exchanged once.
+			_, err = client.ExchangeCode(context.Background(), tt.platform, tt.code)
+			if !errors.Is(err, wechat.ErrCodeUsed) {
+				t.Fatalf("second ExchangeCode error = %v, want ErrCodeUsed", err)
+			}
+		})
+	}
+}
+
+func TestExchangeCodeErrors(t *testing.T) {
+	client, server := newTestClient(t)
+	server.AddCodeError("busy", wechat.ErrSystemBusy)
+	server.AddCodeError("quota", wechat.ErrRateLimited)
+	server.AddCodeError("risky", wechat.ErrHighRiskUser)
+	server.AddCodeError("other", &wechat.APIError{Code: 99999, Message: "something new"})
+	server.AddCode("no-openid", wechat.Identity{UnionID: "unionid"})
+
+	tests := []struct {
+		name     string
+		platform wechat.Platform
+		code     string
+		want     error
+	}{
+		{"empty code", wechat.MiniProgram, "", wechat.ErrInvalidCode},
+		{"unknown code", wechat.MiniProgram, "unknown", wechat.ErrInvalidCode},
+		{"system busy", wechat.MiniProgram, "busy", wechat.ErrSystemBusy},
+		{"rate limited", wechat.MobileApp, "quota", wechat.ErrRateLimited},
+		{"high risk user", wechat.MiniProgram, "risky", wechat.ErrHighRiskUser},
+		{"unknown errcode", wechat.MiniProgram, "other", &wechat.APIError{Code: 99999}},
+		{"no openid", wechat.MiniProgram, "no-openid", wechat.ErrMissingOpenID},
+		{"platform not configured", wechat.Website, "anything", wechat.ErrPlatformNotConfigured},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.ExchangeCode(context.Background(), tt.platform, tt.code)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ExchangeCode = %+v, %v; want error %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPhoneNumber(t *testing.T) {
+	client, server := newTestClient(t)
+	server.AddPhoneCode("phone", wechat.PhoneInfo{
+		PhoneNumber:     "13800138000",
+		PurePhoneNumber: "13800138000",
+		CountryCode:     "86",
+	})
+
+	info, err := client.GetPhoneNumber(context.Background(), "phone")
+	if err != nil {
+		t.Fatalf("GetPhoneNumber: %v", err)
+	}
+	if info.PurePhoneNumber != "13800138000" || info.CountryCode != "86" {
+		t.Fatalf("GetPhoneNumber = %+v", info)
+	}
+
+	if _, err := client.GetPhoneNumber(context.Background(), "unknown"); !errors.Is(
+		err,
+		wechat.ErrInvalidCode,
+	) {
+		t.Fatalf("GetPhoneNumber with an unknown code: error = %v, want ErrInvalidCode", err)
+	}
+}
+
+func TestDecryptPhoneNumber(t *testing.T) {
+	client, _ := newTestClient(t)
+	key := bytes.Repeat([]byte{1}, aes.BlockSize)
+	iv := bytes.Repeat([]byte{2}, aes.BlockSize)
+	sessionKey := base64.StdEncoding.EncodeToString(key)
+	encodedIV := base64.StdEncoding.EncodeToString(iv)
+
+	tests := []struct {
+		name    string
+		appID   string
+		wantErr error
+	}{
+		{"our mini program", miniProgramAppID, nil},
+		{"another app", "wx_someone_else", wechat.ErrInvalidEncryptedData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info wechat.PhoneInfo
+			info.PurePhoneNumber = "13800138000"
+			info.CountryCode = "86"
+			info.Watermark.AppID = tt.appID
+			encrypted := encrypt(t, key, iv, info)
+
+			got, err := client.DecryptPhoneNumber(sessionKey, encrypted, encodedIV)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DecryptPhoneNumber error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.PurePhoneNumber != info.PurePhoneNumber {
+				t.Fatalf("DecryptPhoneNumber = %+v", got)
+			}
+		})
+	}
+
+	wrongKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{3}, aes.BlockSize))
+	var info wechat.PhoneInfo
+	info.Watermark.AppID = miniProgramAppID
+	encrypted := encrypt(t, key, iv, info)
+	if _, err := client.DecryptPhoneNumber(wrongKey, encrypted, encodedIV); !errors.Is(
+		err,
+		wechat.ErrInvalidEncryptedData,
+	) {
+		t.Fatalf("DecryptPhoneNumber with the wrong key: error = %v", err)
+	}
+}
+
+// encrypt encrypts the phone info the way the mini program's getPhoneNumber does.
+func encrypt(t *testing.T, key, iv []byte, info wechat.PhoneInfo) string {
+	t.Helper()
+	plaintext, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := aes.BlockSize - len(plaintext)%aes.BlockSize
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(n)}, n)...)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+	return base64.StdEncoding.EncodeToString(ciphertext)
+}
@@ -3,26 +3,49 @@ package wechat
 import (
 	"context"
 	"fmt"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// Platform is the kind of WeChat app a login code was issued to. Each platform is a separate
+// WeChat app with its own AppID, so the same person has a different openid on each of them.
+type Platform string
+
+const (
+	MiniProgram Platform = "mini_program" // wx.login in the mini program
+	MobileApp   Platform = "mobile_app"   // OAuth through the WeChat SDK in our native apps
+	Website     Platform = "website"      // OAuth through the QR code login on the web
 )
 
-// Client simulates interactions with the WeChat API.
-type Client struct {
-	// In a real implementation, this would hold configuration
-	// like AppID and AppSecret.
+// Identity is the result of exchanging a login code with WeChat.
+type Identity struct {
+	OpenID string
+	// UnionID identifies the user across all apps bound to our Open Platform account. It may be
+	// empty if the app is not bound.
+	UnionID string
+	// SessionKey is used to decrypt data from the mini program. It is only set for MiniProgram.
+	SessionKey string
 }
 
-// NewClient creates a new mock WeChat client.
-func NewClient() *Client {
-	return &Client{}
+// Client exchanges WeChat login codes for user identities.
+type Client interface {
+	ExchangeCode(ctx context.Context, platform Platform, code string) (*Identity, error)
+	// DecryptPhoneNumber decrypts the phone number shared through the mini program's
+	// getPhoneNumber button, using the session key from the user's latest mini program login.
+	DecryptPhoneNumber(sessionKey, encryptedData, iv string) (*PhoneInfo, error)
+	// GetPhoneNumber exchanges the phone code returned by newer versions of getPhoneNumber.
+	GetPhoneNumber(ctx context.Context, code string) (*PhoneInfo, error)
 }
 
-// CodeToOpenID simulates exchanging a temporary code for a user's openid.
-// In a real application, this would make an HTTP request to the WeChat API.
-func (c *Client) CodeToOpenID(ctx context.Context, code string) (string, error) {
-	if code == "" {
-		return "", fmt.Errorf("wechat code cannot be empty")
+// NewClient creates the WeChat client selected by the configuration: "api" talks to the real
+// WeChat API, "mock" fabricates identities for local development.
+func NewClient(cfg config.WechatConfig) (Client, error) {
+	switch cfg.Driver {
+	case "api":
+		return NewAPIClient(cfg), nil
+	case "mock", "":
+		return NewMockClient(), nil
+	default:
+		return nil, fmt.Errorf("unknown wechat driver: %q", cfg.Driver)
 	}
-	// For simulation purposes, we just prepend a prefix to the code.
-	// A real openid is much longer and more complex.
-	return "mock_openid_for_" + code, nil
 }
@@ -0,0 +1,42 @@
+package wechat
+
+import (
+	"errors"
+	"fmt"
+)
+
+// APIError is an error code returned by the WeChat API.
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("wechat api error %d: %s", e.Code, e.Message)
+}
+
+// Is reports whether target is an APIError with the same code, so that errors.Is can match the
+// sentinel errors below regardless of the message.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrSystemBusy        = &APIError{Code: -1, Message: "system busy"}
+	ErrInvalidCredential = &APIError{Code: 40001, Message: "invalid app secret or access token"}
+	ErrInvalidAppID      = &APIError{Code: 40013, Message: "invalid appid"}
+	ErrInvalidCode       = &APIError{Code: 40029, Message: "invalid code"}
+	ErrCodeUsed          = &APIError{Code: 40163, Message: "code has been used"}
+	ErrRateLimited       = &APIError{Code: 45011, Message: "api minute-quota reached"}
+	ErrHighRiskUser      = &APIError{Code: 40226, Message: "high risk user"}
+)
+
+var (
+	// ErrPlatformNotConfigured is returned when no app credentials are configured for a
+	// platform.
+	ErrPlatformNotConfigured = errors.New("wechat platform is not configured")
+	// ErrMissingOpenID is returned when WeChat reports a successful code exchange without an
+	// openid, which must never become an identity.
+	ErrMissingOpenID = errors.New("wechat response has no openid")
+)
@@ -0,0 +1,60 @@
+package wechat
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+)
+
+// MockClient simulates interactions with the WeChat API for local development.
+type MockClient struct{}
+
+// NewMockClient creates a new mock WeChat client.
+func NewMockClient() *MockClient {
+	return &MockClient{}
+}
+
+// ExchangeCode simulates exchanging a temporary code for a user's identity. The same code always
+// yields the same identity.
+func (c *MockClient) ExchangeCode(
+	ctx context.Context,
+	platform Platform,
+	code string,
+) (*Identity, error) {
+	if code == "" {
+		return nil, fmt.Errorf("wechat code cannot be empty")
+	}
+	// For simulation purposes, we just prepend a prefix to the code.
+	// A real openid is much longer and more complex.
+	identity := &Identity{
+		OpenID:  "mock_openid_for_" + code,
+		UnionID: "mock_unionid_for_" + code,
+	}
+	if platform == MiniProgram {
+		// Session keys are base64-encoded 16-byte AES keys.
+		sum := md5.Sum([]byte(code))
+		identity.SessionKey = base64.StdEncoding.EncodeToString(sum[:])
+	}
+	return identity, nil
+}
+
+// DecryptPhoneNumber decrypts the phone number without checking which app it was encrypted for.
+func (c *MockClient) DecryptPhoneNumber(sessionKey, encryptedData, iv string) (*PhoneInfo, error) {
+	return decryptPhoneNumber(sessionKey, encryptedData, iv)
+}
+
+// GetPhoneNumber simulates exchanging a phone code. The same code always yields the same
+// mainland China mobile number.
+func (c *MockClient) GetPhoneNumber(ctx context.Context, code string) (*PhoneInfo, error) {
+	if code == "" {
+		return nil, fmt.Errorf("wechat phone code cannot be empty")
+	}
+	pure := fmt.Sprintf("138%08d", crc32.ChecksumIEEE([]byte(code))%100000000)
+	return &PhoneInfo{
+		PhoneNumber:     pure,
+		PurePhoneNumber: pure,
+		CountryCode:     "86",
+	}, nil
+}
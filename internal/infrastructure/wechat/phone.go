@@ -0,0 +1,72 @@
+package wechat
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidEncryptedData is returned when encrypted data from the mini program cannot be
+// decrypted with the session key, usually because the session key has been replaced by a newer
+// wx.login.
+var ErrInvalidEncryptedData = errors.New("invalid wechat encrypted data")
+
+// PhoneInfo is the phone number a user shared through the mini program's getPhoneNumber button.
+type PhoneInfo struct {
+	PhoneNumber     string `json:"phoneNumber"`     // Includes the country code for non-CN numbers
+	PurePhoneNumber string `json:"purePhoneNumber"` // Without the country code
+	CountryCode     string `json:"countryCode"`
+	Watermark       struct {
+		AppID     string `json:"appid"`
+		Timestamp int64  `json:"timestamp"`
+	} `json:"watermark"`
+}
+
+// decryptPhoneNumber decrypts the encryptedData returned by getPhoneNumber. WeChat encrypts it
+// with AES-128-CBC, using the session key as the key, and pads it with PKCS#7.
+func decryptPhoneNumber(sessionKey, encryptedData, iv string) (*PhoneInfo, error) {
+	key, err := base64.StdEncoding.DecodeString(sessionKey)
+	if err != nil || len(key) != aes.BlockSize {
+		return nil, ErrInvalidEncryptedData
+	}
+	ivBytes, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil || len(ivBytes) != aes.BlockSize {
+		return nil, ErrInvalidEncryptedData
+	}
+	data, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, ErrInvalidEncryptedData
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidEncryptedData
+	}
+	plaintext := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, ivBytes).CryptBlocks(plaintext, data)
+
+	plaintext, err = pkcs7Unpad(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	var info PhoneInfo
+	if err := json.Unmarshal(plaintext, &info); err != nil {
+		return nil, ErrInvalidEncryptedData
+	}
+	return &info, nil
+}
+
+func pkcs7Unpad(data []byte) ([]byte, error) {
+	n := int(data[len(data)-1])
+	if n == 0 || n > aes.BlockSize || n > len(data) {
+		return nil, ErrInvalidEncryptedData
+	}
+	if !bytes.Equal(data[len(data)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, ErrInvalidEncryptedData
+	}
+	return data[:len(data)-n], nil
+}
@@ -0,0 +1,141 @@
+// Package wechattest provides a local stand-in for the WeChat API, for use in tests and local
+// development with the "api" WeChat driver.
+package wechattest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/moriverse/45-server/internal/infrastructure/wechat"
+)
+
+const fakeAccessToken = "fake_client_credential_access_token"
+
+// Server is a fake WeChat API. Codes must be registered with AddCode before they can be
+// exchanged, and each code can be exchanged only once, as with the real API.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	codes      map[string]wechat.Identity
+	codeErrors map[string]*wechat.APIError
+	phoneCodes map[string]wechat.PhoneInfo
+	used       map[string]bool
+}
+
+// NewServer starts a new fake WeChat API. The caller must call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		codes:      make(map[string]wechat.Identity),
+		codeErrors: make(map[string]*wechat.APIError),
+		phoneCodes: make(map[string]wechat.PhoneInfo),
+		used:       make(map[string]bool),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sns/jscode2session", s.handleExchange("js_code"))
+	mux.HandleFunc("/sns/oauth2/access_token", s.handleExchange("code"))
+	mux.HandleFunc("/cgi-bin/token", s.handleAccessToken)
+	mux.HandleFunc("/wxa/business/getuserphonenumber", s.handleGetPhoneNumber)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// AddCode registers a login code that exchanges for the given identity.
+func (s *Server) AddCode(code string, identity wechat.Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[code] = identity
+}
+
+// AddCodeError registers a login code whose exchange fails with the given error.
+func (s *Server) AddCodeError(code string, err *wechat.APIError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codeErrors[code] = err
+}
+
+// AddPhoneCode registers a phone code that exchanges for the given phone number.
+func (s *Server) AddPhoneCode(code string, info wechat.PhoneInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.phoneCodes[code] = info
+}
+
+func (s *Server) handleAccessToken(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("appid") == "" {
+		writeError(w, wechat.ErrInvalidAppID)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"access_token": fakeAccessToken,
+		"expires_in":   7200,
+	})
+}
+
+func (s *Server) handleGetPhoneNumber(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("access_token") != fakeAccessToken {
+		writeError(w, wechat.ErrInvalidCredential)
+		return
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, wechat.ErrInvalidCode)
+		return
+	}
+
+	s.mu.Lock()
+	info, ok := s.phoneCodes[body.Code]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, wechat.ErrInvalidCode)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "phone_info": info})
+}
+
+func (s *Server) handleExchange(codeParam string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := r.URL.Query().Get(codeParam)
+
+		s.mu.Lock()
+		identity, ok := s.codes[code]
+		codeErr := s.codeErrors[code]
+		used := s.used[code]
+		s.used[code] = true
+		s.mu.Unlock()
+
+		switch {
+		case r.URL.Query().Get("appid") == "":
+			writeError(w, wechat.ErrInvalidAppID)
+		case codeErr != nil:
+			writeError(w, codeErr)
+		case !ok:
+			writeError(w, wechat.ErrInvalidCode)
+		case used:
+			writeError(w, wechat.ErrCodeUsed)
+		default:
+			writeJSON(w, map[string]interface{}{
+				"openid":       identity.OpenID,
+				"unionid":      identity.UnionID,
+				"session_key":  identity.SessionKey,
+				"access_token": "fake_access_token_for_" + code,
+				"expires_in":   7200,
+			})
+		}
+	}
+}
+
+func writeError(w http.ResponseWriter, err *wechat.APIError) {
+	writeJSON(w, map[string]interface{}{"errcode": err.Code, "errmsg": err.Message})
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	// The real API uses text/plain, which clients must tolerate.
+	w.Header().Set("Content-Type", "text/plain")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -2,41 +2,56 @@ package utils
 
 import (
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateToken(userID string, secretKey string, expiresInHours int) (string, error) {
-	claims := &jwt.RegisteredClaims{
-		Subject:   userID,
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expiresInHours) * time.Hour)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		Issuer:    "45ai",
-	}
+// Claims are the claims of an access token. The subject is the user ID and the token ID is the
+// session ID.
+type Claims struct {
+	jwt.RegisteredClaims
+	Guest bool `json:"guest,omitempty"` // The user has not signed in with a real login method
+	// Act is set when a staff member acts as the user (RFC 8693), and names the staff member.
+	Act *Actor `json:"act,omitempty"`
+	// ClientID and Scope are set on tokens issued to third-party OAuth clients, which may only
+	// use the APIs their scopes allow. The token ID is then the ID of the OAuth grant.
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// AuthTime and AMR record when and how the user last proved their identity on the session
+	// (OpenID Connect Core 1.0, section 2), so sensitive operations can demand a recent login.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	AMR      []string         `json:"amr,omitempty"`
+}
+
+// Actor is the party acting on behalf of the subject of a token.
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// GenerateToken signs an access token with the given claims, filling in its issuer and
+// lifetime.
+func GenerateToken(claims Claims, keys *KeySet, expiresIn time.Duration) (string, error) {
+	now := time.Now()
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(expiresIn))
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.Issuer = "45ai"
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secretKey))
+	return keys.sign(&claims)
 }
 
-func ValidateToken(tokenString string, secretKey string) (*jwt.RegisteredClaims, error) {
+func ValidateToken(tokenString string, keys *KeySet) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
-		&jwt.RegisteredClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(secretKey), nil
-		},
+		&Claims{},
+		keys.keyFunc,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
 		return claims, nil
 	}
 
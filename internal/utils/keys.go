@@ -0,0 +1,231 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// Key is a single JWT signing or verification key.
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey interface{} // nil for verification-only keys
+	PublicKey  interface{} // The HMAC secret for symmetric keys
+}
+
+// KeySet holds the key used to sign new tokens and every key that tokens may be verified with.
+// Keeping retired keys in the set lets tokens signed before a rotation stay valid until they
+// expire.
+type KeySet struct {
+	signing      *Key
+	verification map[string]*Key
+}
+
+// LoadKeySet builds a KeySet from the configuration, reading PEM files from disk. The legacy
+// secret key, if set, is accepted for tokens without a key ID and used for signing when no
+// signing key ID is configured.
+func LoadKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	ks := &KeySet{verification: make(map[string]*Key)}
+
+	if cfg.SecretKey != "" {
+		ks.verification[""] = &Key{
+			Method:     jwt.SigningMethodHS256,
+			PrivateKey: []byte(cfg.SecretKey),
+			PublicKey:  []byte(cfg.SecretKey),
+		}
+	}
+
+	for _, kc := range cfg.Keys {
+		key, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jwt key %q: %w", kc.ID, err)
+		}
+		if _, exists := ks.verification[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate jwt key id %q", key.ID)
+		}
+		ks.verification[key.ID] = key
+	}
+
+	signing, ok := ks.verification[cfg.SigningKeyID]
+	if !ok {
+		return nil, fmt.Errorf("jwt signing key %q is not configured", cfg.SigningKeyID)
+	}
+	if signing.PrivateKey == nil {
+		return nil, fmt.Errorf("jwt signing key %q has no private key", cfg.SigningKeyID)
+	}
+	ks.signing = signing
+
+	return ks, nil
+}
+
+func loadKey(kc config.JWTKeyConfig) (*Key, error) {
+	if kc.ID == "" {
+		return nil, fmt.Errorf("key id is required")
+	}
+
+	key := &Key{ID: kc.ID}
+	switch kc.Algorithm {
+	case "RS256":
+		key.Method = jwt.SigningMethodRS256
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.PrivateKey = privateKey
+			key.PublicKey = &privateKey.PublicKey
+			return key, nil
+		}
+		pem, err := os.ReadFile(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key.PublicKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		return key, err
+
+	case "EdDSA":
+		key.Method = jwt.SigningMethodEdDSA
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			privateKey, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.PrivateKey = privateKey
+			key.PublicKey = privateKey.(crypto.Signer).Public()
+			return key, nil
+		}
+		pem, err := os.ReadFile(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key.PublicKey, err = jwt.ParseEdPublicKeyFromPEM(pem)
+		return key, err
+
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", kc.Algorithm)
+	}
+}
+
+// sign signs the claims with the signing key, setting the kid header.
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	if ks.signing.ID != "" {
+		token.Header["kid"] = ks.signing.ID
+	}
+	return token.SignedString(ks.signing.PrivateKey)
+}
+
+// keyFunc selects the verification key named by the token's kid header. The token's algorithm
+// must match the key's, so a public key can never be used as an HMAC secret.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
+}
+
+// JWK is a JSON Web Key as defined in RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every asymmetric verification key. Symmetric keys are never
+// published.
+func (ks *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(ks.verification))
+	for id := range ks.verification {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JWKS{Keys: []JWK{}}
+	for _, id := range ids {
+		key := ks.verification[id]
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return set
+}
+
+// PublicKey converts the JWK into the public key it describes.
+func (k JWK) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
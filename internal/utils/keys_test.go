@@ -0,0 +1,278 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// testKeys are the keys of a key set with an RSA signing key, a retired Ed25519 key whose
+// private half is no longer configured, and the legacy secret.
+type testKeys struct {
+	cfg        config.JWTConfig
+	rsaKey     *rsa.PrivateKey
+	rsaPEM     []byte // The public key, as published
+	retiredKey ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublic, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retiredPublic, retiredKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retiredDER, err := x509.MarshalPKIXPublicKey(retiredPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaFile := write("rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	return testKeys{
+		cfg: config.JWTConfig{
+			SecretKey:    "legacy-secret",
+			SigningKeyID: "rsa-2024",
+			Keys: []config.JWTKeyConfig{
+				{
+					ID:             "rsa-2024",
+					Algorithm:      "RS256",
+					PrivateKeyFile: rsaFile,
+				},
+				{
+					ID:            "ed-2023",
+					Algorithm:     "EdDSA",
+					PublicKeyFile: write("ed.pub.pem", "PUBLIC KEY", retiredDER),
+				},
+			},
+		},
+		rsaKey:     rsaKey,
+		rsaPEM:     pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublic}),
+		retiredKey: retiredKey,
+	}
+}
+
+func signTestToken(
+	t *testing.T,
+	method jwt.SigningMethod,
+	kid string,
+	key interface{},
+	expiresAt time.Time,
+) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestValidateToken(t *testing.T) {
+	keys := newTestKeys(t)
+	keySet, err := LoadKeySet(keys.cfg)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	issued, err := GenerateToken(Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"}},
+		keySet, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	earlier := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"issued by us", issued, true},
+		{
+			"retired key",
+			signTestToken(t, jwt.SigningMethodEdDSA, "ed-2023", keys.retiredKey, later),
+			true,
+		},
+		{
+			"legacy secret without kid",
+			signTestToken(t, jwt.SigningMethodHS256, "", []byte("legacy-secret"), later),
+			true,
+		},
+		{
+			"expired",
+			signTestToken(t, jwt.SigningMethodRS256, "rsa-2024", keys.rsaKey, earlier),
+			false,
+		},
+		{
+			"unknown kid",
+			signTestToken(t, jwt.SigningMethodRS256, "rsa-2099", keys.rsaKey, later),
+			false,
+		},
+		{
+			"public key used as an HMAC secret",
+			signTestToken(t, jwt.SigningMethodHS256, "rsa-2024", keys.rsaPEM, later),
+			false,
+		},
+		{
+			"algorithm of another key",
+			signTestToken(t, jwt.SigningMethodRS256, "ed-2023", keys.rsaKey, later),
+			false,
+		},
+		{
+			"legacy secret with the signing key's kid",
+			signTestToken(t, jwt.SigningMethodHS256, "rsa-2024", []byte("legacy-secret"), later),
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ValidateToken(tt.token, keySet)
+			if tt.valid && (err != nil || claims.Subject != "user-1") {
+				t.Fatalf("ValidateToken = %+v, %v; want user-1", claims, err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("ValidateToken accepted the token")
+			}
+		})
+	}
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	keys := newTestKeys(t)
+	rsaKey := keys.cfg.Keys[0]
+	retiredKey := keys.cfg.Keys[1]
+
+	tests := []struct {
+		name   string
+		modify func(cfg *config.JWTConfig)
+		want   string
+	}{
+		{
+			"signing key not configured",
+			func(cfg *config.JWTConfig) { cfg.SigningKeyID = "rsa-2099" },
+			"is not configured",
+		},
+		{
+			"signing key without private key",
+			func(cfg *config.JWTConfig) { cfg.SigningKeyID = retiredKey.ID },
+			"has no private key",
+		},
+		{
+			"duplicate key id",
+			func(cfg *config.JWTConfig) { cfg.Keys = append(cfg.Keys, rsaKey) },
+			"duplicate jwt key id",
+		},
+		{
+			"unsupported algorithm",
+			func(cfg *config.JWTConfig) { cfg.Keys[0].Algorithm = "HS256" },
+			"unsupported algorithm",
+		},
+		{
+			"missing key id",
+			func(cfg *config.JWTConfig) { cfg.Keys[1].ID = "" },
+			"key id is required",
+		},
+		{
+			"missing key file",
+			func(cfg *config.JWTConfig) { cfg.Keys[1].PublicKeyFile = "/nonexistent.pem" },
+			"failed to load jwt key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := keys.cfg
+			cfg.Keys = append([]config.JWTKeyConfig(nil), keys.cfg.Keys...)
+			tt.modify(&cfg)
+			_, err := LoadKeySet(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadKeySet error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+	keySet, err := LoadKeySet(keys.cfg)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+
+	set := keySet.JWKS()
+	// The legacy secret is never published, and keys are sorted by ID.
+	if len(set.Keys) != 2 || set.Keys[0].Kid != "ed-2023" || set.Keys[1].Kid != "rsa-2024" {
+		t.Fatalf("JWKS = %+v", set)
+	}
+	want := map[string]interface{}{
+		"ed-2023":  keys.retiredKey.Public(),
+		"rsa-2024": &keys.rsaKey.PublicKey,
+	}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "sig" {
+			t.Errorf("key %q has use %q", jwk.Kid, jwk.Use)
+		}
+		got, err := jwk.PublicKey()
+		if err != nil {
+			t.Fatalf("PublicKey(%q): %v", jwk.Kid, err)
+		}
+		if !reflect.DeepEqual(got, want[jwk.Kid]) {
+			t.Errorf("PublicKey(%q) does not round-trip", jwk.Kid)
+		}
+	}
+}
+
+func TestJWKPublicKeyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		jwk  JWK
+	}{
+		{"unsupported key type", JWK{Kty: "EC", Crv: "P-256"}},
+		{"unsupported curve", JWK{Kty: "OKP", Crv: "X25519", X: "AAAA"}},
+		{"short ed25519 key", JWK{Kty: "OKP", Crv: "Ed25519", X: "AAAA"}},
+		{"invalid modulus", JWK{Kty: "RSA", N: "not base64!", E: "AQAB"}},
+		{"invalid exponent", JWK{Kty: "RSA", N: "AQAB", E: "not base64!"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if key, err := tt.jwk.PublicKey(); err == nil {
+				t.Fatalf("PublicKey = %v, want an error", key)
+			}
+		})
+	}
+}
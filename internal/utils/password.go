@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// ErrUnsupportedPasswordHash is returned for stored hashes in a format we cannot verify.
+var ErrUnsupportedPasswordHash = errors.New("unsupported password hash")
+
+// PasswordHasher hashes passwords with argon2id and verifies them against argon2id or legacy
+// bcrypt hashes.
+type PasswordHasher struct {
+	memory      uint32 // KiB
+	iterations  uint32
+	parallelism uint8
+	dummyHash   string
+}
+
+// NewPasswordHasher creates a PasswordHasher that hashes with the configured argon2id
+// parameters.
+func NewPasswordHasher(cfg config.Argon2Config) (*PasswordHasher, error) {
+	if cfg.MemoryKiB == 0 || cfg.Iterations == 0 || cfg.Parallelism == 0 {
+		return nil, fmt.Errorf("argon2 memory, iterations and parallelism must be positive")
+	}
+	h := &PasswordHasher{
+		memory:      cfg.MemoryKiB,
+		iterations:  cfg.Iterations,
+		parallelism: cfg.Parallelism,
+	}
+
+	// Hashed once so that VerifyDummy costs as much as verifying a real password.
+	dummyHash, err := h.Hash("dummy password")
+	if err != nil {
+		return nil, err
+	}
+	h.dummyHash = dummyHash
+	return h, nil
+}
+
+// Hash hashes the password with argon2id and a random salt, encoding the result in the PHC
+// string format.
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey(
+		[]byte(password), salt, h.iterations, h.memory, h.parallelism, argon2KeyLength,
+	)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.memory,
+		h.iterations,
+		h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether the password matches the encoded hash. needsRehash is true when the
+// password matches but the hash was made with a different algorithm or weaker parameters than
+// the hasher's, so the caller should store a fresh hash.
+func (h *PasswordHasher) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	if strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$") {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		return true, true, nil
+	}
+
+	var version int
+	var memory, iterations uint32
+	var parallelism uint8
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, false, ErrUnsupportedPasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, ErrUnsupportedPasswordHash
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism)
+	if err != nil {
+		return false, false, ErrUnsupportedPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrUnsupportedPasswordHash
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false, ErrUnsupportedPasswordHash
+	}
+
+	key := argon2.IDKey(
+		[]byte(password), salt, iterations, memory, parallelism, uint32(len(expected)),
+	)
+	if subtle.ConstantTimeCompare(key, expected) != 1 {
+		return false, false, nil
+	}
+	needsRehash = memory != h.memory || iterations != h.iterations ||
+		parallelism != h.parallelism || len(expected) != argon2KeyLength
+	return true, needsRehash, nil
+}
+
+// VerifyDummy does the work of verifying a password without a stored hash, so that failing for
+// an unknown account takes as long as failing for a wrong password.
+func (h *PasswordHasher) VerifyDummy(password string) {
+	_, _, _ = h.Verify(password, h.dummyHash)
+}
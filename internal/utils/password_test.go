@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/moriverse/45-server/internal/infrastructure/config"
+)
+
+// Cheap parameters keep the tests fast; the hashing is the same at any cost.
+var testArgon2Config = config.Argon2Config{MemoryKiB: 1024, Iterations: 1, Parallelism: 1}
+
+func newTestHasher(t *testing.T, cfg config.Argon2Config) *PasswordHasher {
+	t.Helper()
+	h, err := NewPasswordHasher(cfg)
+	if err != nil {
+		t.Fatalf("NewPasswordHasher: %v", err)
+	}
+	return h
+}
+
+func TestNewPasswordHasherRejectsZeroParameters(t *testing.T) {
+	tests := []config.Argon2Config{
+		{MemoryKiB: 0, Iterations: 1, Parallelism: 1},
+		{MemoryKiB: 1024, Iterations: 0, Parallelism: 1},
+		{MemoryKiB: 1024, Iterations: 1, Parallelism: 0},
+	}
+	for _, cfg := range tests {
+		if _, err := NewPasswordHasher(cfg); err == nil {
+			t.Errorf("NewPasswordHasher(%+v) succeeded", cfg)
+		}
+	}
+}
+
+func TestPasswordHasher(t *testing.T) {
+	h := newTestHasher(t, testArgon2Config)
+	weaker := newTestHasher(t, config.Argon2Config{MemoryKiB: 512, Iterations: 1, Parallelism: 1})
+
+	hash := func(h *PasswordHasher, password string) string {
+		t.Helper()
+		encoded, err := h.Hash(password)
+		if err != nil {
+			t.Fatalf("Hash: %v", err)
+		}
+		return encoded
+	}
+	bcryptHash := func(password string) string {
+		t.Helper()
+		encoded, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(encoded)
+	}
+	current := hash(h, "correct horse")
+	weak := hash(weaker, "correct horse")
+	legacy := bcryptHash("correct horse")
+	legacy2y := "$2y$" + strings.TrimPrefix(legacy, "$2a$")
+	argon2i := strings.Replace(current, "argon2id", "argon2i", 1)
+	oldVersion := strings.Replace(current, "v=19", "v=16", 1)
+	truncated := current[:strings.LastIndex(current, "$")]
+
+	tests := []struct {
+		name        string
+		password    string
+		encoded     string
+		ok          bool
+		needsRehash bool
+		err         error
+	}{
+		{"argon2id", "correct horse", current, true, false, nil},
+		{"argon2id, wrong password", "battery staple", current, false, false, nil},
+		{"argon2id, weaker parameters", "correct horse", weak, true, true, nil},
+		{"bcrypt", "correct horse", legacy, true, true, nil},
+		{"bcrypt, wrong password", "battery staple", legacy, false, false, nil},
+		{"bcrypt $2y$", "correct horse", legacy2y, true, true, nil},
+		{"argon2i", "correct horse", argon2i, false, false, ErrUnsupportedPasswordHash},
+		{"other version", "correct horse", oldVersion, false, false, ErrUnsupportedPasswordHash},
+		{"truncated", "correct horse", truncated, false, false, ErrUnsupportedPasswordHash},
+		{"not a hash", "correct horse", "plaintext", false, false, ErrUnsupportedPasswordHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := h.Verify(tt.password, tt.encoded)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.err)
+			}
+			if ok != tt.ok || needsRehash != tt.needsRehash {
+				t.Fatalf("Verify = %v, %v; want %v, %v", ok, needsRehash, tt.ok, tt.needsRehash)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherSaltsEachHash(t *testing.T) {
+	h := newTestHasher(t, testArgon2Config)
+	first, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatal("two hashes of the same password are equal")
+	}
+	if !strings.HasPrefix(first, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("Hash = %q, want the PHC format with the configured parameters", first)
+	}
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890".
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		at     int64
+		ok     bool
+		step   int64
+	}{
+		// The last six digits of the RFC 6238 test vectors.
+		{"RFC 6238 at 59", rfc6238Secret, "287082", 59, true, 1},
+		{"RFC 6238 at 1111111109", rfc6238Secret, "081804", 1111111109, true, 37037036},
+		{"RFC 6238 at 1111111111", rfc6238Secret, "050471", 1111111111, true, 37037037},
+		{"RFC 6238 at 1234567890", rfc6238Secret, "005924", 1234567890, true, 41152263},
+		{"RFC 6238 at 2000000000", rfc6238Secret, "279037", 2000000000, true, 66666666},
+		{"lowercase secret", strings.ToLower(rfc6238Secret), "279037", 2000000000, true, 66666666},
+		{"previous period", rfc6238Secret, "279037", 2000000000 + 30, true, 66666666},
+		{"next period", rfc6238Secret, "279037", 2000000000 - 30, true, 66666666},
+		{"two periods late", rfc6238Secret, "279037", 2000000000 + 60, false, 0},
+		{"wrong code", rfc6238Secret, "279038", 2000000000, false, 0},
+		{"eight digits", rfc6238Secret, "69279037", 2000000000, false, 0},
+		{"invalid secret", "not base32!", "279037", 2000000000, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(tt.secret, tt.code, time.Unix(tt.at, 0))
+			if ok != tt.ok || step != tt.step {
+				t.Fatalf("ValidateTOTP = %d, %v; want %d, %v", step, ok, tt.step, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != totpSecretLength {
+		t.Fatalf("GenerateTOTPSecret = %q, which decodes to %d bytes (%v)", secret, len(key), err)
+	}
+
+	// A code computed from the new secret validates against it.
+	now := time.Now()
+	code := hotp(key, uint64(now.Unix()/int64(totpPeriod.Seconds())))
+	if _, ok := ValidateTOTP(secret, code, now); !ok {
+		t.Fatal("ValidateTOTP rejected the current code")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(TOTPURI("45 AI", "someone@example.com", rfc6238Secret))
+	if err != nil {
+		t.Fatalf("TOTPURI is not a URL: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/45 AI:someone@example.com" {
+		t.Fatalf("TOTPURI = %s", uri)
+	}
+	want := map[string]string{
+		"secret":    rfc6238Secret,
+		"issuer":    "45 AI",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for param, value := range want {
+		if got := uri.Query().Get(param); got != value {
+			t.Errorf("TOTPURI %s = %q, want %q", param, got, value)
+		}
+	}
+}
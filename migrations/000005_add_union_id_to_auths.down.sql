@@ -0,0 +1,3 @@
+-- +migrate Down
+DROP INDEX IF EXISTS idx_auths_provider_union_id;
+ALTER TABLE auths DROP COLUMN IF EXISTS union_id;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE auths ADD COLUMN IF NOT EXISTS union_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_auths_provider_union_id ON auths(provider, union_id)
+    WHERE union_id IS NOT NULL;
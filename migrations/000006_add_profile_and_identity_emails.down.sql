@@ -0,0 +1,5 @@
+-- +migrate Down
+ALTER TABLE auths DROP COLUMN IF EXISTS email_is_private;
+ALTER TABLE auths DROP COLUMN IF EXISTS email;
+
+ALTER TABLE users DROP COLUMN IF EXISTS nickname;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS nickname VARCHAR(255);
+
+ALTER TABLE auths ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+ALTER TABLE auths ADD COLUMN IF NOT EXISTS email_is_private BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+-- +migrate Down
+ALTER TABLE auths DROP COLUMN IF EXISTS email_verified_at;
+
+DROP TABLE IF EXISTS verification_tokens;
@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS verification_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    auth_id UUID NOT NULL REFERENCES auths(id) ON DELETE CASCADE,
+    purpose VARCHAR(50) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    email VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_verification_tokens_auth_id ON verification_tokens(auth_id, purpose);
+
+ALTER TABLE auths ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,12 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS account_merges (
+    id UUID PRIMARY KEY,
+    source_user_id UUID NOT NULL REFERENCES users(id),
+    target_user_id UUID NOT NULL REFERENCES users(id),
+    initiated_by UUID NOT NULL REFERENCES users(id),
+    reason VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_merges_source_user_id ON account_merges(source_user_id);
+CREATE INDEX IF NOT EXISTS idx_account_merges_target_user_id ON account_merges(target_user_id);
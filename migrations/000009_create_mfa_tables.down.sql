@@ -0,0 +1,3 @@
+-- +migrate Down
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS mfa_totp;
@@ -0,0 +1,3 @@
+-- +migrate Down
+DROP INDEX IF EXISTS idx_users_guest_last_active;
+ALTER TABLE users DROP COLUMN IF EXISTS is_guest;
@@ -0,0 +1,6 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_guest BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Lets the purge job find stale guests without scanning every user.
+CREATE INDEX IF NOT EXISTS idx_users_guest_last_active
+    ON users(COALESCE(last_active_at, created_at)) WHERE is_guest;
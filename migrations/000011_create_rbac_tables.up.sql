@@ -0,0 +1,36 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS roles (
+    id UUID PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles(role_id);
+
+INSERT INTO roles (id, name, description) VALUES
+    ('6f1c2d3e-0000-4000-8000-000000000001', 'admin', 'Full access to the admin endpoints'),
+    ('6f1c2d3e-0000-4000-8000-000000000002', 'moderator', 'Can look up and ban users')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission) VALUES
+    ('6f1c2d3e-0000-4000-8000-000000000001', 'users:read'),
+    ('6f1c2d3e-0000-4000-8000-000000000001', 'users:ban'),
+    ('6f1c2d3e-0000-4000-8000-000000000001', 'roles:manage'),
+    ('6f1c2d3e-0000-4000-8000-000000000002', 'users:read'),
+    ('6f1c2d3e-0000-4000-8000-000000000002', 'users:ban')
+ON CONFLICT DO NOTHING;
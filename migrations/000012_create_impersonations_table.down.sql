@@ -0,0 +1,3 @@
+-- +migrate Down
+DELETE FROM role_permissions WHERE permission = 'users:impersonate';
+DROP TABLE IF EXISTS impersonations;
@@ -0,0 +1,18 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS impersonations (
+    id UUID PRIMARY KEY,
+    actor_id UUID NOT NULL REFERENCES users(id),
+    target_user_id UUID NOT NULL REFERENCES users(id),
+    reason TEXT NOT NULL,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonations_actor_id ON impersonations(actor_id);
+CREATE INDEX IF NOT EXISTS idx_impersonations_target_user_id ON impersonations(target_user_id);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'users:impersonate' FROM roles WHERE name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- +migrate Down
+DELETE FROM role_permissions WHERE permission = 'api_keys:manage';
+DROP TABLE IF EXISTS api_key_scopes;
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,22 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    key_hash VARCHAR(64) NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS api_key_scopes (
+    api_key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    scope VARCHAR(100) NOT NULL,
+    PRIMARY KEY (api_key_id, scope)
+);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'api_keys:manage' FROM roles WHERE name = 'admin'
+ON CONFLICT DO NOTHING;
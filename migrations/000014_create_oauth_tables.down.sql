@@ -0,0 +1,7 @@
+-- +migrate Down
+DELETE FROM role_permissions WHERE permission = 'oauth_clients:manage';
+DROP TABLE IF EXISTS oauth_grants;
+DROP TABLE IF EXISTS oauth_consents;
+DROP TABLE IF EXISTS oauth_client_scopes;
+DROP TABLE IF EXISTS oauth_client_redirect_uris;
+DROP TABLE IF EXISTS oauth_clients;
@@ -0,0 +1,48 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    secret_hash VARCHAR(64),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS oauth_client_redirect_uris (
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    redirect_uri VARCHAR(2048) NOT NULL,
+    PRIMARY KEY (client_id, redirect_uri)
+);
+
+CREATE TABLE IF NOT EXISTS oauth_client_scopes (
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scope VARCHAR(100) NOT NULL,
+    PRIMARY KEY (client_id, scope)
+);
+
+CREATE TABLE IF NOT EXISTS oauth_consents (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, client_id)
+);
+
+CREATE TABLE IF NOT EXISTS oauth_grants (
+    id UUID PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL,
+    refresh_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_grants_user_id_client_id ON oauth_grants(user_id, client_id);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'oauth_clients:manage' FROM roles WHERE name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,19 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS login_events (
+    id UUID PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    success BOOLEAN NOT NULL,
+    failure_reason VARCHAR(255),
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    device_id VARCHAR(255),
+    source VARCHAR(50),
+    country VARCHAR(2),
+    new_device BOOLEAN NOT NULL DEFAULT FALSE,
+    new_country BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user_id_created_at
+    ON login_events(user_id, created_at DESC);
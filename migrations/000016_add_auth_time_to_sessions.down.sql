@@ -0,0 +1,3 @@
+-- +migrate Down
+ALTER TABLE sessions DROP COLUMN IF EXISTS amr;
+ALTER TABLE sessions DROP COLUMN IF EXISTS auth_time;
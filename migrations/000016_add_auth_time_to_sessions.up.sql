@@ -0,0 +1,8 @@
+-- +migrate Up
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP WITH TIME ZONE;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS amr VARCHAR(255) NOT NULL DEFAULT '';
+
+UPDATE sessions SET auth_time = created_at WHERE auth_time IS NULL;
+
+ALTER TABLE sessions ALTER COLUMN auth_time SET NOT NULL;
+ALTER TABLE sessions ALTER COLUMN auth_time SET DEFAULT NOW();
@@ -0,0 +1,5 @@
+-- +migrate Down
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
+ALTER TABLE users DROP COLUMN IF EXISTS birthday;
+ALTER TABLE users DROP COLUMN IF EXISTS gender;
+ALTER TABLE users DROP COLUMN IF EXISTS bio;
@@ -0,0 +1,4 @@
+-- +migrate Down
+-- The numbers as originally entered are not kept, and E.164 numbers work with either version of
+-- the service, so there is nothing to undo.
+SELECT 1;
@@ -0,0 +1,69 @@
+-- +migrate Up
+-- Phone numbers used to be stored as entered, and are now looked up in E.164 form. Convert the
+-- old ones the same way the service does: strip formatting, and add +86 to mainland China mobile
+-- numbers entered without it. Other numbers without a + may or may not start with a country
+-- code, so they are left as they are. A number whose E.164 form is already taken belongs to a
+-- duplicate account and is left as it is; only the oldest of several old numbers with the same
+-- E.164 form is converted.
+WITH stripped AS (
+    SELECT id, created_at, regexp_replace(phone_number, '[ ()-]', '', 'g') AS phone_number
+    FROM users
+    WHERE phone_number IS NOT NULL
+), normalized AS (
+    SELECT
+        id,
+        CASE
+            WHEN phone_number ~ '^\+[1-9][0-9]{6,14}$' THEN phone_number
+            WHEN phone_number ~ '^1(3[0-9]|4[5-9]|5[0-35-9]|6[2567]|7[0-8]|8[0-9]|9[0-35-9])[0-9]{8}$'
+                THEN '+86' || phone_number
+        END AS phone_number,
+        created_at
+    FROM stripped
+), ranked AS (
+    SELECT
+        id,
+        phone_number,
+        ROW_NUMBER() OVER (PARTITION BY phone_number ORDER BY created_at, id) AS rank
+    FROM normalized
+    WHERE phone_number IS NOT NULL
+)
+UPDATE users
+SET phone_number = ranked.phone_number
+FROM ranked
+WHERE users.id = ranked.id
+  AND ranked.rank = 1
+  AND users.phone_number <> ranked.phone_number
+  AND NOT EXISTS (SELECT 1 FROM users other WHERE other.phone_number = ranked.phone_number);
+
+WITH stripped AS (
+    SELECT id, created_at, regexp_replace(provider_id, '[ ()-]', '', 'g') AS provider_id
+    FROM auths
+    WHERE provider = 'phone'
+), normalized AS (
+    SELECT
+        id,
+        CASE
+            WHEN provider_id ~ '^\+[1-9][0-9]{6,14}$' THEN provider_id
+            WHEN provider_id ~ '^1(3[0-9]|4[5-9]|5[0-35-9]|6[2567]|7[0-8]|8[0-9]|9[0-35-9])[0-9]{8}$'
+                THEN '+86' || provider_id
+        END AS provider_id,
+        created_at
+    FROM stripped
+), ranked AS (
+    SELECT
+        id,
+        provider_id,
+        ROW_NUMBER() OVER (PARTITION BY provider_id ORDER BY created_at, id) AS rank
+    FROM normalized
+    WHERE provider_id IS NOT NULL
+)
+UPDATE auths
+SET provider_id = ranked.provider_id, updated_at = NOW()
+FROM ranked
+WHERE auths.id = ranked.id
+  AND ranked.rank = 1
+  AND auths.provider_id <> ranked.provider_id
+  AND NOT EXISTS (
+      SELECT 1 FROM auths other
+      WHERE other.provider = 'phone' AND other.provider_id = ranked.provider_id
+  );
@@ -0,0 +1,3 @@
+-- +migrate Down
+-- The released numbers may have signed up again since, so there is nothing to undo.
+SELECT 1;
@@ -0,0 +1,4 @@
+-- +migrate Up
+-- Deleting a user now releases their phone number, so that it can sign up again instead of
+-- signing in to the deleted user. Release the numbers of users deleted before.
+UPDATE users SET phone_number = NULL WHERE deleted_at IS NOT NULL AND phone_number IS NOT NULL;
@@ -0,0 +1,2 @@
+-- +migrate Down
+ALTER TABLE auths DROP COLUMN IF EXISTS device_id;
@@ -0,0 +1,2 @@
+-- +migrate Up
+ALTER TABLE auths ADD COLUMN IF NOT EXISTS device_id VARCHAR(255);